@@ -1,141 +1,443 @@
-package main
-
-import (
-	"log/slog"
-	"os"
-	"os/signal"
-	"syscall"
-
-	aClient "github.com/BazaarTrade/ApiGatewayService/internal/api/gRPC/authClient"
-	mClient "github.com/BazaarTrade/ApiGatewayService/internal/api/gRPC/matchingEngineClient"
-	qClient "github.com/BazaarTrade/ApiGatewayService/internal/api/gRPC/quoteClient"
-	"github.com/BazaarTrade/ApiGatewayService/internal/api/rest"
-	ws "github.com/BazaarTrade/ApiGatewayService/internal/api/websocket"
-	"github.com/BazaarTrade/ApiGatewayService/internal/repository"
-	"github.com/BazaarTrade/ApiGatewayService/internal/repository/postgresPgx"
-	"github.com/joho/godotenv"
-)
-
-func main() {
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
-
-	if _, err := os.Stat("../.env"); err == nil {
-		if err := godotenv.Load("../.env"); err != nil {
-			logger.Error("failed to load .env file", "error", err)
-			return
-		}
-	}
-
-	logger.Info("starting aplication...")
-
-	DB_CONNECTION := os.Getenv("DB_CONNECTION")
-	if DB_CONNECTION == "" {
-		logger.Error("DB_CONNECTION environment variable is not set")
-		return
-	}
-
-	repository, err := postgresPgx.NewPostgres(DB_CONNECTION, logger)
-	if err != nil {
-		logger.Error("failed to connect to database", "error", err)
-		return
-	}
-
-	hub := ws.NewHub(logger)
-
-	CONN_ADDR_MATCHING_ENGINE := os.Getenv("CONN_ADDR_MATCHING_ENGINE")
-	if CONN_ADDR_MATCHING_ENGINE == "" {
-		logger.Error("CONN_ADDR_MATCHING_ENGINE environment variable is not set")
-		return
-	}
-
-	mClient := mClient.New(logger)
-	if err := mClient.Run(CONN_ADDR_MATCHING_ENGINE); err != nil {
-		return
-	}
-
-	CON_ADDR_QUOTE := os.Getenv("CONN_ADDR_QUOTE")
-	if CON_ADDR_QUOTE == "" {
-		logger.Error("CONN_ADDR_QUOTE environment variable is not set")
-		return
-	}
-
-	qClient := qClient.New(hub, logger)
-	if err := qClient.Run(CON_ADDR_QUOTE); err != nil {
-		return
-	}
-
-	CONN_ADDR_AUTH := os.Getenv("CONN_ADDR_AUTH")
-	if CONN_ADDR_AUTH == "" {
-		logger.Error("CONN_ADDR_AUTH environment variable is not set")
-		return
-	}
-
-	aClient := aClient.New(logger)
-	if err := aClient.Run(CONN_ADDR_AUTH); err != nil {
-		return
-	}
-
-	if err := initPairs(qClient, hub, repository); err != nil {
-		return
-	}
-
-	ACCESS_TOKEN_SECRET_PHRASE := os.Getenv("ACCESS_TOKEN_SECRET_PHRASE")
-	if ACCESS_TOKEN_SECRET_PHRASE == "" {
-		logger.Error("ACCESS_TOKEN_SECRET_PHRASE environment variable is not set")
-		return
-	}
-
-	ADDR := os.Getenv("ADDR")
-	if ADDR == "" {
-		logger.Error("ADDR environment variable is not set")
-		return
-	}
-
-	rest := rest.New(mClient, qClient, aClient, hub, repository, ACCESS_TOKEN_SECRET_PHRASE, logger)
-	go func() {
-		if err := rest.Run(ADDR); err != nil {
-			os.Exit(1)
-		}
-	}()
-
-	//Graceful shutdown
-
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
-
-	<-stop
-	logger.Info("shutting down...")
-
-	qClient.CloseConnection()
-	logger.Info("closed qClient connection")
-
-	mClient.CloseConnection()
-	logger.Info("closed mClient connection")
-
-	aClient.CloseConnection()
-	logger.Info("closed aClient connection")
-
-	rest.Stop()
-	logger.Info("stopped gRPC server")
-
-	repository.Close()
-	logger.Info("closed database connection")
-
-	logger.Info("gracefully stopped")
-}
-
-func initPairs(qClient *qClient.Client, hub *ws.Hub, repository repository.Repository) error {
-	pairsParams, err := repository.GetPairsParams()
-	if err != nil {
-		return nil
-	}
-
-	for _, pairParams := range pairsParams {
-		hub.AddOrderBookSnapshotTopic(pairParams.Pair, pairParams.OrderBookPricePrecisions)
-		hub.AddTradesTopic(pairParams.Pair)
-		hub.AddTickerTopic(pairParams.Pair)
-		hub.AddCandleStickTopic(pairParams.Pair, pairParams.CandleStickTimeframes)
-		qClient.StartStreamReaders(pairParams.Pair)
-	}
-	return nil
-}
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/algoOrder"
+	aClient "github.com/BazaarTrade/ApiGatewayService/internal/api/gRPC/authClient"
+	mClient "github.com/BazaarTrade/ApiGatewayService/internal/api/gRPC/matchingEngineClient"
+	qClient "github.com/BazaarTrade/ApiGatewayService/internal/api/gRPC/quoteClient"
+	"github.com/BazaarTrade/ApiGatewayService/internal/api/rest"
+	ws "github.com/BazaarTrade/ApiGatewayService/internal/api/websocket"
+	"github.com/BazaarTrade/ApiGatewayService/internal/conditionalOrder"
+	"github.com/BazaarTrade/ApiGatewayService/internal/marketData"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/orderGroup"
+	"github.com/BazaarTrade/ApiGatewayService/internal/orderRouter"
+	"github.com/BazaarTrade/ApiGatewayService/internal/orderState"
+	"github.com/BazaarTrade/ApiGatewayService/internal/rateLimiter"
+	"github.com/BazaarTrade/ApiGatewayService/internal/reconciler"
+	"github.com/BazaarTrade/ApiGatewayService/internal/recorder"
+	"github.com/BazaarTrade/ApiGatewayService/internal/repository"
+	"github.com/BazaarTrade/ApiGatewayService/internal/repository/postgresPgx"
+	"github.com/BazaarTrade/ApiGatewayService/internal/riskChecker"
+	"github.com/BazaarTrade/ApiGatewayService/internal/sandbox"
+	"github.com/BazaarTrade/ApiGatewayService/internal/scheduler"
+	"github.com/BazaarTrade/ApiGatewayService/internal/watchdog"
+	"github.com/joho/godotenv"
+	"google.golang.org/grpc"
+)
+
+func main() {
+	sandboxMode := flag.Bool("sandbox", false, "run matching engine, quote and auth services and storage in process")
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	if _, err := os.Stat("../.env"); err == nil {
+		if err := godotenv.Load("../.env"); err != nil {
+			logger.Error("failed to load .env file", "error", err)
+			return
+		}
+	}
+
+	logger.Info("starting aplication...")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		repository  repository.Repository
+		dialOptions []grpc.DialOption
+		err         error
+	)
+
+	if *sandboxMode {
+		sandbox := runSandbox(ctx, logger)
+		defer sandbox.Stop()
+
+		repository = sandbox.Repository
+		dialOptions = append(dialOptions, sandbox.DialOption())
+	} else {
+		DB_CONNECTION := os.Getenv("DB_CONNECTION")
+		if DB_CONNECTION == "" {
+			logger.Error("DB_CONNECTION environment variable is not set")
+			return
+		}
+
+		repository, err = postgresPgx.NewPostgres(DB_CONNECTION, logger)
+		if err != nil {
+			logger.Error("failed to connect to database", "error", err)
+			return
+		}
+	}
+
+	hub := ws.NewHub(logger)
+	cache := marketData.New()
+
+	staleThresholds, err := staleThresholdsFromEnv()
+	if err != nil {
+		logger.Error("failed to parse stale thresholds", "error", err)
+		return
+	}
+
+	watchdog := watchdog.New(staleThresholds, cache, hub, logger)
+	go watchdog.Run(ctx)
+
+	CONN_ADDR_MATCHING_ENGINE := os.Getenv("CONN_ADDR_MATCHING_ENGINE")
+	if CONN_ADDR_MATCHING_ENGINE == "" {
+		logger.Error("CONN_ADDR_MATCHING_ENGINE environment variable is not set")
+		return
+	}
+
+	mClient := mClient.New(logger)
+	if err := mClient.Run(CONN_ADDR_MATCHING_ENGINE, dialOptions...); err != nil {
+		return
+	}
+
+	riskChecker := riskChecker.New(repository, cache, logger)
+	if err := riskChecker.Load(); err != nil {
+		logger.Error("failed to load risk limits", "error", err)
+		return
+	}
+	go riskChecker.Run(ctx)
+
+	router := orderRouter.New(mClient, hub, riskChecker, logger)
+
+	// single order lookups are served from published order updates younger than ORDER_STATE_MAX_AGE, "0" disables it
+	ORDER_STATE_MAX_AGE, err := durationFromEnv("ORDER_STATE_MAX_AGE", 2*time.Second)
+	if err != nil {
+		logger.Error("failed to parse ORDER_STATE_MAX_AGE", "error", err)
+		return
+	}
+
+	orderState := orderState.New(ORDER_STATE_MAX_AGE)
+	router.AddListener(orderState)
+	go orderState.Run(ctx)
+
+	conditionalOrderEngine := conditionalOrder.New(repository, router, hub, logger)
+	if err := conditionalOrderEngine.Load(); err != nil {
+		logger.Error("failed to load conditional orders", "error", err)
+		return
+	}
+	go conditionalOrderEngine.Run(ctx)
+
+	// time in force jobs are stored, so pending expiries and start times survive restarts
+	scheduler := scheduler.New(repository, router, hub, logger)
+
+	qClient := qClient.New(hub, cache, watchdog, logger)
+
+	// with QUOTE_LAZY_STREAMS upstream streams are only open while clients are subscribed
+	if os.Getenv("QUOTE_LAZY_STREAMS") == "true" {
+		QUOTE_STREAM_GRACE_PERIOD, err := durationFromEnv("QUOTE_STREAM_GRACE_PERIOD", 30*time.Second)
+		if err != nil {
+			logger.Error("failed to parse QUOTE_STREAM_GRACE_PERIOD", "error", err)
+			return
+		}
+		qClient.SetLazy(QUOTE_STREAM_GRACE_PERIOD)
+
+		// the market data cache behind REST snapshots and the FOK and post-only checks is fed by every
+		// stream, narrowing QUOTE_PINNED_STREAMS lets their data go stale while nobody is subscribed
+		QUOTE_PINNED_STREAMS, ok := os.LookupEnv("QUOTE_PINNED_STREAMS")
+		if !ok {
+			QUOTE_PINNED_STREAMS = "orderBook,trades,ticker,candleStick"
+		}
+		for _, stream := range strings.Split(QUOTE_PINNED_STREAMS, ",") {
+			if stream = strings.TrimSpace(stream); stream != "" {
+				qClient.PinStream(stream)
+			}
+		}
+
+		hub.SetDemandHandler(qClient.OnDemandChanged)
+	}
+
+	// stop prices are watched on the trade and ticker streams of the pairs with untriggered orders
+	qClient.AddListener(conditionalOrderEngine)
+	conditionalOrderEngine.SetWatchHandler(func(pair string, watched bool) {
+		for _, stream := range conditionalOrder.WatchedStreams {
+			qClient.SetPairStreamPinned(pair, stream, watched)
+		}
+	})
+
+	// with REPLAY_PATH set market data comes from a recording instead of the quote service
+	var replayer *recorder.Replayer
+	REPLAY_PATH := os.Getenv("REPLAY_PATH")
+	if REPLAY_PATH == "" {
+		CON_ADDR_QUOTE := os.Getenv("CONN_ADDR_QUOTE")
+		if CON_ADDR_QUOTE == "" {
+			logger.Error("CONN_ADDR_QUOTE environment variable is not set")
+			return
+		}
+
+		if err := qClient.Run(CON_ADDR_QUOTE, dialOptions...); err != nil {
+			return
+		}
+	} else {
+		REPLAY_SPEED := 1.0
+		if value := os.Getenv("REPLAY_SPEED"); value != "" {
+			REPLAY_SPEED, err = strconv.ParseFloat(value, 64)
+			if err != nil || REPLAY_SPEED < 0 {
+				logger.Error("invalid REPLAY_SPEED", "value", value)
+				return
+			}
+		}
+
+		replayer = recorder.NewReplayer(REPLAY_PATH, REPLAY_SPEED, hub, cache, logger)
+		go func() {
+			if err := replayer.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				logger.Error("failed to replay market data", "error", err)
+			}
+		}()
+	}
+
+	var marketDataRecorder *recorder.Recorder
+	if RECORD_DIR := os.Getenv("RECORD_DIR"); RECORD_DIR != "" {
+		RECORD_MAX_FILE_SIZE := int64(100 * 1024 * 1024)
+		if value := os.Getenv("RECORD_MAX_FILE_SIZE"); value != "" {
+			RECORD_MAX_FILE_SIZE, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				logger.Error("failed to parse RECORD_MAX_FILE_SIZE", "error", err)
+				return
+			}
+		}
+
+		RECORD_ROTATE_INTERVAL, err := durationFromEnv("RECORD_ROTATE_INTERVAL", time.Hour)
+		if err != nil {
+			logger.Error("failed to parse RECORD_ROTATE_INTERVAL", "error", err)
+			return
+		}
+
+		marketDataRecorder, err = recorder.New(RECORD_DIR, RECORD_MAX_FILE_SIZE, RECORD_ROTATE_INTERVAL, logger)
+		if err != nil {
+			return
+		}
+		qClient.AddListener(marketDataRecorder)
+	}
+
+	CONN_ADDR_AUTH := os.Getenv("CONN_ADDR_AUTH")
+	if CONN_ADDR_AUTH == "" {
+		logger.Error("CONN_ADDR_AUTH environment variable is not set")
+		return
+	}
+
+	aClient := aClient.New(logger)
+	if err := aClient.Run(CONN_ADDR_AUTH, dialOptions...); err != nil {
+		return
+	}
+
+	PAIRS_RECONCILE_INTERVAL, err := durationFromEnv("PAIRS_RECONCILE_INTERVAL", 30*time.Second)
+	if err != nil {
+		logger.Error("failed to parse PAIRS_RECONCILE_INTERVAL", "error", err)
+		return
+	}
+
+	reconciler := reconciler.New(repository, qClient, hub, cache, PAIRS_RECONCILE_INTERVAL, logger)
+	if replayer != nil {
+		// replayed pairs are not necessarily listed, they must survive reconciles
+		reconciler.SetReplayedPairs(replayer.HasPair)
+	}
+	if err := reconciler.Reconcile(); err != nil {
+		logger.Error("failed to init pairs", "error", err)
+		return
+	}
+	go reconciler.Run(ctx)
+
+	algoOrderEngine := algoOrder.New(repository, router, hub, reconciler, logger)
+	if err := algoOrderEngine.Load(); err != nil {
+		logger.Error("failed to load algo orders", "error", err)
+		return
+	}
+	router.AddListener(algoOrderEngine)
+	go algoOrderEngine.Run(ctx)
+
+	// oco and bracket legs are linked through the updates of their orders and stop losses
+	orderGroupEngine := orderGroup.New(repository, router, conditionalOrderEngine, hub, cache, logger)
+	if err := orderGroupEngine.Load(); err != nil {
+		logger.Error("failed to load order groups", "error", err)
+		return
+	}
+	router.AddListener(orderGroupEngine)
+	conditionalOrderEngine.AddListener(orderGroupEngine)
+	go orderGroupEngine.Run(ctx)
+
+	ACCESS_TOKEN_SECRET_PHRASE := os.Getenv("ACCESS_TOKEN_SECRET_PHRASE")
+	if ACCESS_TOKEN_SECRET_PHRASE == "" {
+		logger.Error("ACCESS_TOKEN_SECRET_PHRASE environment variable is not set")
+		return
+	}
+
+	// order requests are limited per user by token buckets, a rate of "0" disables the limit
+	var rateLimits = make(map[string]rateLimiter.Limit)
+	for kind, defaultLimit := range map[string]rateLimiter.Limit{
+		"place":  {Burst: 20, Rate: 10},
+		"cancel": {Burst: 40, Rate: 20},
+	} {
+		envName := "ORDER_" + strings.ToUpper(kind)
+		limit := defaultLimit
+		if limit.Rate, err = floatFromEnv(envName+"_RATE", defaultLimit.Rate); err != nil {
+			logger.Error("failed to parse "+envName+"_RATE", "error", err)
+			return
+		}
+		if limit.Burst, err = floatFromEnv(envName+"_BURST", defaultLimit.Burst); err != nil {
+			logger.Error("failed to parse "+envName+"_BURST", "error", err)
+			return
+		}
+		rateLimits[kind] = limit
+	}
+
+	rateLimiter := rateLimiter.New(rateLimits)
+	go rateLimiter.Run(ctx)
+
+	// the cap is approximate, concurrent placements of a user may exceed it by the requests in flight
+	MAX_OPEN_ORDERS_PER_PAIR := 200
+	if value := os.Getenv("MAX_OPEN_ORDERS_PER_PAIR"); value != "" {
+		MAX_OPEN_ORDERS_PER_PAIR, err = strconv.Atoi(value)
+		if err != nil {
+			logger.Error("failed to parse MAX_OPEN_ORDERS_PER_PAIR", "error", err)
+			return
+		}
+	}
+
+	// admin routes are disabled while ADMIN_TOKEN is not set
+	ADMIN_TOKEN := os.Getenv("ADMIN_TOKEN")
+
+	ADDR := os.Getenv("ADDR")
+	if ADDR == "" {
+		logger.Error("ADDR environment variable is not set")
+		return
+	}
+
+	rest := rest.New(mClient, qClient, aClient, hub, cache, watchdog, reconciler, router, orderState, conditionalOrderEngine, scheduler, algoOrderEngine, orderGroupEngine, rateLimiter, riskChecker, MAX_OPEN_ORDERS_PER_PAIR, repository, ACCESS_TOKEN_SECRET_PHRASE, ADMIN_TOKEN, logger)
+	// the server registers what cancelAllAfter deadlines cancel, so jobs are run only from here on
+	go scheduler.Run(ctx)
+
+	go func() {
+		if err := rest.Run(ADDR); err != nil {
+			os.Exit(1)
+		}
+	}()
+
+	//Graceful shutdown
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
+
+	<-stop
+	logger.Info("shutting down...")
+
+	cancel()
+
+	qClient.CloseConnection()
+	logger.Info("closed qClient connection")
+
+	if marketDataRecorder != nil {
+		marketDataRecorder.Close()
+		logger.Info("closed market data recorder")
+	}
+
+	mClient.CloseConnection()
+	logger.Info("closed mClient connection")
+
+	aClient.CloseConnection()
+	logger.Info("closed aClient connection")
+
+	rest.Stop()
+	logger.Info("stopped gRPC server")
+
+	repository.Close()
+	logger.Info("closed database connection")
+
+	logger.Info("gracefully stopped")
+}
+
+// runSandbox starts the in-process services with one liquid pair and points
+// every setting that is not set explicitly at them
+func runSandbox(ctx context.Context, logger *slog.Logger) *sandbox.Sandbox {
+	logger.Info("running in sandbox mode, all backend services are simulated in process")
+
+	sandboxEnv := map[string]string{
+		"CONN_ADDR_MATCHING_ENGINE":  sandbox.Address,
+		"CONN_ADDR_QUOTE":            sandbox.Address,
+		"CONN_ADDR_AUTH":             sandbox.Address,
+		"ACCESS_TOKEN_SECRET_PHRASE": "sandbox",
+		"ADDR":                       ":8080",
+	}
+	for name, value := range sandboxEnv {
+		if os.Getenv(name) == "" {
+			os.Setenv(name, value)
+		}
+	}
+
+	s := sandbox.New(logger)
+	s.Run()
+
+	pairParams := models.PairParams{
+		Pair:                     "BTC_USDT",
+		OrderBookPricePrecisions: []int32{0, 1, 2},
+		QtyPrecision:             4,
+		CandleStickTimeframes:    []string{"1m", "5m", "15m", "1h", "4h", "1d"},
+	}
+	if err := s.CreatePair(pairParams); err != nil {
+		logger.Error("failed to create sandbox pair", "pair", pairParams.Pair, "error", err)
+		return s
+	}
+
+	go sandbox.NewMarketMaker(s.MatchingEngine, pairParams, "60000", logger).Run(ctx)
+	return s
+}
+
+func durationFromEnv(name string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+	return time.ParseDuration(value)
+}
+
+func floatFromEnv(name string, defaultValue float64) (float64, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// staleThresholdsFromEnv reads STALE_THRESHOLD_* durations (e.g. "30s"), "0" disables the check for the stream
+func staleThresholdsFromEnv() (map[string]time.Duration, error) {
+	var staleThresholds = map[string]time.Duration{
+		"ticker":      30 * time.Second,
+		"orderBook":   30 * time.Second,
+		"trades":      0,
+		"candleStick": 0,
+	}
+
+	envNames := map[string]string{
+		"ticker":      "STALE_THRESHOLD_TICKER",
+		"orderBook":   "STALE_THRESHOLD_ORDER_BOOK",
+		"trades":      "STALE_THRESHOLD_TRADES",
+		"candleStick": "STALE_THRESHOLD_CANDLE_STICK",
+	}
+
+	for stream, envName := range envNames {
+		value := os.Getenv(envName)
+		if value == "" {
+			continue
+		}
+
+		threshold, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, err
+		}
+		staleThresholds[stream] = threshold
+	}
+	return staleThresholds, nil
+}
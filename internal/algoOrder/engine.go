@@ -0,0 +1,603 @@
+package algoOrder
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/big"
+	"sync"
+	"time"
+
+	ws "github.com/BazaarTrade/ApiGatewayService/internal/api/websocket"
+	"github.com/BazaarTrade/ApiGatewayService/internal/converter"
+	"github.com/BazaarTrade/ApiGatewayService/internal/decimal"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/orderRouter"
+	"github.com/BazaarTrade/ApiGatewayService/internal/reconciler"
+	"github.com/BazaarTrade/ApiGatewayService/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	pollInterval = time.Second
+	// syncInterval is how often child orders placed by other gateways are picked up
+	syncInterval = 5 * time.Second
+	// lockDuration is how long a claimed algo order is left to its gateway before it is worked again
+	lockDuration = 60 * time.Second
+	// workDuration is the longest working an algo order is expected to take, a cancel and a place in the matching
+	// engine and some database work, an algo order is only worked while its lock has that long left
+	workDuration = 30 * time.Second
+	claimLimit   = 10
+	// icebergCheckInterval is how often an iceberg is checked for a slice filled without its update reaching the gateway
+	icebergCheckInterval = 5 * time.Second
+)
+
+var (
+	ErrNotRunning = errors.New("algo order is not running")
+	ErrNotPaused  = errors.New("algo order is not paused")
+	ErrClosed     = errors.New("algo order is already closed")
+)
+
+// Engine works algo orders. At every twap slice it cancels the unfilled rest of the previous child order
+// and places the next one, sized so that the filled qty catches up with an even split of the total.
+// An iceberg slice is hidden from the owner and replaced as soon as its fill is published.
+// Algo orders are stored in the repository, so they survive restarts and are shared between gateways.
+type Engine struct {
+	db         repository.Repository
+	router     *orderRouter.Router
+	hub        *ws.Hub
+	reconciler *reconciler.Reconciler
+
+	// children maps the resting child orders of open algo orders to their parent
+	children map[int]int
+	mu       sync.Mutex
+	// refreshMu keeps the filled qty of parents from being overwritten by an older child update
+	refreshMu sync.Mutex
+	logger    *slog.Logger
+}
+
+func New(db repository.Repository, router *orderRouter.Router, hub *ws.Hub, reconciler *reconciler.Reconciler, logger *slog.Logger) *Engine {
+	return &Engine{
+		db:         db,
+		router:     router,
+		hub:        hub,
+		reconciler: reconciler,
+		children:   make(map[int]int),
+		logger:     logger,
+	}
+}
+
+// Load maps the resting child orders of the open algo orders, so that their fills are reported
+// even if they were placed before a restart or by another gateway
+func (e *Engine) Load() error {
+	algoOrders, err := e.db.GetOpenAlgoOrders()
+	if err != nil {
+		return err
+	}
+
+	var children = make(map[int]int)
+	for _, algoOrder := range algoOrders {
+		orders, err := e.db.GetAlgoOrderChildren(algoOrder.ID)
+		if err != nil {
+			return err
+		}
+		for _, order := range orders {
+			if order.Status == "filling" {
+				children[order.ID] = algoOrder.ID
+				if algoOrder.Type == "iceberg" {
+					e.router.Hide(order.ID)
+				}
+			}
+		}
+	}
+
+	e.mu.Lock()
+	e.children = children
+	e.mu.Unlock()
+	return nil
+}
+
+func (e *Engine) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	syncTicker := time.NewTicker(syncInterval)
+	defer syncTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.runDue()
+		case <-syncTicker.C:
+			if err := e.Load(); err != nil {
+				e.logger.Error("failed to load algo orders", "error", err)
+			}
+		}
+	}
+}
+
+// Create stores a validated algo order request, its first slice is placed right away by the next poll
+func (e *Engine) Create(req models.AlgoOrderReq) (models.AlgoOrder, error) {
+	now := time.Now()
+
+	algoOrder := models.AlgoOrder{
+		UserID:        req.UserID,
+		IsBid:         req.IsBid,
+		Pair:          req.Pair,
+		Type:          req.Type,
+		Price:         req.Price,
+		Qty:           req.Qty,
+		Duration:      time.Duration(req.Duration) * time.Second,
+		SliceInterval: time.Duration(req.SliceInterval) * time.Second,
+		VisibleQty:    req.VisibleQty,
+		SizeFilled:    "0",
+		Status:        "running",
+		NextSliceAt:   now,
+	}
+	if algoOrder.Type == "twap" {
+		algoOrder.EndAt = now.Add(algoOrder.Duration)
+	}
+
+	algoOrder, err := e.db.CreateAlgoOrder(algoOrder)
+	if err != nil {
+		return models.AlgoOrder{}, err
+	}
+
+	e.hub.BroadcastOrder(converter.AlgoOrderToModelsOrder(algoOrder))
+	return algoOrder, nil
+}
+
+// Pause stops placing slices and cancels the resting child order, the caller is expected to check
+// that the algo order belongs to the user
+func (e *Engine) Pause(id int) (models.AlgoOrder, error) {
+	algoOrder, err := e.db.GetAlgoOrder(id)
+	if err != nil {
+		return models.AlgoOrder{}, err
+	}
+	if algoOrder.Status != "running" {
+		return models.AlgoOrder{}, ErrNotRunning
+	}
+
+	algoOrder.Status = "paused"
+	algoOrder.PausedAt = time.Now()
+	if ok, err := e.db.UpdateAlgoOrder(algoOrder, "running"); err != nil || !ok {
+		if err != nil {
+			return models.AlgoOrder{}, err
+		}
+		return models.AlgoOrder{}, ErrNotRunning
+	}
+
+	e.stop(&algoOrder)
+	return algoOrder, nil
+}
+
+// Resume continues a paused algo order. The remaining twap slices keep their spacing and are moved by the pause,
+// an iceberg gets its slice back right away.
+func (e *Engine) Resume(id int) (models.AlgoOrder, error) {
+	algoOrder, err := e.db.GetAlgoOrder(id)
+	if err != nil {
+		return models.AlgoOrder{}, err
+	}
+	if algoOrder.Status != "paused" {
+		return models.AlgoOrder{}, ErrNotPaused
+	}
+
+	if algoOrder.Type == "twap" {
+		paused := time.Since(algoOrder.PausedAt)
+		algoOrder.NextSliceAt = algoOrder.NextSliceAt.Add(paused)
+		algoOrder.EndAt = algoOrder.EndAt.Add(paused)
+	} else {
+		algoOrder.NextSliceAt = time.Now()
+	}
+	algoOrder.Status = "running"
+	algoOrder.PausedAt = time.Time{}
+	if ok, err := e.db.UpdateAlgoOrder(algoOrder, "paused"); err != nil || !ok {
+		if err != nil {
+			return models.AlgoOrder{}, err
+		}
+		return models.AlgoOrder{}, ErrNotPaused
+	}
+
+	e.hub.BroadcastOrder(converter.AlgoOrderToModelsOrder(algoOrder))
+	return algoOrder, nil
+}
+
+// Cancel closes a running or paused algo order and cancels its resting child order
+func (e *Engine) Cancel(id int) (models.AlgoOrder, error) {
+	algoOrder, err := e.db.GetAlgoOrder(id)
+	if err != nil {
+		return models.AlgoOrder{}, err
+	}
+
+	previousStatus := algoOrder.Status
+	if previousStatus != "running" && previousStatus != "paused" {
+		return models.AlgoOrder{}, ErrClosed
+	}
+
+	algoOrder.Status = "canceled"
+	algoOrder.ClosedAt = time.Now()
+	if ok, err := e.db.UpdateAlgoOrder(algoOrder, previousStatus); err != nil || !ok {
+		if err != nil {
+			return models.AlgoOrder{}, err
+		}
+		return models.AlgoOrder{}, ErrClosed
+	}
+
+	e.stop(&algoOrder)
+	return algoOrder, nil
+}
+
+// stop cancels the resting child order of an algo order that was just paused or closed
+func (e *Engine) stop(algoOrder *models.AlgoOrder) {
+	if filled, err := e.cancelChildren(*algoOrder); err != nil {
+		e.logger.Error("failed to cancel child orders of algo order", "algoOrderID", algoOrder.ID, "error", err)
+	} else {
+		algoOrder.SizeFilled = decimal.Format(filled)
+		if err := e.db.UpdateAlgoOrderSizeFilled(algoOrder.ID, algoOrder.SizeFilled); err != nil {
+			e.logger.Error("failed to save filled size of algo order", "algoOrderID", algoOrder.ID, "error", err)
+		}
+	}
+
+	e.hub.BroadcastOrder(converter.AlgoOrderToModelsOrder(*algoOrder))
+}
+
+// OnOrderUpdate reports the fills of child orders as updates of their parent
+func (e *Engine) OnOrderUpdate(order models.Order) {
+	e.mu.Lock()
+	algoOrderID, ok := e.children[order.ID]
+	if ok && order.Status != "filling" {
+		delete(e.children, order.ID)
+	}
+	e.mu.Unlock()
+
+	if ok {
+		go e.onChildUpdate(algoOrderID, order)
+	}
+}
+
+// onChildUpdate refreshes the parent and replaces a filled iceberg slice. An iceberg slice
+// canceled while the iceberg is running was canceled by its owner, so the iceberg is canceled too.
+func (e *Engine) onChildUpdate(algoOrderID int, update models.Order) {
+	algoOrder, ok := e.refresh(algoOrderID, update)
+	if !ok || algoOrder.Type != "iceberg" || algoOrder.Status != "running" {
+		return
+	}
+
+	switch update.Status {
+	case "filled":
+		now := time.Now()
+		// a locked iceberg is being worked already, the slice is then replaced by the next check
+		claimed, ok, err := e.db.ClaimAlgoOrder(algoOrderID, now, now.Add(lockDuration))
+		if err != nil || !ok {
+			return
+		}
+		e.workIceberg(claimed, &update)
+
+	case "canceled":
+		algoOrder.Status = "canceled"
+		algoOrder.ClosedAt = time.Now()
+		if ok, err := e.db.UpdateAlgoOrder(algoOrder, "running"); err != nil || !ok {
+			return
+		}
+		e.hub.BroadcastOrder(converter.AlgoOrderToModelsOrder(algoOrder))
+	}
+}
+
+// refresh sums the fills of the children, update is newer than what the repository may hold
+func (e *Engine) refresh(algoOrderID int, update models.Order) (models.AlgoOrder, bool) {
+	e.refreshMu.Lock()
+	defer e.refreshMu.Unlock()
+
+	algoOrder, err := e.db.GetAlgoOrder(algoOrderID)
+	if err != nil {
+		e.logger.Error("failed to get algo order", "algoOrderID", algoOrderID, "error", err)
+		return models.AlgoOrder{}, false
+	}
+
+	children, err := e.childOrders(algoOrderID, &update)
+	if err != nil {
+		e.logger.Error("failed to get child orders of algo order", "algoOrderID", algoOrderID, "error", err)
+		return models.AlgoOrder{}, false
+	}
+
+	var filled = new(big.Rat)
+	for _, child := range children {
+		addFilled(filled, child)
+	}
+
+	sizeFilled := decimal.Format(filled)
+	if sizeFilled == algoOrder.SizeFilled {
+		return algoOrder, true
+	}
+
+	algoOrder.SizeFilled = sizeFilled
+	if err := e.db.UpdateAlgoOrderSizeFilled(algoOrderID, sizeFilled); err != nil {
+		e.logger.Error("failed to save filled size of algo order", "algoOrderID", algoOrderID, "error", err)
+		return models.AlgoOrder{}, false
+	}
+
+	if qty, _ := decimal.Parse(algoOrder.Qty); algoOrder.Status == "running" && filled.Cmp(qty) >= 0 {
+		algoOrder.Status = "completed"
+		algoOrder.ClosedAt = time.Now()
+		if ok, err := e.db.UpdateAlgoOrder(algoOrder, "running"); err != nil || !ok {
+			return models.AlgoOrder{}, false
+		}
+	}
+
+	e.hub.BroadcastOrder(converter.AlgoOrderToModelsOrder(algoOrder))
+	return algoOrder, true
+}
+
+// childOrders returns the child orders of the algo order with update in place of the stored one,
+// update may be nil
+func (e *Engine) childOrders(algoOrderID int, update *models.Order) ([]models.Order, error) {
+	children, err := e.db.GetAlgoOrderChildren(algoOrderID)
+	if err != nil || update == nil {
+		return children, err
+	}
+
+	for i := range children {
+		if children[i].ID == update.ID {
+			children[i] = *update
+			return children, nil
+		}
+	}
+	return append(children, *update), nil
+}
+
+func (e *Engine) runDue() {
+	now := time.Now()
+	algoOrders, err := e.db.ClaimDueAlgoOrders(now, now.Add(lockDuration), claimLimit)
+	if err != nil {
+		e.logger.Error("failed to claim algo orders", "error", err)
+		return
+	}
+
+	for _, algoOrder := range algoOrders {
+		// the algo orders left are claimed again by any gateway once the lock expires
+		if time.Until(algoOrder.LockedUntil) < workDuration {
+			return
+		}
+
+		switch algoOrder.Type {
+		case "twap":
+			e.workTWAP(algoOrder)
+		case "iceberg":
+			e.workIceberg(algoOrder, nil)
+		default:
+			e.logger.Error("unknown algo order type", "algoOrderID", algoOrder.ID, "type", algoOrder.Type)
+		}
+	}
+}
+
+// workTWAP runs the due slice of a claimed twap order. It is retried once the lock expires
+// if the matching engine or the database fails.
+func (e *Engine) workTWAP(algoOrder models.AlgoOrder) {
+	filled, err := e.cancelChildren(algoOrder)
+	if err != nil {
+		e.logger.Error("failed to cancel child orders of algo order", "algoOrderID", algoOrder.ID, "error", err)
+		return
+	}
+
+	qty, _ := decimal.Parse(algoOrder.Qty)
+	if filled.Cmp(qty) >= 0 || algoOrder.SlicesPlaced >= slicesCount(algoOrder) {
+		algoOrder.SizeFilled = decimal.Format(filled)
+		algoOrder.Status = "completed"
+		algoOrder.ClosedAt = time.Now()
+		if e.save(algoOrder, 0) {
+			e.hub.BroadcastOrder(converter.AlgoOrderToModelsOrder(algoOrder))
+		}
+		return
+	}
+
+	var childID int
+	if sliceQty := e.sliceQty(algoOrder, qty, filled); sliceQty.Sign() > 0 {
+		req := models.PlaceOrderReq{
+			UserID: algoOrder.UserID,
+			IsBid:  algoOrder.IsBid,
+			Pair:   algoOrder.Pair,
+			Qty:    decimal.Format(sliceQty),
+			Type:   "market",
+		}
+		if algoOrder.Price != "" {
+			req.Type = "limit"
+			req.Price = algoOrder.Price
+		}
+
+		order, ok := e.placeSlice(&algoOrder, req, filled)
+		if !ok {
+			return
+		}
+		childID = order.ID
+		addFilled(filled, order)
+	}
+
+	algoOrder.SizeFilled = decimal.Format(filled)
+	algoOrder.SlicesPlaced++
+	algoOrder.NextSliceAt = algoOrder.NextSliceAt.Add(algoOrder.SliceInterval)
+	if algoOrder.NextSliceAt.After(algoOrder.EndAt) {
+		algoOrder.NextSliceAt = algoOrder.EndAt
+	}
+	if filled.Cmp(qty) >= 0 {
+		algoOrder.Status = "completed"
+		algoOrder.ClosedAt = time.Now()
+	}
+	if e.save(algoOrder, childID) {
+		e.hub.BroadcastOrder(converter.AlgoOrderToModelsOrder(algoOrder))
+	}
+}
+
+// workIceberg places a slice of a claimed iceberg order unless one is resting, update is the
+// latest state of a child and may be nil. A slice filled on placement is replaced right away.
+func (e *Engine) workIceberg(algoOrder models.AlgoOrder, update *models.Order) {
+	children, err := e.childOrders(algoOrder.ID, update)
+	if err != nil {
+		e.logger.Error("failed to get child orders of algo order", "algoOrderID", algoOrder.ID, "error", err)
+		return
+	}
+
+	var (
+		filled  = new(big.Rat)
+		resting bool
+	)
+	for _, child := range children {
+		resting = resting || child.Status == "filling"
+		addFilled(filled, child)
+	}
+
+	qty, _ := decimal.Parse(algoOrder.Qty)
+	visibleQty, _ := decimal.Parse(algoOrder.VisibleQty)
+
+	// slices filled on placement are replaced while the lock lasts, the next check places the rest
+	var childID int
+	for !resting && filled.Cmp(qty) < 0 && time.Until(algoOrder.LockedUntil) >= workDuration {
+		sliceQty := new(big.Rat).Sub(qty, filled)
+		if sliceQty.Cmp(visibleQty) > 0 {
+			sliceQty.Set(visibleQty)
+		}
+
+		order, ok := e.placeSlice(&algoOrder, models.PlaceOrderReq{
+			UserID: algoOrder.UserID,
+			IsBid:  algoOrder.IsBid,
+			Pair:   algoOrder.Pair,
+			Price:  algoOrder.Price,
+			Qty:    decimal.Format(sliceQty),
+			Type:   "limit",
+		}, filled)
+		if !ok {
+			return
+		}
+
+		childID = order.ID
+		resting = order.Status == "filling"
+		algoOrder.SlicesPlaced++
+		addFilled(filled, order)
+	}
+
+	changed := algoOrder.SizeFilled != decimal.Format(filled) || childID != 0
+	algoOrder.SizeFilled = decimal.Format(filled)
+	algoOrder.NextSliceAt = time.Now().Add(icebergCheckInterval)
+	if filled.Cmp(qty) >= 0 {
+		algoOrder.Status = "completed"
+		algoOrder.ClosedAt = time.Now()
+		changed = true
+	}
+
+	if e.save(algoOrder, childID) && changed {
+		e.hub.BroadcastOrder(converter.AlgoOrderToModelsOrder(algoOrder))
+	}
+}
+
+// placeSlice places a child order of the algo order. A slice rejected by the matching engine fails the
+// algo order, as the next ones would be rejected as well. It returns false if nothing was placed.
+func (e *Engine) placeSlice(algoOrder *models.AlgoOrder, req models.PlaceOrderReq, filled *big.Rat) (models.Order, bool) {
+	place := e.router.PlaceOrder
+	if algoOrder.Type == "iceberg" {
+		place = e.router.PlaceHiddenOrder
+	}
+
+	order, err := place(req)
+	if err != nil {
+		st, ok := status.FromError(err)
+		if !ok || st.Code() == codes.Unavailable || st.Code() == codes.DeadlineExceeded {
+			// retried once the lock expires
+			e.logger.Error("failed to place slice of algo order", "algoOrderID", algoOrder.ID, "error", err)
+			return models.Order{}, false
+		}
+
+		algoOrder.SizeFilled = decimal.Format(filled)
+		algoOrder.Status = "failed"
+		algoOrder.Error = st.Message()
+		algoOrder.ClosedAt = time.Now()
+		if e.save(*algoOrder, 0) {
+			e.hub.BroadcastOrder(converter.AlgoOrderToModelsOrder(*algoOrder))
+		}
+		return models.Order{}, false
+	}
+
+	if err := e.db.AddAlgoOrderChild(algoOrder.ID, order.ID); err != nil {
+		e.logger.Error("failed to save child order of algo order", "algoOrderID", algoOrder.ID, "orderID", order.ID, "error", err)
+	}
+	if order.Status == "filling" {
+		e.mu.Lock()
+		e.children[order.ID] = algoOrder.ID
+		e.mu.Unlock()
+	}
+	return order, true
+}
+
+// save stores the worked algo order and releases its lock. If it was paused or canceled meanwhile,
+// or the lock expired and was taken by another gateway, the child order just placed is canceled instead
+// and false is returned.
+func (e *Engine) save(algoOrder models.AlgoOrder, childID int) bool {
+	ok, err := e.db.UpdateClaimedAlgoOrder(algoOrder)
+	if err != nil {
+		// the order is worked again once the lock expires
+		e.logger.Error("failed to update algo order", "algoOrderID", algoOrder.ID, "error", err)
+		return false
+	}
+
+	if !ok && childID != 0 {
+		if _, err := e.router.CancelOrder(childID); err != nil {
+			e.logger.Error("failed to cancel child order of stopped algo order", "algoOrderID", algoOrder.ID, "orderID", childID, "error", err)
+		}
+	}
+	return ok
+}
+
+// cancelChildren cancels the resting child orders and returns the filled qty of all children
+func (e *Engine) cancelChildren(algoOrder models.AlgoOrder) (*big.Rat, error) {
+	children, err := e.db.GetAlgoOrderChildren(algoOrder.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var filled = new(big.Rat)
+	for _, child := range children {
+		if child.Status == "filling" {
+			canceled, err := e.router.CancelOrder(child.ID)
+			if err != nil {
+				return nil, err
+			}
+			child = canceled
+		}
+		addFilled(filled, child)
+	}
+	return filled, nil
+}
+
+// sliceQty is what the filled qty lacks behind the even split of the total up to the current slice,
+// rounded down to the lot size. The last slice takes the whole rest.
+func (e *Engine) sliceQty(algoOrder models.AlgoOrder, qty, filled *big.Rat) *big.Rat {
+	count := slicesCount(algoOrder)
+
+	target := new(big.Rat).Set(qty)
+	if algoOrder.SlicesPlaced+1 < count {
+		target.Mul(target, big.NewRat(int64(algoOrder.SlicesPlaced+1), int64(count)))
+		if pairParams, ok := e.reconciler.PairParams(algoOrder.Pair); ok {
+			lotSize := decimal.Step(pairParams.QtyPrecision)
+			lots := new(big.Rat).Quo(target, lotSize)
+			lots.SetInt(new(big.Int).Quo(lots.Num(), lots.Denom()))
+			target.Mul(lots, lotSize)
+		}
+	}
+
+	sliceQty := target.Sub(target, filled)
+	if sliceQty.Sign() < 0 {
+		sliceQty.SetInt64(0)
+	}
+	return sliceQty
+}
+
+func slicesCount(algoOrder models.AlgoOrder) int {
+	return int((algoOrder.Duration + algoOrder.SliceInterval - 1) / algoOrder.SliceInterval)
+}
+
+func addFilled(filled *big.Rat, order models.Order) {
+	if sizeFilled, ok := decimal.Parse(order.SizeFilled); ok {
+		filled.Add(filled, sizeFilled)
+	}
+}
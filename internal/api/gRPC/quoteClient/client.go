@@ -1,64 +1,116 @@
-package qClient
-
-import (
-	"context"
-	"log/slog"
-
-	ws "github.com/BazaarTrade/ApiGatewayService/internal/api/websocket"
-	"github.com/BazaarTrade/QuoteProtoGen/pbQ"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
-)
-
-type Client struct {
-	client       pbQ.QuoteClient
-	conn         *grpc.ClientConn
-	hub          *ws.Hub
-	ctx          context.Context
-	cancel       context.CancelFunc
-	cancelByPair map[string]context.CancelFunc
-	logger       *slog.Logger
-}
-
-func New(hub *ws.Hub, logger *slog.Logger) *Client {
-	ctx, cancel := context.WithCancel(context.Background())
-	return &Client{
-		hub:          hub,
-		ctx:          ctx,
-		cancel:       cancel,
-		cancelByPair: make(map[string]context.CancelFunc),
-		logger:       logger,
-	}
-}
-
-func (c *Client) Run(CONN_ADDR_QUOTE string) error {
-	var err error
-	c.conn, err = grpc.NewClient(CONN_ADDR_QUOTE, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		c.logger.Error("failed connecting to localhost:50052", "error", err)
-		return err
-	}
-
-	c.client = pbQ.NewQuoteClient(c.conn)
-
-	return nil
-}
-
-func (c *Client) CloseConnection() {
-	if c.cancel != nil {
-		c.cancel()
-	}
-
-	if c.conn != nil {
-		if err := c.conn.Close(); err != nil {
-			c.logger.Error("failed to close qClient connection", "error", err)
-		}
-	}
-}
-
-func (c *Client) StopStreamReadersByPair(pair string) {
-	if cancel, ok := c.cancelByPair[pair]; ok {
-		cancel()
-		delete(c.cancelByPair, pair)
-	}
-}
+package qClient
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	ws "github.com/BazaarTrade/ApiGatewayService/internal/api/websocket"
+	"github.com/BazaarTrade/ApiGatewayService/internal/marketData"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/watchdog"
+	"github.com/BazaarTrade/QuoteProtoGen/pbQ"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+var ErrNotConnected = errors.New("quote client is not connected")
+
+// Listener receives every converted quote stream message in addition to the hub and the cache
+type Listener interface {
+	OnOrderBookSnapshot(pOBS models.OrderBookSnapshot, precision int32)
+	OnTrades(trades []models.Trade)
+	OnTicker(ticker models.Ticker)
+	OnCandleStick(candleStick models.CandleStick)
+}
+
+type Client struct {
+	client      pbQ.QuoteClient
+	conn        *grpc.ClientConn
+	hub         *ws.Hub
+	cache       *marketData.Cache
+	watchdog    *watchdog.Watchdog
+	listeners   []Listener
+	ctx         context.Context
+	cancel      context.CancelFunc
+	readers     map[string]*pairReaders
+	lazy        bool
+	gracePeriod time.Duration
+	pinned      map[string]bool
+	// streams pinned for single pairs by stream and pair
+	pairPins map[string]map[string]bool
+	mu       sync.Mutex
+	logger   *slog.Logger
+}
+
+func New(hub *ws.Hub, cache *marketData.Cache, watchdog *watchdog.Watchdog, logger *slog.Logger) *Client {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Client{
+		hub:      hub,
+		cache:    cache,
+		watchdog: watchdog,
+		ctx:      ctx,
+		cancel:   cancel,
+		readers:  make(map[string]*pairReaders),
+		pinned:   make(map[string]bool),
+		pairPins: make(map[string]map[string]bool),
+		logger:   logger,
+	}
+}
+
+func (c *Client) Run(CONN_ADDR_QUOTE string, opts ...grpc.DialOption) error {
+	var err error
+	c.conn, err = grpc.NewClient(CONN_ADDR_QUOTE, append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, opts...)...)
+	if err != nil {
+		c.logger.Error("failed connecting to localhost:50052", "error", err)
+		return err
+	}
+
+	c.client = pbQ.NewQuoteClient(c.conn)
+
+	return nil
+}
+
+// AddListener must be called before stream readers are started. In lazy mode listeners only receive
+// the streams that are open, the ones they depend on have to be pinned.
+func (c *Client) AddListener(listener Listener) {
+	c.listeners = append(c.listeners, listener)
+}
+
+func (c *Client) CloseConnection() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+
+	if c.conn != nil {
+		if err := c.conn.Close(); err != nil {
+			c.logger.Error("failed to close qClient connection", "error", err)
+		}
+	}
+}
+
+func (c *Client) StopStreamReadersByPair(pair string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if readers, ok := c.readers[pair]; ok {
+		readers.cancel()
+		for _, reader := range readers.streams {
+			reader.stopIdle()
+		}
+		delete(c.readers, pair)
+	}
+}
+
+func (c *Client) RunningPairs() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var pairs = make([]string, 0, len(c.readers))
+	for pair := range c.readers {
+		pairs = append(pairs, pair)
+	}
+	return pairs
+}
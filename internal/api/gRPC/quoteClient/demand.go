@@ -0,0 +1,157 @@
+package qClient
+
+import (
+	"context"
+	"time"
+)
+
+// streams are named after the websocket topics they feed
+var streams = []string{"orderBook", "trades", "ticker", "candleStick"}
+
+type pairReaders struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	streams map[string]*streamReader
+}
+
+type streamReader struct {
+	cancel    context.CancelFunc
+	stopTimer *time.Timer
+	// generation invalidates a stop timer that fired while the stream got demand again
+	generation int
+}
+
+func (r *streamReader) stopIdle() {
+	if r.stopTimer != nil {
+		r.stopTimer.Stop()
+		r.stopTimer = nil
+	}
+	r.generation++
+}
+
+// SetLazy switches to demand driven streams: a stream is opened when the first client subscribes
+// to its topic and closed after gracePeriod once the last one left. It must be called before Run.
+func (c *Client) SetLazy(gracePeriod time.Duration) {
+	c.lazy = true
+	c.gracePeriod = gracePeriod
+}
+
+// PinStream keeps the stream open for every pair regardless of subscribers,
+// for streams that internal consumers such as the market data cache depend on.
+func (c *Client) PinStream(stream string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pinned[stream] = true
+	for pair, readers := range c.readers {
+		c.startStream(readers, pair, stream)
+	}
+}
+
+// SetPairStreamPinned keeps the stream of a single pair open regardless of subscribers
+// while pinned, for internal consumers that only need some pairs.
+func (c *Client) SetPairStreamPinned(pair, stream string, pinned bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pairs, ok := c.pairPins[stream]
+	if !ok {
+		pairs = make(map[string]bool)
+		c.pairPins[stream] = pairs
+	}
+	if pinned {
+		pairs[pair] = true
+	} else {
+		delete(pairs, pair)
+	}
+
+	if c.lazy {
+		c.updateStream(pair, stream)
+	}
+}
+
+// isPinned must be called with c.mu held
+func (c *Client) isPinned(pair, stream string) bool {
+	return c.pinned[stream] || c.pairPins[stream][pair]
+}
+
+// OnDemandChanged is the hub demand handler, it opens or schedules closing of the stream
+// depending on the current number of subscribers.
+func (c *Client) OnDemandChanged(topic, pair string) {
+	if !c.lazy {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.updateStream(pair, topic)
+}
+
+// updateStream opens the stream while it is pinned or subscribed and schedules closing it otherwise,
+// it must be called with c.mu held
+func (c *Client) updateStream(pair, stream string) {
+	readers, ok := c.readers[pair]
+	if !ok {
+		return
+	}
+
+	if c.isPinned(pair, stream) || c.hub.SubscribersCount(stream, pair) > 0 {
+		c.startStream(readers, pair, stream)
+		return
+	}
+
+	reader, ok := readers.streams[stream]
+	if !ok || reader.stopTimer != nil {
+		return
+	}
+
+	generation := reader.generation
+	reader.stopTimer = time.AfterFunc(c.gracePeriod, func() {
+		c.stopIdleStream(pair, stream, reader, generation)
+	})
+}
+
+func (c *Client) stopIdleStream(pair, stream string, reader *streamReader, generation int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	readers, ok := c.readers[pair]
+	if !ok || readers.streams[stream] != reader || reader.generation != generation {
+		return
+	}
+
+	if c.isPinned(pair, stream) || c.hub.SubscribersCount(stream, pair) > 0 {
+		reader.stopIdle()
+		return
+	}
+
+	reader.cancel()
+	delete(readers.streams, stream)
+
+	// the cached data is not updated any more until the stream is opened again
+	c.cache.SetStale(pair, stream, true)
+	c.logger.Info("closed idle quote stream", "pair", pair, "stream", stream)
+}
+
+// startStream must be called with c.mu held
+func (c *Client) startStream(readers *pairReaders, pair, stream string) {
+	if reader, ok := readers.streams[stream]; ok {
+		reader.stopIdle()
+		return
+	}
+
+	ctx, cancel := context.WithCancel(readers.ctx)
+	readers.streams[stream] = &streamReader{cancel: cancel}
+
+	switch stream {
+	case "orderBook":
+		go c.readPrecisedOrderBookSnapshots(ctx, pair)
+	case "trades":
+		go c.readPrecisedTrade(ctx, pair)
+	case "ticker":
+		go c.readTicker(ctx, pair)
+	case "candleStick":
+		go c.readCandleStick(ctx, pair)
+	}
+}
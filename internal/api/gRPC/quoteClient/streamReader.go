@@ -1,232 +1,288 @@
-package qClient
-
-import (
-	"context"
-	"errors"
-	"io"
-	"time"
-
-	"github.com/BazaarTrade/ApiGatewayService/internal/converter"
-	"github.com/BazaarTrade/QuoteProtoGen/pbQ"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
-)
-
-var retry = time.Second * 5
-
-func (c *Client) StartStreamReaders(pair string) {
-	ctx, cancel := context.WithCancel(c.ctx)
-	c.cancelByPair[pair] = cancel
-	go c.readPrecisedOrderBookSnapshots(ctx, pair)
-	go c.readPrecisedTrade(ctx, pair)
-	go c.readCandleStick(ctx, pair)
-	go c.readTicker(ctx, pair)
-}
-
-func (c *Client) readPrecisedOrderBookSnapshots(ctx context.Context, pair string) {
-	for {
-		select {
-		case <-ctx.Done():
-			c.logger.Info("stopped pOBSs stream reader", "pair", pair)
-			return
-		default:
-		}
-
-		stream, err := c.client.StreamPrecisedOrderBookSnapshots(ctx, &pbQ.Pair{Pair: pair})
-		if err != nil {
-			c.logger.Warn("failed to connect to pbQ pOBSs stream", "pair", pair)
-			time.Sleep(retry)
-			continue
-		}
-
-		c.logger.Info("successfully connected to pOBSs stream", "pair", pair)
-
-		for {
-			select {
-			case <-ctx.Done():
-				c.logger.Info("stopped pOBSs stream reader", "pair", pair)
-				return
-			default:
-			}
-
-			pOBSs, err := stream.Recv()
-			if err != nil {
-				if errors.Is(err, io.EOF) {
-					c.logger.Info("pOBSs stream closed by server", "pair", pair)
-					time.Sleep(retry)
-					break
-				}
-
-				if status.Code(err) == codes.Canceled {
-					c.logger.Info("stopped pOBSs stream reader", "pair", pair)
-					return
-				}
-
-				c.logger.Error("failed to receive pOBSs", "pair", pair, "error", err, "status", status.Code(err))
-				time.Sleep(retry)
-				break
-			}
-
-			c.logger.Debug("recieved pOBSs", "pair", pair)
-
-			for orderBookprecision, pbPOBS := range pOBSs.PrecisedOrderBookSnapshot {
-				go c.hub.BroadcastPrecisedOrderBookSnapshot(converter.PbQOBSToModelsOBS(pbPOBS), orderBookprecision)
-			}
-		}
-	}
-}
-
-func (c *Client) readPrecisedTrade(ctx context.Context, pair string) {
-	for {
-		select {
-		case <-ctx.Done():
-			c.logger.Info("stopped precised trades stream reader", "pair", pair)
-			return
-		default:
-		}
-
-		stream, err := c.client.StreamPrecisedTrades(ctx, &pbQ.Pair{Pair: pair})
-		if err != nil {
-			c.logger.Warn("failed to connect to pbQ precised trade stream", "pair", pair)
-			time.Sleep(retry)
-			continue
-		}
-
-		c.logger.Info("successfully connected to pbQ precised trade stream", "pair", pair)
-
-		for {
-			select {
-			case <-ctx.Done():
-				c.logger.Info("stopped precised trades stream reader", "pair", pair)
-				return
-			default:
-			}
-
-			pbQPrecisedTrades, err := stream.Recv()
-			if err != nil {
-				if errors.Is(err, io.EOF) {
-					c.logger.Info("precised trade stream closed by server", "pair", pair)
-					time.Sleep(retry)
-					break
-				}
-
-				if status.Code(err) == codes.Canceled {
-					c.logger.Info("stopped precised trades stream reader", "pair", pair)
-					return
-				}
-
-				c.logger.Error("failed to receive precised trade", "pair", pair, "error", err, "status", status.Code(err))
-				time.Sleep(retry)
-				break
-			}
-
-			c.logger.Debug("recieved precised trade", "pair", pair)
-
-			go c.hub.BroadcastPrecisedTrades(converter.PbQTradeToModelsTrade(pbQPrecisedTrades))
-		}
-	}
-
-}
-
-func (c *Client) readTicker(ctx context.Context, pair string) {
-	for {
-		select {
-		case <-ctx.Done():
-			c.logger.Info("stopped ticker stream reader", "pair", pair)
-			return
-		default:
-		}
-
-		stream, err := c.client.StreamTicker(ctx, &pbQ.Pair{Pair: pair})
-		if err != nil {
-			c.logger.Warn("failed to connect to pbQ ticker stream", "pair", pair)
-			time.Sleep(retry)
-			continue
-		}
-
-		c.logger.Info("successfully connected to ticker stream", "pair", pair)
-
-		for {
-			select {
-			case <-ctx.Done():
-				c.logger.Info("stopped ticker stream reader", "pair", pair)
-				return
-			default:
-			}
-
-			pbQTicker, err := stream.Recv()
-			if err != nil {
-				if errors.Is(err, io.EOF) {
-					c.logger.Info("ticker stream closed by server", "pair", pair)
-					time.Sleep(retry)
-					break
-				}
-
-				if status.Code(err) == codes.Canceled {
-					c.logger.Info("stopped ticker stream reader", "pair", pair)
-					return
-				}
-
-				c.logger.Error("failed to receive ticker", "error", err)
-				time.Sleep(retry)
-				break
-			}
-
-			c.logger.Debug("recieved ticker", "pair", pair)
-
-			go c.hub.BroadcastTicker(converter.PbQTickerToModelsTicker(pbQTicker))
-		}
-	}
-
-}
-
-func (c *Client) readCandleStick(ctx context.Context, pair string) {
-	for {
-		select {
-		case <-ctx.Done():
-			c.logger.Info("stopped candleStick stream reader", "pair", pair)
-			return
-		default:
-		}
-
-		stream, err := c.client.StreamCandleStick(ctx, &pbQ.Pair{Pair: pair})
-		if err != nil {
-			c.logger.Warn("failed to connect to pbQ candle stick stream", "pair", pair)
-			time.Sleep(retry)
-			continue
-		}
-
-		c.logger.Info("successfully connected to candleStick stream", "pair", pair)
-
-		for {
-			select {
-			case <-ctx.Done():
-				c.logger.Info("stopped candleStick stream reader", "pair", pair)
-				return
-			default:
-			}
-
-			pbQCandleStick, err := stream.Recv()
-			if err != nil {
-				if errors.Is(err, io.EOF) {
-					c.logger.Info("candle stick stream closed by server", "pair", pair)
-					time.Sleep(retry)
-					break
-				}
-
-				if status.Code(err) == codes.Canceled {
-					c.logger.Info("stopped candleStick stream reader", "pair", pair)
-					return
-				}
-
-				c.logger.Error("failed to receive candle stick", "error", err)
-				time.Sleep(retry)
-				break
-			}
-
-			c.logger.Debug("recieved candleStick", "pair", pair)
-
-			go c.hub.BroadcastCandleStick(converter.PbQCandleStickToModelsCandleStick(pbQCandleStick))
-		}
-	}
-}
+package qClient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/converter"
+	"github.com/BazaarTrade/QuoteProtoGen/pbQ"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var retry = time.Second * 5
+
+// StartStreamReaders is a no-op if readers for the pair are already running
+// or if the client is not connected, e.g. while market data is replayed from a recording
+func (c *Client) StartStreamReaders(pair string) {
+	if c.client == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.readers[pair]; ok {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	readers := &pairReaders{
+		ctx:     ctx,
+		cancel:  cancel,
+		streams: make(map[string]*streamReader),
+	}
+	c.readers[pair] = readers
+
+	for _, stream := range streams {
+		if !c.lazy || c.isPinned(pair, stream) || c.hub.SubscribersCount(stream, pair) > 0 {
+			c.startStream(readers, pair, stream)
+		}
+	}
+}
+
+func (c *Client) readPrecisedOrderBookSnapshots(ctx context.Context, pair string) {
+	defer c.watchdog.Forget(pair, "orderBook")
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.logger.Info("stopped pOBSs stream reader", "pair", pair)
+			return
+		default:
+		}
+
+		stream, err := c.client.StreamPrecisedOrderBookSnapshots(ctx, &pbQ.Pair{Pair: pair})
+		if err != nil {
+			c.logger.Warn("failed to connect to pbQ pOBSs stream", "pair", pair)
+			time.Sleep(retry)
+			continue
+		}
+
+		c.logger.Info("successfully connected to pOBSs stream", "pair", pair)
+		c.watchdog.Watch(pair, "orderBook")
+
+		for {
+			select {
+			case <-ctx.Done():
+				c.logger.Info("stopped pOBSs stream reader", "pair", pair)
+				return
+			default:
+			}
+
+			pOBSs, err := stream.Recv()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					c.logger.Info("pOBSs stream closed by server", "pair", pair)
+					time.Sleep(retry)
+					break
+				}
+
+				if status.Code(err) == codes.Canceled {
+					c.logger.Info("stopped pOBSs stream reader", "pair", pair)
+					return
+				}
+
+				c.logger.Error("failed to receive pOBSs", "pair", pair, "error", err, "status", status.Code(err))
+				time.Sleep(retry)
+				break
+			}
+
+			c.logger.Debug("recieved pOBSs", "pair", pair)
+			c.watchdog.Touch(pair, "orderBook")
+
+			for orderBookprecision, pbPOBS := range pOBSs.PrecisedOrderBookSnapshot {
+				pOBS := converter.PbQOBSToModelsOBS(pbPOBS)
+				c.cache.SetOrderBookSnapshot(pOBS, orderBookprecision)
+				for _, listener := range c.listeners {
+					listener.OnOrderBookSnapshot(pOBS, orderBookprecision)
+				}
+				c.hub.BroadcastPrecisedOrderBookSnapshot(pOBS, orderBookprecision)
+			}
+		}
+	}
+}
+
+func (c *Client) readPrecisedTrade(ctx context.Context, pair string) {
+	defer c.watchdog.Forget(pair, "trades")
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.logger.Info("stopped precised trades stream reader", "pair", pair)
+			return
+		default:
+		}
+
+		stream, err := c.client.StreamPrecisedTrades(ctx, &pbQ.Pair{Pair: pair})
+		if err != nil {
+			c.logger.Warn("failed to connect to pbQ precised trade stream", "pair", pair)
+			time.Sleep(retry)
+			continue
+		}
+
+		c.logger.Info("successfully connected to pbQ precised trade stream", "pair", pair)
+		c.watchdog.Watch(pair, "trades")
+
+		for {
+			select {
+			case <-ctx.Done():
+				c.logger.Info("stopped precised trades stream reader", "pair", pair)
+				return
+			default:
+			}
+
+			pbQPrecisedTrades, err := stream.Recv()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					c.logger.Info("precised trade stream closed by server", "pair", pair)
+					time.Sleep(retry)
+					break
+				}
+
+				if status.Code(err) == codes.Canceled {
+					c.logger.Info("stopped precised trades stream reader", "pair", pair)
+					return
+				}
+
+				c.logger.Error("failed to receive precised trade", "pair", pair, "error", err, "status", status.Code(err))
+				time.Sleep(retry)
+				break
+			}
+
+			c.logger.Debug("recieved precised trade", "pair", pair)
+			c.watchdog.Touch(pair, "trades")
+
+			precisedTrades := converter.PbQTradeToModelsTrade(pbQPrecisedTrades)
+			c.cache.AddTrades(precisedTrades)
+			for _, listener := range c.listeners {
+				listener.OnTrades(precisedTrades)
+			}
+			c.hub.BroadcastPrecisedTrades(precisedTrades)
+		}
+	}
+
+}
+
+func (c *Client) readTicker(ctx context.Context, pair string) {
+	defer c.watchdog.Forget(pair, "ticker")
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.logger.Info("stopped ticker stream reader", "pair", pair)
+			return
+		default:
+		}
+
+		stream, err := c.client.StreamTicker(ctx, &pbQ.Pair{Pair: pair})
+		if err != nil {
+			c.logger.Warn("failed to connect to pbQ ticker stream", "pair", pair)
+			time.Sleep(retry)
+			continue
+		}
+
+		c.logger.Info("successfully connected to ticker stream", "pair", pair)
+		c.watchdog.Watch(pair, "ticker")
+
+		for {
+			select {
+			case <-ctx.Done():
+				c.logger.Info("stopped ticker stream reader", "pair", pair)
+				return
+			default:
+			}
+
+			pbQTicker, err := stream.Recv()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					c.logger.Info("ticker stream closed by server", "pair", pair)
+					time.Sleep(retry)
+					break
+				}
+
+				if status.Code(err) == codes.Canceled {
+					c.logger.Info("stopped ticker stream reader", "pair", pair)
+					return
+				}
+
+				c.logger.Error("failed to receive ticker", "error", err)
+				time.Sleep(retry)
+				break
+			}
+
+			c.logger.Debug("recieved ticker", "pair", pair)
+			c.watchdog.Touch(pair, "ticker")
+
+			ticker := converter.PbQTickerToModelsTicker(pbQTicker)
+			c.cache.SetTicker(ticker)
+			for _, listener := range c.listeners {
+				listener.OnTicker(ticker)
+			}
+			c.hub.BroadcastTicker(ticker)
+		}
+	}
+
+}
+
+func (c *Client) readCandleStick(ctx context.Context, pair string) {
+	defer c.watchdog.Forget(pair, "candleStick")
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.logger.Info("stopped candleStick stream reader", "pair", pair)
+			return
+		default:
+		}
+
+		stream, err := c.client.StreamCandleStick(ctx, &pbQ.Pair{Pair: pair})
+		if err != nil {
+			c.logger.Warn("failed to connect to pbQ candle stick stream", "pair", pair)
+			time.Sleep(retry)
+			continue
+		}
+
+		c.logger.Info("successfully connected to candleStick stream", "pair", pair)
+		c.watchdog.Watch(pair, "candleStick")
+
+		for {
+			select {
+			case <-ctx.Done():
+				c.logger.Info("stopped candleStick stream reader", "pair", pair)
+				return
+			default:
+			}
+
+			pbQCandleStick, err := stream.Recv()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					c.logger.Info("candle stick stream closed by server", "pair", pair)
+					time.Sleep(retry)
+					break
+				}
+
+				if status.Code(err) == codes.Canceled {
+					c.logger.Info("stopped candleStick stream reader", "pair", pair)
+					return
+				}
+
+				c.logger.Error("failed to receive candle stick", "error", err)
+				time.Sleep(retry)
+				break
+			}
+
+			c.logger.Debug("recieved candleStick", "pair", pair)
+			c.watchdog.Touch(pair, "candleStick")
+
+			candleStick := converter.PbQCandleStickToModelsCandleStick(pbQCandleStick)
+			c.cache.SetCandleStick(candleStick)
+			for _, listener := range c.listeners {
+				listener.OnCandleStick(candleStick)
+			}
+			c.hub.BroadcastCandleStick(candleStick)
+		}
+	}
+}
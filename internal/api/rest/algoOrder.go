@@ -0,0 +1,209 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/algoOrder"
+	"github.com/BazaarTrade/ApiGatewayService/internal/converter"
+	"github.com/BazaarTrade/ApiGatewayService/internal/middleware"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/orderValidator"
+	"github.com/BazaarTrade/ApiGatewayService/internal/repository"
+	"github.com/labstack/echo/v4"
+)
+
+type algoOrderResponse struct {
+	models.Order
+	Children []models.Order `json:"children"`
+}
+
+func (s *Server) placeAlgoOrder(c echo.Context) error {
+	var req models.AlgoOrderReq
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid request format",
+		})
+	}
+
+	userID, _ := middleware.UserID(c)
+	if req.UserID == 0 {
+		req.UserID = userID
+	}
+
+	if req.UserID != userID {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "placing orders for another user is forbidden",
+		})
+	}
+
+	pairParams, _ := s.reconciler.PairParams(req.Pair)
+	if fieldErrors := orderValidator.ValidateAlgoOrder(req, pairParams); len(fieldErrors) > 0 {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error":  "invalid order",
+			"fields": fieldErrors,
+		})
+	}
+
+	if ok, err := s.allowRate(c, "place", 1); !ok {
+		return err
+	}
+
+	// the algo order counts as one open order, its slices do not count again
+	if orderErr := s.checkOpenOrdersCap(req.UserID, req.Pair, 1, 0); orderErr != nil {
+		return c.JSON(orderErr.status, orderErr.response())
+	}
+
+	// slices are checked one by one when they are placed, the limits apply to the total as well
+	total := models.PlaceOrderReq{
+		UserID: req.UserID,
+		IsBid:  req.IsBid,
+		Pair:   req.Pair,
+		Price:  req.Price,
+		Qty:    req.Qty,
+		Type:   "market",
+	}
+	if req.Price != "" {
+		total.Type = "limit"
+	}
+	if orderErr := s.checkRisk(total, 0); orderErr != nil {
+		return c.JSON(orderErr.status, orderErr.response())
+	}
+
+	created, err := s.algoOrderEngine.Create(req)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+	return c.JSON(http.StatusOK, algoOrderResponse{
+		Order:    converter.AlgoOrderToModelsOrder(created),
+		Children: []models.Order{},
+	})
+}
+
+// getAlgoOrder returns the algo order of the user together with its child orders
+func (s *Server) getAlgoOrder(c echo.Context) error {
+	existing, ok, err := s.userAlgoOrder(c)
+	if !ok {
+		return err
+	}
+
+	children, err := s.db.GetAlgoOrderChildren(existing.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+
+	if children == nil {
+		children = []models.Order{}
+	}
+	return c.JSON(http.StatusOK, algoOrderResponse{
+		Order:    converter.AlgoOrderToModelsOrder(existing),
+		Children: children,
+	})
+}
+
+// getAlgoOrders lists every algo order of the authenticated user
+func (s *Server) getAlgoOrders(c echo.Context) error {
+	userID, _ := middleware.UserID(c)
+
+	orders, err := s.algoOrders(userID, false)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+
+	if orders == nil {
+		orders = []models.Order{}
+	}
+	return c.JSON(http.StatusOK, orders)
+}
+
+func (s *Server) pauseAlgoOrder(c echo.Context) error {
+	return s.changeAlgoOrder(c, s.algoOrderEngine.Pause)
+}
+
+func (s *Server) resumeAlgoOrder(c echo.Context) error {
+	return s.changeAlgoOrder(c, s.algoOrderEngine.Resume)
+}
+
+func (s *Server) cancelAlgoOrder(c echo.Context) error {
+	return s.changeAlgoOrder(c, s.algoOrderEngine.Cancel)
+}
+
+// changeAlgoOrder pauses, resumes or cancels the algo order of the user
+func (s *Server) changeAlgoOrder(c echo.Context, change func(id int) (models.AlgoOrder, error)) error {
+	existing, ok, err := s.userAlgoOrder(c)
+	if !ok {
+		return err
+	}
+
+	if ok, err := s.allowRate(c, "cancel", 1); !ok {
+		return err
+	}
+
+	changed, err := change(existing.ID)
+	if err != nil {
+		if errors.Is(err, algoOrder.ErrNotRunning) || errors.Is(err, algoOrder.ErrNotPaused) || errors.Is(err, algoOrder.ErrClosed) {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+	return c.JSON(http.StatusOK, converter.AlgoOrderToModelsOrder(changed))
+}
+
+// userAlgoOrder gets the algo order of the path if it belongs to the authenticated user,
+// otherwise the error response is already written
+func (s *Server) userAlgoOrder(c echo.Context) (models.AlgoOrder, bool, error) {
+	id, err := strconv.Atoi(c.Param("algoOrderID"))
+	if err != nil || id < 1 {
+		return models.AlgoOrder{}, false, c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid algoOrderId",
+		})
+	}
+
+	existing, err := s.db.GetAlgoOrder(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return models.AlgoOrder{}, false, c.JSON(http.StatusNotFound, map[string]string{
+				"error": "order not found",
+			})
+		}
+		return models.AlgoOrder{}, false, c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+
+	if userID, _ := middleware.UserID(c); existing.UserID != userID {
+		return models.AlgoOrder{}, false, c.JSON(http.StatusForbidden, map[string]string{
+			"error": "access to another user's orders is forbidden",
+		})
+	}
+	return existing, true, nil
+}
+
+// algoOrders lists the algo orders of the user as orders, optionally only the running and paused ones
+func (s *Server) algoOrders(userID int, openOnly bool) ([]models.Order, error) {
+	algoOrders, err := s.db.GetAlgoOrdersByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []models.Order
+	for _, algoOrder := range algoOrders {
+		if openOnly && algoOrder.Status != "running" && algoOrder.Status != "paused" {
+			continue
+		}
+		orders = append(orders, converter.AlgoOrderToModelsOrder(algoOrder))
+	}
+	return orders, nil
+}
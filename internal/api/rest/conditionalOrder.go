@@ -0,0 +1,101 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/conditionalOrder"
+	"github.com/BazaarTrade/ApiGatewayService/internal/converter"
+	"github.com/BazaarTrade/ApiGatewayService/internal/middleware"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/repository"
+	"github.com/labstack/echo/v4"
+)
+
+// submitConditionalOrder stores a checked stop or trailing stop order until it is triggered
+func (s *Server) submitConditionalOrder(req models.PlaceOrderReq, idempotencyKey string) (models.Order, *orderError) {
+	if req.ClientOrderID != "" || idempotencyKey != "" {
+		return models.Order{}, &orderError{
+			status:  http.StatusBadRequest,
+			message: "clientOrderId and Idempotency-Key are not supported for conditional orders",
+		}
+	}
+
+	ticker, _ := s.cache.Ticker(req.Pair)
+	created, err := s.conditionalOrderEngine.Create(req, ticker.LastPrice)
+	if err != nil {
+		if errors.Is(err, conditionalOrder.ErrNoLastPrice) || errors.Is(err, conditionalOrder.ErrWouldTrigger) {
+			return models.Order{}, &orderError{
+				status:  http.StatusBadRequest,
+				message: err.Error(),
+			}
+		}
+		return models.Order{}, &orderError{
+			status:  http.StatusInternalServerError,
+			message: "internal error in database",
+		}
+	}
+	return converter.ConditionalOrderToModelsOrder(created), nil
+}
+
+func (s *Server) cancelConditionalOrder(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("conditionalOrderID"))
+	if err != nil || id < 1 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid conditionalOrderId",
+		})
+	}
+
+	existing, err := s.db.GetConditionalOrder(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "order not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+
+	if userID, _ := middleware.UserID(c); existing.UserID != userID {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "access to another user's orders is forbidden",
+		})
+	}
+
+	if ok, err := s.allowRate(c, "cancel", 1); !ok {
+		return err
+	}
+
+	canceled, err := s.conditionalOrderEngine.Cancel(id)
+	if err != nil {
+		if errors.Is(err, conditionalOrder.ErrNotCancelable) {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+	return c.JSON(http.StatusOK, converter.ConditionalOrderToModelsOrder(canceled))
+}
+
+// conditionalOrders lists the conditional orders of the user as orders, optionally only the untriggered ones
+func (s *Server) conditionalOrders(userID int, untriggeredOnly bool) ([]models.Order, error) {
+	conditionalOrders, err := s.db.GetConditionalOrdersByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []models.Order
+	for _, conditionalOrder := range conditionalOrders {
+		if untriggeredOnly && conditionalOrder.Status != "untriggered" {
+			continue
+		}
+		orders = append(orders, converter.ConditionalOrderToModelsOrder(conditionalOrder))
+	}
+	return orders, nil
+}
@@ -0,0 +1,165 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/algoOrder"
+	"github.com/BazaarTrade/ApiGatewayService/internal/conditionalOrder"
+	"github.com/BazaarTrade/ApiGatewayService/internal/middleware"
+	"github.com/BazaarTrade/ApiGatewayService/internal/orderGroup"
+	"github.com/BazaarTrade/ApiGatewayService/internal/tokenManager"
+	"github.com/labstack/echo/v4"
+)
+
+var maxCancelAllAfterTimeout = time.Hour
+
+type cancelAllAfterReq struct {
+	// Timeout is in milliseconds, 0 disarms the deadline
+	Timeout int64 `json:"timeout"`
+}
+
+type cancelAllAfterResponse struct {
+	Timeout     int64  `json:"timeout"`
+	TriggerTime string `json:"triggerTime,omitempty"`
+}
+
+// cancelAllAfter is the dead man's switch: everything of the user is canceled unless
+// the call is repeated before the timeout. The deadline is kept by the scheduler,
+// so it holds across gateway restarts.
+func (s *Server) cancelAllAfter(c echo.Context) error {
+	var req cancelAllAfterReq
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid request",
+		})
+	}
+
+	timeout := time.Duration(req.Timeout) * time.Millisecond
+	if req.Timeout != 0 && (timeout < time.Second || timeout > maxCancelAllAfterTimeout) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "timeout must be 0 or between 1000 and " + strconv.FormatInt(maxCancelAllAfterTimeout.Milliseconds(), 10) + " milliseconds",
+		})
+	}
+
+	if ok, err := s.allowRate(c, "cancel", 1); !ok {
+		return err
+	}
+
+	userID, _ := middleware.UserID(c)
+
+	triggerTime, err := s.scheduler.CancelAllAfter(userID, timeout)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+
+	var response = cancelAllAfterResponse{Timeout: req.Timeout}
+	if !triggerTime.IsZero() {
+		response.TriggerTime = triggerTime.UTC().Format(time.RFC3339Nano)
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// cancelOnDisconnect cancels everything of a user whose last cancelOnDisconnect session dropped.
+// A gateway that crashes can not do it, cancelAllAfter covers that case.
+func (s *Server) cancelOnDisconnect(userID int) {
+	canceled, err := s.cancelEverything(userID)
+	if err != nil {
+		s.logger.Error("failed to cancel everything of disconnected user", "userID", userID, "canceled", canceled, "error", err)
+		return
+	}
+	s.logger.Info("canceled orders of disconnected user", "userID", userID, "canceled", canceled)
+}
+
+// cancelEverything is what the dead man's switch does: it cancels the algo orders and order groups of the user
+// first, so that they place nothing more, then the conditional and held orders and at last every open order.
+// It returns the number of canceled orders and an error if anything could not be canceled.
+func (s *Server) cancelEverything(userID int) (int, error) {
+	var (
+		canceled int
+		errs     []error
+	)
+
+	algoOrders, err := s.db.GetAlgoOrdersByUser(userID)
+	if err != nil {
+		return 0, err
+	}
+	for _, existing := range algoOrders {
+		if existing.Status != "running" && existing.Status != "paused" {
+			continue
+		}
+		if _, err := s.algoOrderEngine.Cancel(existing.ID); err != nil {
+			if !errors.Is(err, algoOrder.ErrClosed) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		canceled++
+	}
+
+	orderGroups, err := s.db.GetOrderGroupsByUser(userID)
+	if err != nil {
+		return canceled, err
+	}
+	for _, existing := range orderGroups {
+		if existing.Status != "pending" && existing.Status != "active" {
+			continue
+		}
+		if _, err := s.orderGroupEngine.Cancel(existing.ID); err != nil {
+			if !errors.Is(err, orderGroup.ErrClosed) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		canceled++
+	}
+
+	conditionalOrders, err := s.db.GetConditionalOrdersByUser(userID)
+	if err != nil {
+		return canceled, err
+	}
+	for _, existing := range conditionalOrders {
+		if existing.Status != "untriggered" {
+			continue
+		}
+		if _, err := s.conditionalOrderEngine.Cancel(existing.ID); err != nil {
+			if !errors.Is(err, conditionalOrder.ErrNotCancelable) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		canceled++
+	}
+
+	// a held order that is being placed right now can not be canceled, it is canceled in the book on a retry
+	jobs, err := s.db.GetPendingScheduledJobsByUser(userID, "place")
+	if err != nil {
+		return canceled, err
+	}
+	for _, job := range jobs {
+		if _, err := s.scheduler.CancelScheduledOrder(job.ID); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		canceled++
+	}
+
+	summary, err := s.cancelAllUserOrders(userID, "")
+	if err != nil {
+		return canceled, err
+	}
+	canceled += len(summary.Canceled)
+	if len(summary.Failed) > 0 {
+		errs = append(errs, errors.New("failed to cancel "+strconv.Itoa(len(summary.Failed))+" open orders"))
+	}
+	return canceled, errors.Join(errs...)
+}
+
+func (s *Server) authenticateWebsocket(accessToken string) (int, bool) {
+	userID, isValid, err := tokenManager.ValidateAccessToken(accessToken, s.accessTokenSecretPhrase)
+	return userID, err == nil && isValid
+}
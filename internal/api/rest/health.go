@@ -0,0 +1,23 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ready always answers 200 so that stale upstream data does not take every replica
+// out of rotation, stale pairs are reported in the body instead.
+func (s *Server) ready(c echo.Context) error {
+	stalePairs := s.watchdog.StalePairs()
+
+	status := "ready"
+	if len(stalePairs) > 0 {
+		status = "degraded"
+	}
+
+	return c.JSON(http.StatusOK, struct {
+		Status     string              `json:"status"`
+		StalePairs map[string][]string `json:"stalePairs"`
+	}{status, stalePairs})
+}
@@ -0,0 +1,85 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (s *Server) getTicker(c echo.Context) error {
+	ticker, ok := s.cache.Ticker(c.Param("pair"))
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "ticker not found",
+		})
+	}
+
+	return c.JSON(http.StatusOK, ticker)
+}
+
+func (s *Server) getTickers(c echo.Context) error {
+	return c.JSON(http.StatusOK, s.cache.Tickers())
+}
+
+func (s *Server) getOrderBookSnapshot(c echo.Context) error {
+	precision, err := strconv.Atoi(c.QueryParam("precision"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid precision",
+		})
+	}
+
+	var depth int
+	if depthString := c.QueryParam("depth"); depthString != "" {
+		depth, err = strconv.Atoi(depthString)
+		if err != nil || depth < 1 {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "invalid depth",
+			})
+		}
+	}
+
+	pOBS, ok := s.cache.OrderBookSnapshot(c.Param("pair"), int32(precision))
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "orderbook not found",
+		})
+	}
+
+	if depth > 0 {
+		pOBS.Bids = pOBS.Bids[:min(depth, len(pOBS.Bids))]
+		pOBS.Asks = pOBS.Asks[:min(depth, len(pOBS.Asks))]
+	}
+
+	return c.JSON(http.StatusOK, pOBS)
+}
+
+func (s *Server) getRecentTrades(c echo.Context) error {
+	trades, ok := s.cache.RecentTrades(c.Param("pair"))
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "trades not found",
+		})
+	}
+
+	return c.JSON(http.StatusOK, trades)
+}
+
+func (s *Server) getCurrentCandleStick(c echo.Context) error {
+	timeframe := c.QueryParam("timeframe")
+	if timeframe == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "timeframe is required",
+		})
+	}
+
+	candleStick, ok := s.cache.CurrentCandleStick(c.Param("pair"), timeframe)
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "candleStick not found",
+		})
+	}
+
+	return c.JSON(http.StatusOK, candleStick)
+}
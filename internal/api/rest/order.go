@@ -1,115 +1,529 @@
-package rest
-
-import (
-	"net/http"
-	"strconv"
-
-	"github.com/BazaarTrade/MatchingEngineProtoGen/pbM"
-	"github.com/labstack/echo/v4"
-	"google.golang.org/grpc/status"
-)
-
-func (s *Server) placeOrder(c echo.Context) error {
-	var req pbM.PlaceOrderReq
-
-	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "invalid request format",
-		})
-	}
-
-	switch {
-	case req.Type != "market" && req.Type != "limit":
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "invalid order type",
-		})
-
-	case req.Type == "limit" && req.Price == "":
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "invalid price",
-		})
-
-	case req.Qty == "":
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "invalid qty",
-		})
-	}
-
-	order, matchOrders, err := s.mClient.PlaceOrder(&req)
-	if err != nil {
-		st, _ := status.FromError(err)
-		if st.Message() != "" {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": st.Message(),
-			})
-		}
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "internal error in matching engine",
-		})
-	}
-
-	for _, matchOrder := range matchOrders {
-		go s.hub.BroadcastOrder(matchOrder)
-	}
-	return c.JSON(http.StatusOK, order)
-}
-
-func (s *Server) cancelOrder(c echo.Context) error {
-	id, err := strconv.Atoi(c.Param("orderID"))
-	if err != nil {
-		s.logger.Error("failed to convert orderID to int", "error", err)
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "invalid orderId",
-		})
-	}
-
-	if id < 1 {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "orderId must be greater than 0",
-		})
-	}
-
-	order, err := s.mClient.CancelOrder(&pbM.OrderID{OrderID: int64(id)})
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "internal error in matching engine service",
-		})
-	}
-	return c.JSON(http.StatusOK, order)
-}
-
-func (s *Server) getCurrentOrders(c echo.Context) error {
-	userID, err := strconv.Atoi(c.Param("userID"))
-	if err != nil {
-		s.logger.Error("failed to convert userID to int", "error", err)
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "invalid user_id",
-		})
-	}
-
-	orders, err := s.db.GetNotFilledOrdersByUser(userID)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "internal error in matching engine service",
-		})
-	}
-	return c.JSON(http.StatusOK, orders)
-}
-
-func (s *Server) getOrders(c echo.Context) error {
-	userID, err := strconv.Atoi(c.Param("userID"))
-	if err != nil {
-		s.logger.Error("failed to convert userID to int", "error", err)
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "invalid user_id",
-		})
-	}
-
-	orders, err := s.db.GetNotFilledOrdersByUser(userID)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "internal error in matching engine service",
-		})
-	}
-	return c.JSON(http.StatusOK, orders)
-}
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/decimal"
+	"github.com/BazaarTrade/ApiGatewayService/internal/middleware"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/orderValidator"
+	"github.com/BazaarTrade/ApiGatewayService/internal/repository"
+	"github.com/labstack/echo/v4"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// clientOrderTimeout is how long a request whose order can not be found blocks retries with the same keys
+var clientOrderTimeout = time.Minute
+
+// orderError is a rejected order operation together with the status code it is reported with
+type orderError struct {
+	status  int
+	message string
+	fields  []orderValidator.FieldError
+}
+
+func (e *orderError) response() map[string]any {
+	response := map[string]any{
+		"error": e.message,
+	}
+	if len(e.fields) > 0 {
+		response["fields"] = e.fields
+	}
+	return response
+}
+
+func (s *Server) placeOrder(c echo.Context) error {
+	var req models.PlaceOrderReq
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid request format",
+		})
+	}
+
+	if ok, err := s.allowRate(c, "place", 1); !ok {
+		return err
+	}
+
+	userID, _ := middleware.UserID(c)
+	order, orderErr := s.submitOrder(userID, req, c.Request().Header.Get("Idempotency-Key"), 0)
+	if orderErr != nil {
+		return c.JSON(orderErr.status, orderErr.response())
+	}
+	return c.JSON(http.StatusOK, order)
+}
+
+// testOrder runs every check of placeOrder without sending the order to the matching engine
+func (s *Server) testOrder(c echo.Context) error {
+	var req models.PlaceOrderReq
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid request format",
+		})
+	}
+
+	userID, _ := middleware.UserID(c)
+	req, orderErr := s.checkOrder(userID, req)
+	if orderErr != nil {
+		return c.JSON(orderErr.status, orderErr.response())
+	}
+
+	// conditional and scheduled orders are risk checked when they are placed
+	if !orderValidator.IsConditional(req.Type) && req.TimeInForce != "GAT" {
+		if orderErr := s.checkRisk(req, 0); orderErr != nil {
+			return c.JSON(orderErr.status, orderErr.response())
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "order is valid",
+	})
+}
+
+// checkOrder authorizes and validates the order request of the user, userID in the request is optional
+func (s *Server) checkOrder(userID int, req models.PlaceOrderReq) (models.PlaceOrderReq, *orderError) {
+	if req.UserID == 0 {
+		req.UserID = userID
+	}
+
+	if req.UserID != userID {
+		return req, &orderError{
+			status:  http.StatusForbidden,
+			message: "placing orders for another user is forbidden",
+		}
+	}
+
+	pairParams, _ := s.reconciler.PairParams(req.Pair)
+	ticker, _ := s.cache.Ticker(req.Pair)
+	if fieldErrors := orderValidator.Validate(req, pairParams, ticker.LastPrice); len(fieldErrors) > 0 {
+		return req, &orderError{
+			status:  http.StatusBadRequest,
+			message: "invalid order",
+			fields:  fieldErrors,
+		}
+	}
+
+	if req.TimeInForce == "FOK" {
+		if orderErr := s.checkFillable(req); orderErr != nil {
+			return req, orderErr
+		}
+	}
+
+	if req.PostOnly {
+		if orderErr := s.checkPostOnly(req); orderErr != nil {
+			return req, orderErr
+		}
+	}
+	return req, nil
+}
+
+// submitOrder checks and places the order of the user, conditional orders are handed to the conditional order engine.
+// A retried request with a known client order ID or idempotency key gets the original order.
+// replacedOrderID is the amended order the new one replaces, the open orders checks leave it out, 0 if there is none.
+func (s *Server) submitOrder(userID int, req models.PlaceOrderReq, idempotencyKey string, replacedOrderID int) (models.Order, *orderError) {
+	req, orderErr := s.checkOrder(userID, req)
+	if orderErr != nil {
+		return models.Order{}, orderErr
+	}
+
+	if restsOpen(req) {
+		if orderErr := s.checkOpenOrdersCap(req.UserID, req.Pair, 1, replacedOrderID); orderErr != nil {
+			return models.Order{}, orderErr
+		}
+	}
+
+	if orderValidator.IsConditional(req.Type) {
+		return s.submitConditionalOrder(req, idempotencyKey)
+	}
+
+	if req.TimeInForce == "GAT" {
+		return s.submitScheduledOrder(req, idempotencyKey)
+	}
+
+	var clientOrder models.ClientOrder
+	if req.ClientOrderID != "" || idempotencyKey != "" {
+		var (
+			existing *models.Order
+			orderErr *orderError
+		)
+		clientOrder, existing, orderErr = s.reserveClientOrder(req, idempotencyKey)
+		if orderErr != nil {
+			return models.Order{}, orderErr
+		}
+		if existing != nil {
+			return *existing, nil
+		}
+	}
+
+	order, err := s.router.PlaceReplacementOrder(req, replacedOrderID)
+	if err != nil {
+		if clientOrder.ID != 0 {
+			if isOrderRejected(err) {
+				// the order was not placed, so the same keys may be used again
+				if err := s.db.DeleteClientOrder(clientOrder.ID); err != nil {
+					s.logger.Error("failed to release client order", "clientOrderID", req.ClientOrderID, "error", err)
+				}
+			} else if order, ok := s.findClientOrder(clientOrder, req); ok {
+				// the order reached the book although the matching engine did not answer
+				return order, nil
+			} else {
+				return models.Order{}, &orderError{
+					status:  http.StatusServiceUnavailable,
+					message: "order state is unknown, retry with the same clientOrderId or Idempotency-Key",
+				}
+			}
+		}
+
+		st, _ := status.FromError(err)
+		if st.Message() != "" {
+			return models.Order{}, &orderError{
+				status:  http.StatusBadRequest,
+				message: st.Message(),
+			}
+		}
+		return models.Order{}, &orderError{
+			status:  http.StatusInternalServerError,
+			message: "internal error in matching engine",
+		}
+	}
+
+	order, orderErr = s.applyTimeInForce(req, order)
+	if orderErr != nil {
+		if clientOrder.ID != 0 {
+			// a retry must not place an order that is still resting again
+			if order.ID != 0 {
+				s.completeClientOrder(clientOrder, order)
+			} else if err := s.db.DeleteClientOrder(clientOrder.ID); err != nil {
+				s.logger.Error("failed to release client order", "clientOrderID", req.ClientOrderID, "error", err)
+			}
+		}
+		return models.Order{}, orderErr
+	}
+
+	// an amend of the resting order keeps it post-only
+	if req.PostOnly && order.Status == "filling" {
+		if err := s.db.AddPostOnlyOrder(order.ID); err != nil {
+			s.logger.Error("failed to save post-only order", "orderID", order.ID, "error", err)
+		}
+	}
+
+	if clientOrder.ID != 0 {
+		s.completeClientOrder(clientOrder, order)
+	}
+	return order, nil
+}
+
+// completeClientOrder stores the order as the result of the reserved client order
+func (s *Server) completeClientOrder(clientOrder models.ClientOrder, order models.Order) {
+	response, err := json.Marshal(order)
+	if err != nil {
+		s.logger.Error("failed to marshal order", "error", err)
+	} else if err := s.db.CompleteClientOrder(clientOrder.ID, order.ID, response); err != nil {
+		s.logger.Error("failed to save client order", "clientOrderID", clientOrder.ClientOrderID, "orderID", order.ID, "error", err)
+	}
+}
+
+// reserveClientOrder reserves the client order ID and idempotency key of the order. A retried request gets the
+// original order. A reservation without an order is left to the first request until it is older than
+// clientOrderTimeout, then the order is looked up in the order history or the reservation is taken over.
+func (s *Server) reserveClientOrder(req models.PlaceOrderReq, idempotencyKey string) (models.ClientOrder, *models.Order, *orderError) {
+	clientOrder := models.ClientOrder{
+		UserID:         req.UserID,
+		ClientOrderID:  req.ClientOrderID,
+		IdempotencyKey: idempotencyKey,
+	}
+
+	existing, reserved, err := s.db.ReserveClientOrder(clientOrder)
+	if errors.Is(err, repository.ErrNotFound) {
+		// the conflicting reservation was released in between
+		return s.reserveClientOrder(req, idempotencyKey)
+	}
+	if err != nil {
+		return models.ClientOrder{}, nil, &orderError{
+			status:  http.StatusInternalServerError,
+			message: "internal error in database",
+		}
+	}
+	if reserved {
+		return existing, nil, nil
+	}
+
+	var order models.Order
+	if len(existing.Response) > 0 && json.Unmarshal(existing.Response, &order) == nil {
+		return models.ClientOrder{}, &order, nil
+	}
+
+	// while the first request may still be placing the order, an identical order is not necessarily its own
+	if time.Since(existing.CreatedAt) < clientOrderTimeout {
+		return models.ClientOrder{}, nil, &orderError{
+			status:  http.StatusConflict,
+			message: "order with this clientOrderId or Idempotency-Key is already being placed",
+		}
+	}
+
+	if order, ok := s.findClientOrder(existing, req); ok {
+		return models.ClientOrder{}, &order, nil
+	}
+
+	// the first request never placed the order
+	if err := s.db.DeleteClientOrder(existing.ID); err != nil {
+		return models.ClientOrder{}, nil, &orderError{
+			status:  http.StatusInternalServerError,
+			message: "internal error in database",
+		}
+	}
+	s.logger.Warn("took over expired client order reservation", "userID", req.UserID, "clientOrderID", req.ClientOrderID)
+	return s.reserveClientOrder(req, idempotencyKey)
+}
+
+// findClientOrder looks for the order placed by a request whose result is unknown: the oldest order of the user
+// matching the request since the reservation that does not belong to another client order. It completes the reservation.
+func (s *Server) findClientOrder(clientOrder models.ClientOrder, req models.PlaceOrderReq) (models.Order, bool) {
+	side := "sell"
+	if req.IsBid {
+		side = "buy"
+	}
+	filter := models.OrderFilter{
+		UserID: req.UserID,
+		Pair:   req.Pair,
+		Side:   side,
+		Type:   req.Type,
+		// orders are timestamped by the matching engine
+		From: clientOrder.CreatedAt.Add(-time.Second),
+	}
+
+	openOrders, err := s.db.GetOpenOrders(filter)
+	if err != nil {
+		return models.Order{}, false
+	}
+	closedOrders, err := s.db.GetClosedOrders(filter)
+	if err != nil {
+		return models.Order{}, false
+	}
+
+	price, _ := decimal.Parse(req.Price)
+	qty, _ := decimal.Parse(req.Qty)
+
+	var found *models.Order
+	for _, order := range append(openOrders, closedOrders...) {
+		if orderQty, ok := decimal.Parse(order.Qty); !ok || qty == nil || orderQty.Cmp(qty) != 0 {
+			continue
+		}
+		if orderPrice, ok := decimal.Parse(order.Price); req.Type == "limit" && (!ok || price == nil || orderPrice.Cmp(price) != 0) {
+			continue
+		}
+		if found != nil && found.ID < order.ID {
+			continue
+		}
+		if _, err := s.db.GetClientOrderByOrderID(order.ID); !errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		found = &order
+	}
+	if found == nil {
+		return models.Order{}, false
+	}
+
+	found.ClientOrderID = req.ClientOrderID
+	response, err := json.Marshal(found)
+	if err != nil {
+		s.logger.Error("failed to marshal order", "error", err)
+		return models.Order{}, false
+	}
+	if err := s.db.CompleteClientOrder(clientOrder.ID, found.ID, response); err != nil {
+		s.logger.Error("failed to save client order", "clientOrderID", req.ClientOrderID, "orderID", found.ID, "error", err)
+		return models.Order{}, false
+	}
+
+	s.logger.Info("resolved client order of unknown result", "clientOrderID", req.ClientOrderID, "orderID", found.ID)
+	return *found, true
+}
+
+// isOrderRejected reports whether the matching engine or the risk checks definitely did not accept the order,
+// as opposed to errors after which the order may have reached the book
+func isOrderRejected(err error) bool {
+	st, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+
+	switch st.Code() {
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.NotFound:
+		return true
+	}
+	return false
+}
+
+func (s *Server) cancelOrder(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("orderID"))
+	if err != nil {
+		s.logger.Error("failed to convert orderID to int", "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid orderId",
+		})
+	}
+
+	if ok, err := s.allowRate(c, "cancel", 1); !ok {
+		return err
+	}
+
+	userID, _ := middleware.UserID(c)
+	order, orderErr := s.cancelUserOrder(userID, id)
+	if orderErr != nil {
+		return c.JSON(orderErr.status, orderErr.response())
+	}
+	return c.JSON(http.StatusOK, order)
+}
+
+// cancelUserOrder cancels the order if it belongs to the user and broadcasts the order update
+func (s *Server) cancelUserOrder(userID, orderID int) (models.Order, *orderError) {
+	if orderID < 1 {
+		return models.Order{}, &orderError{
+			status:  http.StatusBadRequest,
+			message: "orderId must be greater than 0",
+		}
+	}
+
+	existing, err := s.db.GetOrderByID(orderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return models.Order{}, &orderError{
+				status:  http.StatusNotFound,
+				message: "order not found",
+			}
+		}
+		return models.Order{}, &orderError{
+			status:  http.StatusInternalServerError,
+			message: "internal error in database",
+		}
+	}
+
+	if existing.UserID != userID {
+		return models.Order{}, &orderError{
+			status:  http.StatusForbidden,
+			message: "access to another user's orders is forbidden",
+		}
+	}
+
+	order, err := s.router.CancelOrder(orderID)
+	if err != nil {
+		return models.Order{}, &orderError{
+			status:  http.StatusInternalServerError,
+			message: "internal error in matching engine service",
+		}
+	}
+	return order, nil
+}
+
+func (s *Server) getOrder(c echo.Context) error {
+	orderID, err := strconv.Atoi(c.Param("orderID"))
+	if err != nil || orderID < 1 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid orderId",
+		})
+	}
+
+	userID, _ := middleware.UserID(c)
+	order, err := s.userOrder(userID, orderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "order not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+	return c.JSON(http.StatusOK, order)
+}
+
+func (s *Server) getOrderByClientOrderID(c echo.Context) error {
+	clientOrder, ok, err := s.clientOrder(c)
+	if !ok {
+		return err
+	}
+
+	order, err := s.userOrder(clientOrder.UserID, clientOrder.OrderID)
+	if err != nil {
+		// the order may not be stored by the matching engine yet
+		if errors.Is(err, repository.ErrNotFound) {
+			return c.JSONBlob(http.StatusOK, clientOrder.Response)
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+
+	order.ClientOrderID = clientOrder.ClientOrderID
+	return c.JSON(http.StatusOK, order)
+}
+
+// userOrder prefers the last order update the gateway published over the database while it is fresh
+func (s *Server) userOrder(userID, orderID int) (models.Order, error) {
+	if order, ok := s.orderState.Order(orderID); ok {
+		if order.UserID != userID {
+			return models.Order{}, repository.ErrNotFound
+		}
+		return order, nil
+	}
+	return s.db.GetUserOrder(userID, orderID)
+}
+
+func (s *Server) cancelOrderByClientOrderID(c echo.Context) error {
+	clientOrder, ok, err := s.clientOrder(c)
+	if !ok {
+		return err
+	}
+
+	if ok, err := s.allowRate(c, "cancel", 1); !ok {
+		return err
+	}
+
+	userID, _ := middleware.UserID(c)
+	order, orderErr := s.cancelUserOrder(userID, clientOrder.OrderID)
+	if orderErr != nil {
+		return c.JSON(orderErr.status, orderErr.response())
+	}
+
+	order.ClientOrderID = clientOrder.ClientOrderID
+	return c.JSON(http.StatusOK, order)
+}
+
+// clientOrder resolves the clientOrderID path param of the authenticated user,
+// if it fails the error response is already written
+func (s *Server) clientOrder(c echo.Context) (models.ClientOrder, bool, error) {
+	userID, _ := middleware.UserID(c)
+
+	clientOrderID := c.Param("clientOrderID")
+	if clientOrderID == "" || len(clientOrderID) > orderValidator.MaxClientOrderIDLength {
+		return models.ClientOrder{}, false, c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid clientOrderId",
+		})
+	}
+
+	clientOrder, err := s.db.GetClientOrder(userID, clientOrderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return models.ClientOrder{}, false, c.JSON(http.StatusNotFound, map[string]string{
+				"error": "order not found",
+			})
+		}
+		return models.ClientOrder{}, false, c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+
+	if clientOrder.OrderID == 0 {
+		return models.ClientOrder{}, false, c.JSON(http.StatusConflict, map[string]string{
+			"error": "order is still being placed",
+		})
+	}
+	return clientOrder, true, nil
+}
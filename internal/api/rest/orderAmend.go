@@ -0,0 +1,229 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/decimal"
+	"github.com/BazaarTrade/ApiGatewayService/internal/middleware"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/repository"
+	"github.com/labstack/echo/v4"
+)
+
+// amendOrderRequest changes the price and/or the total qty of a resting limit order
+type amendOrderRequest struct {
+	Price string `json:"price"`
+	Qty   string `json:"qty"`
+}
+
+// amendOrder replaces a resting limit order by canceling it and placing a new one with the remaining
+// qty at the new price. The client order ID moves to the new order.
+func (s *Server) amendOrder(c echo.Context) error {
+	orderID, err := strconv.Atoi(c.Param("orderID"))
+	if err != nil || orderID < 1 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid orderId",
+		})
+	}
+
+	var req amendOrderRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid request format",
+		})
+	}
+
+	if req.Price == "" && req.Qty == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "price or qty is required",
+		})
+	}
+
+	// an amend cancels one order and places another
+	if ok, err := s.allowRates(c, map[string]int{"cancel": 1, "place": 1}); !ok {
+		return err
+	}
+
+	userID, _ := middleware.UserID(c)
+
+	existing, err := s.db.GetOrderByID(orderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "order not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+
+	if existing.UserID != userID {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "access to another user's orders is forbidden",
+		})
+	}
+
+	// the slice is worked by its iceberg order, canceling it would cancel the whole iceberg
+	if s.router.IsHidden(orderID) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "iceberg slices can not be amended, cancel the iceberg order instead",
+		})
+	}
+
+	// canceling a leg cancels its whole group, the replacement would rest unprotected
+	if s.orderGroupEngine.IsLeg(orderID) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "legs of order groups can not be amended, cancel the order group instead",
+		})
+	}
+
+	if existing.Status != "filling" {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": "order is already " + existing.Status,
+		})
+	}
+
+	if existing.Type != "limit" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "only limit orders can be amended",
+		})
+	}
+
+	clientOrder, err := s.db.GetClientOrderByOrderID(orderID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+
+	// a GTD order keeps its expiry, the expiry job moves to the replacement
+	expiry, err := s.expiryJob(userID, orderID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+
+	// the replacement of a post-only order is checked not to take liquidity either
+	postOnly, err := s.db.IsPostOnlyOrder(orderID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+
+	replacement := models.PlaceOrderReq{
+		UserID: userID,
+		IsBid:  existing.IsBid,
+		Pair:   existing.Pair,
+		Price:  existing.Price,
+		Qty:    existing.Qty,
+		Type:   existing.Type,
+
+		PostOnly: postOnly,
+	}
+	if expiry.ID != 0 {
+		replacement.TimeInForce = "GTD"
+		replacement.ExpireTime = expiry.RunAt.UTC().Format(time.RFC3339)
+	}
+	if req.Price != "" {
+		replacement.Price = req.Price
+	}
+	if req.Qty != "" {
+		replacement.Qty = req.Qty
+	}
+
+	// the new qty is the total size, what is already filled is not placed again
+	replacement.Qty, err = remainingQty(replacement.Qty, existing.SizeFilled)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	checked, orderErr := s.checkOrder(userID, replacement)
+	if orderErr != nil {
+		return c.JSON(orderErr.status, orderErr.response())
+	}
+
+	// the amended order must not be lost to a risk or open orders cap rejection of its replacement
+	if orderErr := s.checkRisk(checked, orderID); orderErr != nil {
+		return c.JSON(orderErr.status, orderErr.response())
+	}
+	if orderErr := s.checkOpenOrdersCap(userID, checked.Pair, 1, orderID); orderErr != nil {
+		return c.JSON(orderErr.status, orderErr.response())
+	}
+
+	canceled, orderErr := s.cancelUserOrder(userID, orderID)
+	if orderErr != nil {
+		return c.JSON(orderErr.status, orderErr.response())
+	}
+	canceled.ClientOrderID = clientOrder.ClientOrderID
+
+	// the order could have been filled further between the checks and the cancel
+	replacement.Qty, err = remainingQty(replacement.Qty, subtractQty(canceled.SizeFilled, existing.SizeFilled))
+	if err != nil {
+		return c.JSON(http.StatusConflict, map[string]any{
+			"error":    "order was filled before it could be amended",
+			"canceled": canceled,
+		})
+	}
+
+	order, orderErr := s.submitOrder(userID, replacement, "", orderID)
+	if orderErr != nil {
+		response := orderErr.response()
+		response["canceled"] = canceled
+		return c.JSON(orderErr.status, response)
+	}
+
+	if expiry.ID != 0 {
+		if _, err := s.db.CancelScheduledJob(expiry.ID); err != nil {
+			s.logger.Error("failed to cancel expiry of amended order", "orderID", orderID, "error", err)
+		}
+	}
+
+	if clientOrder.ID != 0 {
+		order.ClientOrderID = clientOrder.ClientOrderID
+		s.completeClientOrder(clientOrder, order)
+	}
+
+	return c.JSON(http.StatusOK, map[string]models.Order{
+		"canceled": canceled,
+		"order":    order,
+	})
+}
+
+var errNothingToPlace = errors.New("qty must be greater than the filled size")
+
+// remainingQty returns qty - filled, an unparsable filled size counts as nothing filled
+func remainingQty(qty, filled string) (string, error) {
+	total, ok := decimal.Parse(qty)
+	if !ok {
+		return "", errors.New("invalid qty")
+	}
+
+	if filledSize, ok := decimal.Parse(filled); ok {
+		total.Sub(total, filledSize)
+	}
+
+	if total.Sign() <= 0 {
+		return "", errNothingToPlace
+	}
+	return decimal.Format(total), nil
+}
+
+func subtractQty(a, b string) string {
+	x, ok := decimal.Parse(a)
+	if !ok {
+		return "0"
+	}
+
+	if y, ok := decimal.Parse(b); ok {
+		x.Sub(x, y)
+	}
+	return decimal.Format(x)
+}
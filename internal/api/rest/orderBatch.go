@@ -0,0 +1,125 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/middleware"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/orderValidator"
+	"github.com/labstack/echo/v4"
+)
+
+var maxBatchSize = 20
+
+type batchPlaceOrdersRequest struct {
+	Orders []models.PlaceOrderReq `json:"orders"`
+}
+
+type batchCancelOrdersRequest struct {
+	OrderIDs []int `json:"orderIDs"`
+}
+
+// batchResult is the outcome of one item of a batch, either Order or Error is set
+type batchResult struct {
+	Order  *models.Order               `json:"order,omitempty"`
+	Error  string                      `json:"error,omitempty"`
+	Status int                         `json:"status"`
+	Fields []orderValidator.FieldError `json:"fields,omitempty"`
+}
+
+func newBatchResult(order models.Order, orderErr *orderError) batchResult {
+	if orderErr != nil {
+		return batchResult{
+			Error:  orderErr.message,
+			Status: orderErr.status,
+			Fields: orderErr.fields,
+		}
+	}
+
+	return batchResult{
+		Order:  &order,
+		Status: http.StatusOK,
+	}
+}
+
+// placeOrders places every order of the batch concurrently, results are in the order of the request
+func (s *Server) placeOrders(c echo.Context) error {
+	var req batchPlaceOrdersRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid request format",
+		})
+	}
+
+	if len(req.Orders) == 0 || len(req.Orders) > maxBatchSize {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "batch must contain from 1 to " + strconv.Itoa(maxBatchSize) + " orders",
+		})
+	}
+
+	if ok, err := s.allowRate(c, "place", len(req.Orders)); !ok {
+		return err
+	}
+
+	userID, _ := middleware.UserID(c)
+
+	var (
+		results = make([]batchResult, len(req.Orders))
+		wg      sync.WaitGroup
+	)
+	for i, orderReq := range req.Orders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = newBatchResult(s.submitOrder(userID, orderReq, "", 0))
+		}()
+	}
+	wg.Wait()
+
+	return c.JSON(http.StatusOK, map[string][]batchResult{
+		"results": results,
+	})
+}
+
+// cancelOrders cancels every order of the batch concurrently, results are in the order of the request
+func (s *Server) cancelOrders(c echo.Context) error {
+	var req batchCancelOrdersRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid request format",
+		})
+	}
+
+	if len(req.OrderIDs) == 0 || len(req.OrderIDs) > maxBatchSize {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "batch must contain from 1 to " + strconv.Itoa(maxBatchSize) + " orderIDs",
+		})
+	}
+
+	if ok, err := s.allowRate(c, "cancel", len(req.OrderIDs)); !ok {
+		return err
+	}
+
+	userID, _ := middleware.UserID(c)
+
+	var (
+		results = make([]batchResult, len(req.OrderIDs))
+		wg      sync.WaitGroup
+	)
+	for i, orderID := range req.OrderIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = newBatchResult(s.cancelUserOrder(userID, orderID))
+		}()
+	}
+	wg.Wait()
+
+	return c.JSON(http.StatusOK, map[string][]batchResult{
+		"results": results,
+	})
+}
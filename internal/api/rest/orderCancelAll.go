@@ -0,0 +1,71 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/middleware"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/labstack/echo/v4"
+)
+
+type cancelAllFailure struct {
+	OrderID int    `json:"orderID"`
+	Error   string `json:"error"`
+}
+
+type cancelAllSummary struct {
+	Canceled []models.Order     `json:"canceled"`
+	Failed   []cancelAllFailure `json:"failed"`
+}
+
+// cancelAllOrders cancels every open order of the authenticated user, optionally only of one pair
+func (s *Server) cancelAllOrders(c echo.Context) error {
+	if ok, err := s.allowRate(c, "cancel", cancelAllWeight); !ok {
+		return err
+	}
+
+	userID, _ := middleware.UserID(c)
+
+	summary, err := s.cancelAllUserOrders(userID, c.QueryParam("pair"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+	return c.JSON(http.StatusOK, summary)
+}
+
+// cancelAllUserOrders cancels the open orders of the user through the matching engine,
+// an empty pair means every pair. Each canceled order is broadcast to the user.
+func (s *Server) cancelAllUserOrders(userID int, pair string) (cancelAllSummary, error) {
+	orders, err := s.db.GetOpenOrders(models.OrderFilter{
+		UserID: userID,
+		Pair:   pair,
+	})
+	if err != nil {
+		return cancelAllSummary{}, err
+	}
+
+	var orderIDs = make([]int, 0, len(orders))
+	for _, order := range orders {
+		orderIDs = append(orderIDs, order.ID)
+	}
+
+	canceled, failed := s.router.CancelOrders(orderIDs)
+
+	var summary = cancelAllSummary{
+		Canceled: canceled,
+		Failed:   make([]cancelAllFailure, 0, len(failed)),
+	}
+	for _, failure := range failed {
+		summary.Failed = append(summary.Failed, cancelAllFailure{
+			OrderID: failure.OrderID,
+			Error:   "internal error in matching engine service",
+		})
+	}
+
+	if len(summary.Failed) > 0 {
+		s.logger.Warn("failed to cancel some orders of user", "userID", userID, "pair", pair, "failed", len(summary.Failed))
+	}
+	return summary, nil
+}
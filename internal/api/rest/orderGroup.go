@@ -0,0 +1,178 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/conditionalOrder"
+	"github.com/BazaarTrade/ApiGatewayService/internal/middleware"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/orderGroup"
+	"github.com/BazaarTrade/ApiGatewayService/internal/orderValidator"
+	"github.com/BazaarTrade/ApiGatewayService/internal/repository"
+	"github.com/labstack/echo/v4"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Server) placeOrderGroup(c echo.Context) error {
+	var req models.OrderGroupReq
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid request format",
+		})
+	}
+
+	userID, _ := middleware.UserID(c)
+	if req.UserID == 0 {
+		req.UserID = userID
+	}
+
+	if req.UserID != userID {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "placing orders for another user is forbidden",
+		})
+	}
+
+	pairParams, _ := s.reconciler.PairParams(req.Pair)
+	ticker, _ := s.cache.Ticker(req.Pair)
+	if fieldErrors := orderValidator.ValidateOrderGroup(req, pairParams, ticker.LastPrice); len(fieldErrors) > 0 {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error":  "invalid order",
+			"fields": fieldErrors,
+		})
+	}
+
+	// every leg counts as an order, both OCO legs stay open while a bracket opens with its entry only
+	legs, opening := 2, 2
+	if req.Type == "bracket" {
+		legs, opening = 3, 1
+	}
+
+	if ok, err := s.allowRate(c, "place", legs); !ok {
+		return err
+	}
+
+	if orderErr := s.checkOpenOrdersCap(req.UserID, req.Pair, opening, 0); orderErr != nil {
+		return c.JSON(orderErr.status, orderErr.response())
+	}
+
+	created, err := s.orderGroupEngine.Create(req)
+	if err != nil {
+		if errors.Is(err, conditionalOrder.ErrWouldTrigger) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		if st, ok := status.FromError(err); ok && st.Message() != "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": st.Message(),
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error",
+		})
+	}
+	return c.JSON(http.StatusOK, created)
+}
+
+// getOrderGroup returns the order group of the user together with its legs
+func (s *Server) getOrderGroup(c echo.Context) error {
+	existing, ok, err := s.userOrderGroup(c)
+	if !ok {
+		return err
+	}
+
+	order, err := s.orderGroupEngine.Order(existing.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+	return c.JSON(http.StatusOK, order)
+}
+
+// getOrderGroups lists every order group of the authenticated user with its legs
+func (s *Server) getOrderGroups(c echo.Context) error {
+	userID, _ := middleware.UserID(c)
+
+	orderGroups, err := s.db.GetOrderGroupsByUser(userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+
+	var orders = make([]models.Order, 0, len(orderGroups))
+	for _, existing := range orderGroups {
+		order, err := s.orderGroupEngine.Order(existing.ID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "internal error in database",
+			})
+		}
+		orders = append(orders, order)
+	}
+	return c.JSON(http.StatusOK, orders)
+}
+
+// cancelOrderGroup cancels the order group of the user together with its resting legs
+func (s *Server) cancelOrderGroup(c echo.Context) error {
+	existing, ok, err := s.userOrderGroup(c)
+	if !ok {
+		return err
+	}
+
+	if ok, err := s.allowRate(c, "cancel", 1); !ok {
+		return err
+	}
+
+	canceled, err := s.orderGroupEngine.Cancel(existing.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, orderGroup.ErrClosed):
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": err.Error(),
+			})
+		case errors.Is(err, orderGroup.ErrLegNotCanceled):
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "internal error in matching engine",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+	return c.JSON(http.StatusOK, canceled)
+}
+
+// userOrderGroup gets the order group of the path if it belongs to the authenticated user,
+// otherwise the error response is already written
+func (s *Server) userOrderGroup(c echo.Context) (models.OrderGroup, bool, error) {
+	id, err := strconv.Atoi(c.Param("orderGroupID"))
+	if err != nil || id < 1 {
+		return models.OrderGroup{}, false, c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid orderGroupId",
+		})
+	}
+
+	existing, err := s.db.GetOrderGroup(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return models.OrderGroup{}, false, c.JSON(http.StatusNotFound, map[string]string{
+				"error": "order group not found",
+			})
+		}
+		return models.OrderGroup{}, false, c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+
+	if userID, _ := middleware.UserID(c); existing.UserID != userID {
+		return models.OrderGroup{}, false, c.JSON(http.StatusForbidden, map[string]string{
+			"error": "access to another user's orders is forbidden",
+		})
+	}
+	return existing, true, nil
+}
@@ -0,0 +1,262 @@
+package rest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/middleware"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	defaultPageSize = 100
+	maxPageSize     = 500
+)
+
+type ordersPage struct {
+	Orders []models.Order `json:"orders"`
+	// NextCursor is empty on the last page
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// getCurrentOrders lists the open orders of the user together with the untriggered
+// conditional orders and the orders held until their start time
+func (s *Server) getCurrentOrders(c echo.Context) error {
+	userID, ok, err := s.ordersUserID(c)
+	if !ok {
+		return err
+	}
+
+	filter, message := parseOrderFilter(c)
+	if message != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": message,
+		})
+	}
+	filter.UserID = userID
+
+	openOrders, err := s.db.GetOpenOrders(filter)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+
+	// iceberg slices are shown as their iceberg order
+	var orders = make([]models.Order, 0, len(openOrders))
+	for _, order := range openOrders {
+		if !s.router.IsHidden(order.ID) {
+			orders = append(orders, order)
+		}
+	}
+
+	conditionalOrders, err := s.conditionalOrders(userID, true)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+
+	scheduledOrders, err := s.scheduledOrders(userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+
+	algoOrders, err := s.algoOrders(userID, true)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+
+	for _, order := range append(append(conditionalOrders, scheduledOrders...), algoOrders...) {
+		if matchesOrderFilter(order, filter) {
+			orders = append(orders, order)
+		}
+	}
+	return c.JSON(http.StatusOK, orders)
+}
+
+// getOrders pages through the closed orders of the user from the newest
+func (s *Server) getOrders(c echo.Context) error {
+	userID, ok, err := s.ordersUserID(c)
+	if !ok {
+		return err
+	}
+
+	filter, message := parseOrderFilter(c)
+	if message != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": message,
+		})
+	}
+	filter.UserID = userID
+
+	limit, message := parsePageSize(c)
+	if message != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": message,
+		})
+	}
+
+	if value := c.QueryParam("cursor"); value != "" {
+		var cursor models.OrderCursor
+		if !decodeCursor(value, &cursor) || cursor.CreatedAt == "" || cursor.ID < 1 {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "invalid cursor",
+			})
+		}
+		filter.After = &cursor
+	}
+
+	// one more order tells whether there is a next page
+	filter.Limit = limit + 1
+	orders, err := s.db.GetClosedOrders(filter)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+
+	page := ordersPage{
+		Orders: orders,
+	}
+	if page.Orders == nil {
+		page.Orders = []models.Order{}
+	}
+	if len(orders) > limit {
+		page.Orders = orders[:limit]
+		page.NextCursor = encodeCursor(models.OrderCursor{
+			CreatedAt: orders[limit-1].CreatedAt,
+			ID:        orders[limit-1].ID,
+		})
+	}
+	return c.JSON(http.StatusOK, page)
+}
+
+// getConditionalOrders lists every conditional order of the authenticated user
+func (s *Server) getConditionalOrders(c echo.Context) error {
+	userID, _ := middleware.UserID(c)
+
+	orders, err := s.conditionalOrders(userID, false)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+	if orders == nil {
+		orders = []models.Order{}
+	}
+	return c.JSON(http.StatusOK, orders)
+}
+
+// ordersUserID resolves the userID path param, which must be the authenticated user,
+// if it fails the error response is already written
+func (s *Server) ordersUserID(c echo.Context) (int, bool, error) {
+	userID, err := strconv.Atoi(c.Param("userID"))
+	if err != nil {
+		s.logger.Error("failed to convert userID to int", "error", err)
+		return 0, false, c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid user_id",
+		})
+	}
+
+	if authUserID, _ := middleware.UserID(c); userID != authUserID {
+		return 0, false, c.JSON(http.StatusForbidden, map[string]string{
+			"error": "access to another user's orders is forbidden",
+		})
+	}
+	return userID, true, nil
+}
+
+// parseOrderFilter reads the pair, side, type, status, from and to query params,
+// the returned message describes an invalid one
+func parseOrderFilter(c echo.Context) (models.OrderFilter, string) {
+	filter := models.OrderFilter{
+		Pair:   c.QueryParam("pair"),
+		Side:   c.QueryParam("side"),
+		Type:   c.QueryParam("type"),
+		Status: c.QueryParam("status"),
+	}
+
+	if filter.Side != "" && filter.Side != "buy" && filter.Side != "sell" {
+		return models.OrderFilter{}, "side must be buy or sell"
+	}
+
+	var message string
+	filter.From, filter.To, message = parseTimeRange(c)
+	if message != "" {
+		return models.OrderFilter{}, message
+	}
+	return filter, ""
+}
+
+// parseTimeRange reads the optional from and to RFC3339 query params
+func parseTimeRange(c echo.Context) (time.Time, time.Time, string) {
+	var from, to time.Time
+	for name, t := range map[string]*time.Time{"from": &from, "to": &to} {
+		value := c.QueryParam(name)
+		if value == "" {
+			continue
+		}
+
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, name + " must be an RFC3339 time"
+		}
+		*t = parsed
+	}
+
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return time.Time{}, time.Time{}, "from must be before to"
+	}
+	return from, to, ""
+}
+
+// parsePageSize reads the optional limit query param
+func parsePageSize(c echo.Context) (int, string) {
+	value := c.QueryParam("limit")
+	if value == "" {
+		return defaultPageSize, ""
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > maxPageSize {
+		return 0, "limit must be between 1 and " + strconv.Itoa(maxPageSize)
+	}
+	return limit, ""
+}
+
+// matchesOrderFilter applies the filter to orders the gateway holds itself
+func matchesOrderFilter(order models.Order, filter models.OrderFilter) bool {
+	if (filter.Pair != "" && order.Pair != filter.Pair) ||
+		(filter.Side != "" && order.IsBid != (filter.Side == "buy")) ||
+		(filter.Type != "" && order.Type != filter.Type) ||
+		(filter.Status != "" && order.Status != filter.Status) {
+		return false
+	}
+
+	createdAt, err := time.Parse(time.RFC3339, order.CreatedAt)
+	if err != nil {
+		return true
+	}
+	return (filter.From.IsZero() || !createdAt.Before(filter.From)) &&
+		(filter.To.IsZero() || createdAt.Before(filter.To))
+}
+
+// cursors are opaque to clients
+func encodeCursor(cursor any) string {
+	cursorJSON, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(cursorJSON)
+}
+
+func decodeCursor(value string, cursor any) bool {
+	cursorJSON, err := base64.RawURLEncoding.DecodeString(value)
+	return err == nil && json.Unmarshal(cursorJSON, cursor) == nil
+}
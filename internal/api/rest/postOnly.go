@@ -0,0 +1,38 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/decimal"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+)
+
+// checkPostOnly rejects a post-only order whose price reaches the best opposite level of the cached
+// order book. The book may move before the order arrives at the matching engine.
+func (s *Server) checkPostOnly(req models.PlaceOrderReq) *orderError {
+	pOBS, orderErr := s.finestOrderBook(req.Pair)
+	if orderErr != nil {
+		return orderErr
+	}
+
+	limits := pOBS.Asks
+	if !req.IsBid {
+		limits = pOBS.Bids
+	}
+
+	price, _ := decimal.Parse(req.Price)
+	for _, limit := range limits {
+		limitPrice, ok := decimal.Parse(limit.Price)
+		if !ok {
+			continue
+		}
+
+		if (req.IsBid && price.Cmp(limitPrice) >= 0) || (!req.IsBid && price.Cmp(limitPrice) <= 0) {
+			return &orderError{
+				status:  http.StatusConflict,
+				message: "postOnly order would take liquidity at price " + limit.Price,
+			}
+		}
+	}
+	return nil
+}
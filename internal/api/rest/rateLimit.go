@@ -0,0 +1,133 @@
+package rest
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/middleware"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/orderValidator"
+	"github.com/BazaarTrade/ApiGatewayService/internal/rateLimiter"
+	"github.com/labstack/echo/v4"
+)
+
+// cancelAllWeight is the weight of canceling every open order at once
+var cancelAllWeight = 10
+
+type rateLimitsResponse struct {
+	Limits     []rateLimiter.Usage `json:"limits"`
+	OpenOrders openOrdersUsage     `json:"openOrders"`
+}
+
+type openOrdersUsage struct {
+	// MaxPerPair is 0 when open orders are not capped
+	MaxPerPair int            `json:"maxPerPair"`
+	ByPair     map[string]int `json:"byPair"`
+}
+
+// allowRate takes weight tokens of the kind for the authenticated user and sets the quota headers,
+// if the quota is exhausted the 429 response is already written
+func (s *Server) allowRate(c echo.Context, kind string, weight int) (bool, error) {
+	return s.allowRates(c, map[string]int{kind: weight})
+}
+
+// allowRates is allowRate for a request that counts against several kinds,
+// the tokens are only taken if every kind has quota left.
+// A request heavier than the burst of a kind can never pass and is rejected without a Retry-After.
+func (s *Server) allowRates(c echo.Context, weights map[string]int) (bool, error) {
+	var tokens = make(map[string]float64, len(weights))
+	for kind, weight := range weights {
+		if limit, ok := s.rateLimiter.Limit(kind); ok && float64(weight) > limit.Burst {
+			return false, c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "request weight of " + strconv.Itoa(weight) + " exceeds the " + kind + " burst of " +
+					strconv.FormatFloat(limit.Burst, 'f', -1, 64),
+			})
+		}
+		tokens[kind] = float64(weight)
+	}
+
+	userID, _ := middleware.UserID(c)
+	usages, retryAfter, ok := s.rateLimiter.TakeAll(userID, tokens)
+
+	exceeded := ""
+	for _, usage := range usages {
+		if usage.Burst <= 0 {
+			continue
+		}
+
+		prefix := "X-RateLimit-" + strings.ToUpper(usage.Kind[:1]) + usage.Kind[1:]
+		c.Response().Header().Set(prefix+"-Limit", strconv.FormatFloat(usage.Burst, 'f', -1, 64))
+		c.Response().Header().Set(prefix+"-Remaining", strconv.FormatFloat(usage.Remaining, 'f', -1, 64))
+
+		if !ok && exceeded == "" && usage.Remaining < tokens[usage.Kind] {
+			exceeded = usage.Kind
+		}
+	}
+
+	if !ok {
+		c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		return false, c.JSON(http.StatusTooManyRequests, map[string]string{
+			"error": "rate limit of " + exceeded + " requests exceeded",
+		})
+	}
+	return true, nil
+}
+
+// restsOpen reports whether the order can stay open, in the book or held by the gateway until its trigger or start time
+func restsOpen(req models.PlaceOrderReq) bool {
+	if req.TimeInForce == "GAT" || orderValidator.IsConditional(req.Type) {
+		return true
+	}
+	return req.Type == "limit" && req.TimeInForce != "IOC" && req.TimeInForce != "FOK"
+}
+
+// checkOpenOrdersCap rejects opening orders that would stay open beyond the open orders cap of their pair.
+// replacedOrderID is an open order being replaced that is not counted, 0 if there is none.
+// The cap is approximate: the count is read before the order is placed without reserving a slot,
+// so concurrent requests of the user may exceed it by the number of requests in flight.
+func (s *Server) checkOpenOrdersCap(userID int, pair string, opening, replacedOrderID int) *orderError {
+	if s.maxOpenOrdersPerPair <= 0 {
+		return nil
+	}
+
+	byPair, err := s.db.CountOpenOrders(userID, pair, replacedOrderID)
+	if err != nil {
+		return &orderError{
+			status:  http.StatusInternalServerError,
+			message: "internal error in database",
+		}
+	}
+
+	if byPair[pair]+opening > s.maxOpenOrdersPerPair {
+		return &orderError{
+			status:  http.StatusTooManyRequests,
+			message: "open orders limit of " + strconv.Itoa(s.maxOpenOrdersPerPair) + " reached for " + pair,
+		}
+	}
+	return nil
+}
+
+func (s *Server) getRateLimits(c echo.Context) error {
+	userID, _ := middleware.UserID(c)
+
+	byPair, err := s.db.CountOpenOrders(userID, "", 0)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+
+	response := rateLimitsResponse{
+		Limits: s.rateLimiter.Usage(userID),
+		OpenOrders: openOrdersUsage{
+			MaxPerPair: s.maxOpenOrdersPerPair,
+			ByPair:     byPair,
+		},
+	}
+	if response.Limits == nil {
+		response.Limits = []rateLimiter.Usage{}
+	}
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,93 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/decimal"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/repository"
+	"github.com/BazaarTrade/ApiGatewayService/internal/riskChecker"
+	"github.com/labstack/echo/v4"
+	"google.golang.org/grpc/status"
+)
+
+// getRiskLimits returns the risk limits by pair, the defaults are under "*"
+func (s *Server) getRiskLimits(c echo.Context) error {
+	return c.JSON(http.StatusOK, s.riskChecker.Limits())
+}
+
+// setRiskLimits replaces the risk limits of a listed pair, or the defaults for the pair "*"
+func (s *Server) setRiskLimits(c echo.Context) error {
+	var limits models.RiskLimits
+
+	if err := c.Bind(&limits); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid request format",
+		})
+	}
+
+	pair := c.Param("pair")
+	if _, ok := s.reconciler.PairParams(pair); !ok && pair != riskChecker.DefaultPair {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "pair is not listed",
+		})
+	}
+
+	for _, limit := range []string{limits.PriceBand, limits.MaxQty, limits.MaxNotional, limits.MaxUserExposure} {
+		if value, ok := decimal.Parse(limit); limit != "" && (!ok || value.Sign() <= 0) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "risk limits must be empty or greater than 0",
+			})
+		}
+	}
+
+	if err := s.riskChecker.SetLimits(pair, limits); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+
+	s.logger.Info("risk limits changed", "pair", pair, "priceBand", limits.PriceBand, "maxQty", limits.MaxQty, "maxNotional", limits.MaxNotional, "maxUserExposure", limits.MaxUserExposure)
+	return c.JSON(http.StatusOK, limits)
+}
+
+// deleteRiskLimits makes the pair fall back to the default risk limits
+func (s *Server) deleteRiskLimits(c echo.Context) error {
+	pair := c.Param("pair")
+	if err := s.riskChecker.DeleteLimits(pair); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "no risk limits for the pair",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+
+	s.logger.Info("risk limits removed", "pair", pair)
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "risk limits removed",
+	})
+}
+
+// checkRisk runs the risk checks an order gets on its way to the matching engine ahead of time,
+// replacedOrderID is the open order it replaces or 0
+func (s *Server) checkRisk(req models.PlaceOrderReq, replacedOrderID int) *orderError {
+	err := s.riskChecker.Check(req, replacedOrderID)
+	if err == nil {
+		return nil
+	}
+
+	if st, ok := status.FromError(err); ok {
+		return &orderError{
+			status:  http.StatusBadRequest,
+			message: st.Message(),
+		}
+	}
+	return &orderError{
+		status:  http.StatusInternalServerError,
+		message: "internal error in database",
+	}
+}
@@ -1,104 +1,191 @@
-package rest
-
-import (
-	"context"
-	"errors"
-	"log/slog"
-	"net/http"
-
-	aClient "github.com/BazaarTrade/ApiGatewayService/internal/api/gRPC/authClient"
-	mClient "github.com/BazaarTrade/ApiGatewayService/internal/api/gRPC/matchingEngineClient"
-	qClient "github.com/BazaarTrade/ApiGatewayService/internal/api/gRPC/quoteClient"
-	ws "github.com/BazaarTrade/ApiGatewayService/internal/api/websocket"
-	"github.com/BazaarTrade/ApiGatewayService/internal/middleware"
-	"github.com/BazaarTrade/ApiGatewayService/internal/repository"
-	"github.com/labstack/echo/v4"
-	eMiddleware "github.com/labstack/echo/v4/middleware"
-)
-
-type Server struct {
-	mClient *mClient.Client
-	qClient *qClient.Client
-	aClient *aClient.Client
-	echo    *echo.Echo
-
-	hub                     *ws.Hub
-	db                      repository.Repository
-	accessTokenSecretPhrase string
-	logger                  *slog.Logger
-}
-
-func New(mClient *mClient.Client, qClient *qClient.Client, aClient *aClient.Client, hub *ws.Hub, db repository.Repository, ACCESS_TOKEN_SECRET_PHRASE string, logger *slog.Logger) *Server {
-	return &Server{
-		mClient:                 mClient,
-		qClient:                 qClient,
-		aClient:                 aClient,
-		hub:                     hub,
-		db:                      db,
-		accessTokenSecretPhrase: ACCESS_TOKEN_SECRET_PHRASE,
-		logger:                  logger,
-	}
-}
-
-func (s *Server) Run(ADDR string) error {
-	e := echo.New()
-	s.echo = e
-
-	CORS(e)
-	s.init(e)
-
-	s.logger.Info("server is listeninig on port" + ADDR)
-
-	if err := e.Start(ADDR); err != nil && !errors.Is(err, http.ErrServerClosed) {
-		s.logger.Error("failed to serve", "err", err)
-		return err
-	}
-	return nil
-}
-
-func (s *Server) init(e *echo.Echo) {
-	e.Use(eMiddleware.Recover())
-
-	//public routes
-	e.POST("/register", s.register)
-	e.POST("/login", s.login)
-	e.POST("/refresh/:userID", s.refreshAccessToken)
-
-	e.POST("/orderbook", s.createOrderBook)
-	e.DELETE("/orderbook/:pair", s.deleteOrderBook)
-	e.GET("/ws/:userID", s.hub.HandleWebsocket)
-	e.GET("/orderBookPricePrecisions/:pair", s.getOrderBookPricePrecisions)
-	e.GET("/candleSticks", s.getCandleStickHistory)
-
-	//private routes
-	g := e.Group("")
-	g.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
-		return middleware.AccessTokenMiddleware(next, s.accessTokenSecretPhrase)
-	})
-
-	g.POST("/logout", s.logout)
-	g.POST("/changePassword", s.changePassword)
-
-	g.POST("/order", s.placeOrder)
-	g.DELETE("/order/:orderID", s.cancelOrder)
-	g.GET("/orders/current/:userID", s.getCurrentOrders)
-	g.GET("/orders/:userID", s.getOrders)
-}
-
-func CORS(e *echo.Echo) {
-	e.Use(eMiddleware.CORSWithConfig(eMiddleware.CORSConfig{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"*"},
-		AllowHeaders:     []string{"Content-Type", "Authorization"},
-		AllowCredentials: true,
-	}))
-}
-
-func (s *Server) Stop() error {
-	if err := s.echo.Shutdown(context.Background()); err != nil {
-		s.logger.Error("failed to shutdown server", "error", err)
-		return err
-	}
-
-	return nil
-}
+package rest
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/algoOrder"
+	aClient "github.com/BazaarTrade/ApiGatewayService/internal/api/gRPC/authClient"
+	mClient "github.com/BazaarTrade/ApiGatewayService/internal/api/gRPC/matchingEngineClient"
+	qClient "github.com/BazaarTrade/ApiGatewayService/internal/api/gRPC/quoteClient"
+	ws "github.com/BazaarTrade/ApiGatewayService/internal/api/websocket"
+	"github.com/BazaarTrade/ApiGatewayService/internal/conditionalOrder"
+	"github.com/BazaarTrade/ApiGatewayService/internal/marketData"
+	"github.com/BazaarTrade/ApiGatewayService/internal/middleware"
+	"github.com/BazaarTrade/ApiGatewayService/internal/orderGroup"
+	"github.com/BazaarTrade/ApiGatewayService/internal/orderRouter"
+	"github.com/BazaarTrade/ApiGatewayService/internal/orderState"
+	"github.com/BazaarTrade/ApiGatewayService/internal/rateLimiter"
+	"github.com/BazaarTrade/ApiGatewayService/internal/reconciler"
+	"github.com/BazaarTrade/ApiGatewayService/internal/repository"
+	"github.com/BazaarTrade/ApiGatewayService/internal/riskChecker"
+	"github.com/BazaarTrade/ApiGatewayService/internal/scheduler"
+	"github.com/BazaarTrade/ApiGatewayService/internal/watchdog"
+	"github.com/labstack/echo/v4"
+	eMiddleware "github.com/labstack/echo/v4/middleware"
+)
+
+type Server struct {
+	mClient *mClient.Client
+	qClient *qClient.Client
+	aClient *aClient.Client
+	echo    *echo.Echo
+
+	hub                     *ws.Hub
+	cache                   *marketData.Cache
+	watchdog                *watchdog.Watchdog
+	reconciler              *reconciler.Reconciler
+	router                  *orderRouter.Router
+	orderState              *orderState.Tracker
+	conditionalOrderEngine  *conditionalOrder.Engine
+	scheduler               *scheduler.Scheduler
+	algoOrderEngine         *algoOrder.Engine
+	orderGroupEngine        *orderGroup.Engine
+	rateLimiter             *rateLimiter.Limiter
+	riskChecker             *riskChecker.Checker
+	maxOpenOrdersPerPair    int
+	db                      repository.Repository
+	accessTokenSecretPhrase string
+	adminToken              string
+	logger                  *slog.Logger
+}
+
+func New(mClient *mClient.Client, qClient *qClient.Client, aClient *aClient.Client, hub *ws.Hub, cache *marketData.Cache, watchdog *watchdog.Watchdog, reconciler *reconciler.Reconciler, router *orderRouter.Router, orderState *orderState.Tracker, conditionalOrderEngine *conditionalOrder.Engine, scheduler *scheduler.Scheduler, algoOrderEngine *algoOrder.Engine, orderGroupEngine *orderGroup.Engine, rateLimiter *rateLimiter.Limiter, riskChecker *riskChecker.Checker, maxOpenOrdersPerPair int, db repository.Repository, ACCESS_TOKEN_SECRET_PHRASE, ADMIN_TOKEN string, logger *slog.Logger) *Server {
+	s := &Server{
+		mClient:                 mClient,
+		qClient:                 qClient,
+		aClient:                 aClient,
+		hub:                     hub,
+		cache:                   cache,
+		watchdog:                watchdog,
+		reconciler:              reconciler,
+		router:                  router,
+		orderState:              orderState,
+		conditionalOrderEngine:  conditionalOrderEngine,
+		scheduler:               scheduler,
+		algoOrderEngine:         algoOrderEngine,
+		orderGroupEngine:        orderGroupEngine,
+		rateLimiter:             rateLimiter,
+		riskChecker:             riskChecker,
+		maxOpenOrdersPerPair:    maxOpenOrdersPerPair,
+		db:                      db,
+		accessTokenSecretPhrase: ACCESS_TOKEN_SECRET_PHRASE,
+		adminToken:              ADMIN_TOKEN,
+		logger:                  logger,
+	}
+
+	hub.SetAuthenticator(s.authenticateWebsocket)
+	hub.SetDisconnectHandler(s.cancelOnDisconnect)
+	scheduler.SetCancelAllHandler(s.cancelEverything)
+	return s
+}
+
+func (s *Server) Run(ADDR string) error {
+	e := echo.New()
+	s.echo = e
+
+	CORS(e)
+	s.init(e)
+
+	s.logger.Info("server is listeninig on port" + ADDR)
+
+	if err := e.Start(ADDR); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		s.logger.Error("failed to serve", "err", err)
+		return err
+	}
+	return nil
+}
+
+func (s *Server) init(e *echo.Echo) {
+	e.Use(eMiddleware.Recover())
+
+	//public routes
+	e.POST("/register", s.register)
+	e.POST("/login", s.login)
+	e.POST("/refresh/:userID", s.refreshAccessToken)
+	e.GET("/ready", s.ready)
+
+	e.POST("/orderbook", s.createOrderBook)
+	e.DELETE("/orderbook/:pair", s.deleteOrderBook)
+	e.GET("/ws/:userID", s.hub.HandleWebsocket)
+	e.GET("/orderBookPricePrecisions/:pair", s.getOrderBookPricePrecisions)
+	e.GET("/candleSticks", s.getCandleStickHistory)
+	e.GET("/candleSticks/:pair/current", s.getCurrentCandleStick)
+	e.GET("/ticker/:pair", s.getTicker)
+	e.GET("/tickers", s.getTickers)
+	e.GET("/orderbook/:pair", s.getOrderBookSnapshot)
+	e.GET("/trades/:pair/recent", s.getRecentTrades)
+
+	//private routes
+	g := e.Group("")
+	g.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return middleware.AccessTokenMiddleware(next, s.accessTokenSecretPhrase)
+	})
+
+	g.POST("/logout", s.logout)
+	g.POST("/changePassword", s.changePassword)
+
+	g.POST("/order", s.placeOrder)
+	g.POST("/order/test", s.testOrder)
+	g.GET("/order/:orderID", s.getOrder)
+	g.DELETE("/order/:orderID", s.cancelOrder)
+	g.PATCH("/order/:orderID", s.amendOrder)
+	g.GET("/order/client/:clientOrderID", s.getOrderByClientOrderID)
+	g.DELETE("/order/client/:clientOrderID", s.cancelOrderByClientOrderID)
+	g.DELETE("/order/conditional/:conditionalOrderID", s.cancelConditionalOrder)
+	g.DELETE("/order/scheduled/:scheduledOrderID", s.cancelScheduledOrder)
+	g.POST("/order/algo", s.placeAlgoOrder)
+	g.GET("/order/algo/:algoOrderID", s.getAlgoOrder)
+	g.POST("/order/algo/:algoOrderID/pause", s.pauseAlgoOrder)
+	g.POST("/order/algo/:algoOrderID/resume", s.resumeAlgoOrder)
+	g.DELETE("/order/algo/:algoOrderID", s.cancelAlgoOrder)
+	g.POST("/order/group", s.placeOrderGroup)
+	g.GET("/order/group/:orderGroupID", s.getOrderGroup)
+	g.DELETE("/order/group/:orderGroupID", s.cancelOrderGroup)
+	g.DELETE("/orders", s.cancelAllOrders)
+	g.POST("/orders/cancelAllAfter", s.cancelAllAfter)
+	g.POST("/orders/batch", s.placeOrders)
+	g.DELETE("/orders/batch", s.cancelOrders)
+	g.GET("/orders/current/:userID", s.getCurrentOrders)
+	g.GET("/orders/conditional", s.getConditionalOrders)
+	g.GET("/orders/algo", s.getAlgoOrders)
+	g.GET("/orders/group", s.getOrderGroups)
+	g.GET("/orders/:userID", s.getOrders)
+	g.GET("/trades/me", s.getMyTrades)
+	g.GET("/rateLimits/me", s.getRateLimits)
+
+	//admin routes
+	a := e.Group("/admin")
+	a.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return middleware.AdminTokenMiddleware(next, s.adminToken)
+	})
+
+	a.GET("/riskLimits", s.getRiskLimits)
+	a.PUT("/riskLimits/:pair", s.setRiskLimits)
+	a.DELETE("/riskLimits/:pair", s.deleteRiskLimits)
+}
+
+func CORS(e *echo.Echo) {
+	e.Use(eMiddleware.CORSWithConfig(eMiddleware.CORSConfig{
+		AllowOrigins: []string{"*"},
+		AllowMethods: []string{"*"},
+		AllowHeaders: []string{"Content-Type", "Authorization", "Idempotency-Key"},
+		ExposeHeaders: []string{
+			"Retry-After",
+			"X-RateLimit-Place-Limit", "X-RateLimit-Place-Remaining",
+			"X-RateLimit-Cancel-Limit", "X-RateLimit-Cancel-Remaining",
+		},
+		AllowCredentials: true,
+	}))
+}
+
+func (s *Server) Stop() error {
+	if err := s.echo.Shutdown(context.Background()); err != nil {
+		s.logger.Error("failed to shutdown server", "error", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,230 @@
+package rest
+
+import (
+	"errors"
+	"math/big"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/converter"
+	"github.com/BazaarTrade/ApiGatewayService/internal/decimal"
+	"github.com/BazaarTrade/ApiGatewayService/internal/marketData"
+	"github.com/BazaarTrade/ApiGatewayService/internal/middleware"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/repository"
+	"github.com/BazaarTrade/ApiGatewayService/internal/scheduler"
+	"github.com/labstack/echo/v4"
+)
+
+// checkFillable rejects a FOK order that the cached order book can not fill completely.
+// The book at the finest precision is used, price levels beyond its depth are not counted.
+func (s *Server) checkFillable(req models.PlaceOrderReq) *orderError {
+	pOBS, orderErr := s.finestOrderBook(req.Pair)
+	if orderErr != nil {
+		return orderErr
+	}
+
+	limits := pOBS.Asks
+	if !req.IsBid {
+		limits = pOBS.Bids
+	}
+
+	price, _ := decimal.Parse(req.Price)
+	available := new(big.Rat)
+	for _, limit := range limits {
+		limitPrice, ok := decimal.Parse(limit.Price)
+		if !ok {
+			continue
+		}
+		if req.Type == "limit" && ((req.IsBid && limitPrice.Cmp(price) > 0) || (!req.IsBid && limitPrice.Cmp(price) < 0)) {
+			continue
+		}
+
+		if qty, ok := decimal.Parse(limit.Qty); ok {
+			available.Add(available, qty)
+		}
+	}
+
+	if qty, _ := decimal.Parse(req.Qty); qty == nil || available.Cmp(qty) < 0 {
+		return &orderError{
+			status:  http.StatusBadRequest,
+			message: "order can not be filled completely",
+		}
+	}
+	return nil
+}
+
+// remainderCancelAttempts is how often canceling the unfilled remainder of an IOC or FOK order is tried
+var remainderCancelAttempts = 3
+
+// applyTimeInForce cancels the unfilled remainder of IOC and FOK orders and schedules the expiry of GTD orders.
+// On error the returned order is the placed one if it is still resting, otherwise it is empty.
+func (s *Server) applyTimeInForce(req models.PlaceOrderReq, order models.Order) (models.Order, *orderError) {
+	switch req.TimeInForce {
+	case "IOC", "FOK":
+		if order.Status != "filling" {
+			break
+		}
+
+		canceled, err := s.cancelRemainder(order.ID)
+		if err != nil {
+			s.logger.Error("failed to cancel remainder of order", "orderID", order.ID, "timeInForce", req.TimeInForce, "error", err)
+			return order, &orderError{
+				status:  http.StatusInternalServerError,
+				message: "unfilled remainder could not be canceled, order " + strconv.Itoa(order.ID) + " is still resting",
+			}
+		}
+		canceled.ClientOrderID = order.ClientOrderID
+		order = canceled
+
+	case "GTD":
+		expireTime, _ := time.Parse(time.RFC3339, req.ExpireTime)
+		if err := s.scheduler.CancelAt(order.UserID, order.ID, expireTime); err != nil {
+			// an order that would never expire must not stay in the book
+			if _, err := s.router.CancelOrder(order.ID); err != nil {
+				s.logger.Error("failed to cancel order without expiry", "orderID", order.ID, "error", err)
+				return order, &orderError{
+					status:  http.StatusInternalServerError,
+					message: "expiry could not be scheduled, order " + strconv.Itoa(order.ID) + " is still resting",
+				}
+			}
+			return models.Order{}, &orderError{
+				status:  http.StatusInternalServerError,
+				message: "internal error in database",
+			}
+		}
+	}
+
+	order.TimeInForce = req.TimeInForce
+	order.ExpireTime = req.ExpireTime
+	order.PostOnly = req.PostOnly
+	return order, nil
+}
+
+// cancelRemainder cancels the order, retrying while the matching engine may not have received the cancel
+func (s *Server) cancelRemainder(orderID int) (models.Order, error) {
+	for attempt := 1; ; attempt++ {
+		canceled, err := s.router.CancelOrder(orderID)
+		if err == nil || isOrderRejected(err) || attempt == remainderCancelAttempts {
+			return canceled, err
+		}
+		time.Sleep(time.Duration(attempt) * 100 * time.Millisecond)
+	}
+}
+
+// expiryJob returns the pending job that expires the GTD order, an empty job if the order has none
+func (s *Server) expiryJob(userID, orderID int) (models.ScheduledJob, error) {
+	jobs, err := s.db.GetPendingScheduledJobsByUser(userID, "cancel")
+	if err != nil {
+		return models.ScheduledJob{}, err
+	}
+
+	for _, job := range jobs {
+		if job.OrderID == orderID {
+			return job, nil
+		}
+	}
+	return models.ScheduledJob{}, nil
+}
+
+// submitScheduledOrder holds a checked GAT order until its start time
+func (s *Server) submitScheduledOrder(req models.PlaceOrderReq, idempotencyKey string) (models.Order, *orderError) {
+	if req.ClientOrderID != "" || idempotencyKey != "" {
+		return models.Order{}, &orderError{
+			status:  http.StatusBadRequest,
+			message: "clientOrderId and Idempotency-Key are not supported for GAT orders",
+		}
+	}
+
+	startTime, _ := time.Parse(time.RFC3339, req.StartTime)
+	job, err := s.scheduler.PlaceAt(req, startTime)
+	if err != nil {
+		return models.Order{}, &orderError{
+			status:  http.StatusInternalServerError,
+			message: "internal error in database",
+		}
+	}
+	return converter.ScheduledJobToModelsOrder(job), nil
+}
+
+func (s *Server) cancelScheduledOrder(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("scheduledOrderID"))
+	if err != nil || id < 1 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid scheduledOrderId",
+		})
+	}
+
+	existing, err := s.db.GetScheduledJob(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "order not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+
+	if existing.Kind != "place" {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "order not found",
+		})
+	}
+
+	if userID, _ := middleware.UserID(c); existing.UserID != userID {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "access to another user's orders is forbidden",
+		})
+	}
+
+	if ok, err := s.allowRate(c, "cancel", 1); !ok {
+		return err
+	}
+
+	canceled, err := s.scheduler.CancelScheduledOrder(id)
+	if err != nil {
+		if errors.Is(err, scheduler.ErrNotCancelable) {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+	return c.JSON(http.StatusOK, converter.ScheduledJobToModelsOrder(canceled))
+}
+
+// scheduledOrders lists the orders of the user still held until their start time
+func (s *Server) scheduledOrders(userID int) ([]models.Order, error) {
+	jobs, err := s.db.GetPendingScheduledJobsByUser(userID, "place")
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []models.Order
+	for _, job := range jobs {
+		orders = append(orders, converter.ScheduledJobToModelsOrder(job))
+	}
+	return orders, nil
+}
+
+// finestOrderBook is the cached order book of the pair at the tick size, where levels are not merged
+func (s *Server) finestOrderBook(pair string) (marketData.OrderBookSnapshot, *orderError) {
+	pairParams, _ := s.reconciler.PairParams(pair)
+	if len(pairParams.OrderBookPricePrecisions) > 0 {
+		pOBS, ok := s.cache.OrderBookSnapshot(pair, slices.Max(pairParams.OrderBookPricePrecisions))
+		if ok && !pOBS.Stale {
+			return pOBS, nil
+		}
+	}
+
+	return marketData.OrderBookSnapshot{}, &orderError{
+		status:  http.StatusServiceUnavailable,
+		message: "order book is not available",
+	}
+}
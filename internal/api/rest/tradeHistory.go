@@ -0,0 +1,84 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/middleware"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/repository"
+	"github.com/labstack/echo/v4"
+)
+
+type executionsPage struct {
+	Executions []models.Execution `json:"executions"`
+	// NextCursor is empty on the last page
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// getMyTrades pages through the executions of the authenticated user from the newest
+func (s *Server) getMyTrades(c echo.Context) error {
+	userID, _ := middleware.UserID(c)
+
+	from, to, message := parseTimeRange(c)
+	if message != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": message,
+		})
+	}
+
+	limit, message := parsePageSize(c)
+	if message != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": message,
+		})
+	}
+
+	filter := models.ExecutionFilter{
+		UserID: userID,
+		Pair:   c.QueryParam("pair"),
+		From:   from,
+		To:     to,
+		// one more execution tells whether there is a next page
+		Limit: limit + 1,
+	}
+
+	if value := c.QueryParam("cursor"); value != "" {
+		var cursor models.ExecutionCursor
+		if !decodeCursor(value, &cursor) || cursor.Time == "" || cursor.TradeID < 1 || (cursor.Role != "maker" && cursor.Role != "taker") {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "invalid cursor",
+			})
+		}
+		filter.After = &cursor
+	}
+
+	executions, err := s.db.GetExecutions(filter)
+	if errors.Is(err, repository.ErrUnavailable) {
+		return c.JSON(http.StatusNotImplemented, map[string]string{
+			"error": "trade history is not available",
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "internal error in database",
+		})
+	}
+
+	page := executionsPage{
+		Executions: executions,
+	}
+	if page.Executions == nil {
+		page.Executions = []models.Execution{}
+	}
+	if len(executions) > limit {
+		last := executions[limit-1]
+		page.Executions = executions[:limit]
+		page.NextCursor = encodeCursor(models.ExecutionCursor{
+			Time:    last.Time,
+			TradeID: last.TradeID,
+			Role:    last.Role,
+		})
+	}
+	return c.JSON(http.StatusOK, page)
+}
@@ -1,209 +1,209 @@
-package ws
-
-import (
-	"context"
-	"encoding/json"
-
-	"github.com/BazaarTrade/ApiGatewayService/internal/models"
-	"github.com/coder/websocket"
-)
-
-func (h *Hub) BroadcastOrder(order models.Order) error {
-	h.mu.RLock()
-	user, exists := h.Users[order.UserID]
-	h.mu.RUnlock()
-
-	if !exists {
-		return nil
-	}
-
-	message := struct {
-		Topic string       `json:"topic"`
-		Order models.Order `json:"order"`
-	}{
-		Topic: "orderUpdate",
-		Order: order,
-	}
-
-	messageJSON, err := json.Marshal(message)
-	if err != nil {
-		h.logger.Error("failed to marshal order message", "error", err)
-		return err
-	}
-
-	for client := range user.Clients {
-		go func(c *Client) {
-			c.mu.Lock()
-			defer c.mu.Unlock()
-
-			err := c.Conn.Write(context.Background(), websocket.MessageText, messageJSON)
-			if err != nil {
-				h.logger.Error("failed to write order message to websocket", "error", err)
-				return
-			}
-		}(client)
-	}
-	return nil
-}
-
-func (h *Hub) BroadcastPrecisedOrderBookSnapshot(pOBS models.OrderBookSnapshot, orderBookprecision int32) {
-	h.mu.RLock()
-	subscribers, exists := h.Subscribers["orderBook"][OrderBookParams{
-		Pair:      pOBS.Pair,
-		Precision: orderBookprecision,
-	}]
-	h.mu.RUnlock()
-
-	if !exists || subscribers == nil {
-		h.logger.Info("failed to find subscribers for orderBook", "pair", pOBS.Pair, "orderBookprecision", orderBookprecision)
-		return
-	}
-
-	message := struct {
-		Topic  string                   `json:"topic"`
-		Params models.OrderBookSnapshot `json:"params"`
-	}{
-		Topic:  "orderBook",
-		Params: pOBS,
-	}
-
-	OBSJSON, err := json.Marshal(message)
-	if err != nil {
-		h.logger.Error("failed to marshal pOBS message", "error", err)
-		return
-	}
-
-	for client := range subscribers.Clients {
-		go func(c *Client) {
-			c.mu.Lock()
-			defer c.mu.Unlock()
-
-			err := c.Conn.Write(context.Background(), websocket.MessageText, OBSJSON)
-			if err != nil {
-				h.logger.Error("failed to write pOBS message to websocket", "error", err)
-				return
-			}
-		}(client)
-	}
-}
-
-func (h *Hub) BroadcastPrecisedTrades(precisedTrades []models.Trade) {
-	h.mu.RLock()
-	subscribers, exists := h.Subscribers["trades"][TradesParams{
-		Pair: precisedTrades[0].Pair,
-	}]
-	h.mu.RUnlock()
-
-	if !exists || subscribers == nil {
-		h.logger.Info("failed to find subscribers for trades", "pair", precisedTrades[0].Pair)
-		return
-	}
-
-	message := struct {
-		Topic  string         `json:"topic"`
-		Params []models.Trade `json:"params"`
-	}{
-		Topic:  "trades",
-		Params: precisedTrades,
-	}
-
-	tradesJSON, err := json.Marshal(message)
-	if err != nil {
-		h.logger.Error("failed to marshal precised trades message", "error", err)
-		return
-	}
-
-	for client := range subscribers.Clients {
-		go func(c *Client) {
-			c.mu.Lock()
-			defer c.mu.Unlock()
-
-			err := c.Conn.Write(context.Background(), websocket.MessageText, tradesJSON)
-			if err != nil {
-				h.logger.Error("failed to write precised trades message to websocket", "error", err)
-				return
-			}
-		}(client)
-	}
-}
-
-func (h *Hub) BroadcastTicker(ticker models.Ticker) {
-	h.mu.RLock()
-	subscribers, exists := h.Subscribers["ticker"][TickerParams{
-		Pair: ticker.Pair,
-	}]
-	h.mu.RUnlock()
-
-	if !exists || subscribers == nil {
-		h.logger.Info("failed to find subscribers for ticker", "pair", ticker.Pair)
-		return
-	}
-
-	message := struct {
-		Topic  string        `json:"topic"`
-		Params models.Ticker `json:"params"`
-	}{
-		Topic:  "ticker",
-		Params: ticker,
-	}
-
-	tickerJSON, err := json.Marshal(message)
-	if err != nil {
-		h.logger.Error("failed to marshal ticker message", "error", err)
-		return
-	}
-
-	for client := range subscribers.Clients {
-		go func(c *Client) {
-			c.mu.Lock()
-			defer c.mu.Unlock()
-
-			err := c.Conn.Write(context.Background(), websocket.MessageText, tickerJSON)
-			if err != nil {
-				h.logger.Error("failed to write ticker message to websocket", "error", err)
-				return
-			}
-		}(client)
-	}
-}
-
-func (h *Hub) BroadcastCandleStick(candleStick models.CandleStick) {
-	h.mu.RLock()
-	subscribers, exists := h.Subscribers["candleStick"][CandleStickParams{
-		Pair:      candleStick.Pair,
-		Timeframe: candleStick.Timeframe,
-	}]
-	h.mu.RUnlock()
-
-	if !exists || subscribers == nil {
-		h.logger.Info("faied to find subscribers for candleStick", "pair", candleStick.Pair)
-		return
-	}
-
-	message := struct {
-		Topic  string             `json:"topic"`
-		Params models.CandleStick `json:"params"`
-	}{
-		Topic:  "candleStick",
-		Params: candleStick,
-	}
-
-	candleStickJSON, err := json.Marshal(message)
-	if err != nil {
-		h.logger.Error("failed to marshal candleStick message", "error", err)
-		return
-	}
-
-	for client := range subscribers.Clients {
-		go func(c *Client) {
-			c.mu.Lock()
-			defer c.mu.Unlock()
-
-			err := c.Conn.Write(context.Background(), websocket.MessageText, candleStickJSON)
-			if err != nil {
-				h.logger.Error("failed to write candleStick message to websocket", "error", err)
-				return
-			}
-		}(client)
-	}
-}
+package ws
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+)
+
+// BroadcastOrder sends the order update to the clients of its user that authenticated with an access token
+func (h *Hub) BroadcastOrder(order models.Order) error {
+	message := struct {
+		Topic string       `json:"topic"`
+		Order models.Order `json:"order"`
+	}{
+		Topic: "orderUpdate",
+		Order: order,
+	}
+
+	messageJSON, err := json.Marshal(message)
+	if err != nil {
+		h.logger.Error("failed to marshal order message", "error", err)
+		return err
+	}
+
+	h.pipeline.submit("orderUpdate|"+strconv.Itoa(order.UserID), func() {
+		h.mu.RLock()
+		user, exists := h.Users[order.UserID]
+		var clients []*Client
+		if exists {
+			for client := range user.Clients {
+				if client.authenticated {
+					clients = append(clients, client)
+				}
+			}
+		}
+		h.mu.RUnlock()
+
+		for _, client := range clients {
+			client.enqueue(messageJSON)
+		}
+	})
+	return nil
+}
+
+func (h *Hub) BroadcastPrecisedOrderBookSnapshot(pOBS models.OrderBookSnapshot, orderBookprecision int32) {
+	message := struct {
+		Topic  string                   `json:"topic"`
+		Params models.OrderBookSnapshot `json:"params"`
+	}{
+		Topic:  "orderBook",
+		Params: pOBS,
+	}
+
+	OBSJSON, err := json.Marshal(message)
+	if err != nil {
+		h.logger.Error("failed to marshal pOBS message", "error", err)
+		return
+	}
+
+	h.publish("orderBook", pOBS.Pair, OrderBookParams{
+		Pair:      pOBS.Pair,
+		Precision: orderBookprecision,
+	}, OBSJSON)
+}
+
+func (h *Hub) BroadcastPrecisedTrades(precisedTrades []models.Trade) {
+	message := struct {
+		Topic  string         `json:"topic"`
+		Params []models.Trade `json:"params"`
+	}{
+		Topic:  "trades",
+		Params: precisedTrades,
+	}
+
+	tradesJSON, err := json.Marshal(message)
+	if err != nil {
+		h.logger.Error("failed to marshal precised trades message", "error", err)
+		return
+	}
+
+	h.publish("trades", precisedTrades[0].Pair, TradesParams{
+		Pair: precisedTrades[0].Pair,
+	}, tradesJSON)
+}
+
+func (h *Hub) BroadcastTicker(ticker models.Ticker) {
+	message := struct {
+		Topic  string        `json:"topic"`
+		Params models.Ticker `json:"params"`
+	}{
+		Topic:  "ticker",
+		Params: ticker,
+	}
+
+	tickerJSON, err := json.Marshal(message)
+	if err != nil {
+		h.logger.Error("failed to marshal ticker message", "error", err)
+		return
+	}
+
+	h.publish("ticker", ticker.Pair, TickerParams{
+		Pair: ticker.Pair,
+	}, tickerJSON)
+}
+
+func (h *Hub) BroadcastCandleStick(candleStick models.CandleStick) {
+	message := struct {
+		Topic  string             `json:"topic"`
+		Params models.CandleStick `json:"params"`
+	}{
+		Topic:  "candleStick",
+		Params: candleStick,
+	}
+
+	candleStickJSON, err := json.Marshal(message)
+	if err != nil {
+		h.logger.Error("failed to marshal candleStick message", "error", err)
+		return
+	}
+
+	h.publish("candleStick", candleStick.Pair, CandleStickParams{
+		Pair:      candleStick.Pair,
+		Timeframe: candleStick.Timeframe,
+	}, candleStickJSON)
+}
+
+// BroadcastTopicStatus notifies every subscriber of the pair on the topic,
+// e.g. that market data became stale or recovered.
+func (h *Hub) BroadcastTopicStatus(topic, pair, status string) {
+	message := struct {
+		Topic  string `json:"topic"`
+		Pair   string `json:"pair"`
+		Status string `json:"status"`
+	}{
+		Topic:  topic,
+		Pair:   pair,
+		Status: status,
+	}
+
+	messageJSON, err := json.Marshal(message)
+	if err != nil {
+		h.logger.Error("failed to marshal topic status message", "error", err)
+		return
+	}
+
+	// same key as the data of the topic, so the status is never delivered before
+	// an update that was received earlier
+	h.pipeline.submit(topic+"|"+pair, func() {
+		var clients []*Client
+
+		h.mu.RLock()
+		for params, subscribers := range h.Subscribers[topic] {
+			if paramsPair(params) != pair {
+				continue
+			}
+
+			for client := range subscribers.Clients {
+				clients = append(clients, client)
+			}
+		}
+		h.mu.RUnlock()
+
+		for _, client := range clients {
+			client.enqueue(messageJSON)
+		}
+	})
+}
+
+// publish hands the message to the subscribers of the topic params. Messages of the same
+// topic and pair are delivered in the order publish was called for them.
+func (h *Hub) publish(topic, pair string, params any, messageJSON []byte) {
+	h.pipeline.submit(topic+"|"+pair, func() {
+		var clients []*Client
+
+		h.mu.RLock()
+		subscribers, exists := h.Subscribers[topic][params]
+		if exists && subscribers != nil {
+			for client := range subscribers.Clients {
+				clients = append(clients, client)
+			}
+		}
+		h.mu.RUnlock()
+
+		if !exists || subscribers == nil {
+			h.logger.Info("failed to find subscribers", "topic", topic, "pair", pair)
+			return
+		}
+
+		for _, client := range clients {
+			client.enqueue(messageJSON)
+		}
+	})
+}
+
+func paramsPair(params any) string {
+	switch p := params.(type) {
+	case OrderBookParams:
+		return p.Pair
+	case TradesParams:
+		return p.Pair
+	case TickerParams:
+		return p.Pair
+	case CandleStickParams:
+		return p.Pair
+	default:
+		return ""
+	}
+}
@@ -1,427 +1,664 @@
-package ws
-
-import (
-	"context"
-	"encoding/json"
-	"errors"
-	"io"
-	"log/slog"
-	"net/http"
-	"strconv"
-	"sync"
-
-	"github.com/BazaarTrade/ApiGatewayService/internal/models"
-	"github.com/coder/websocket"
-	"github.com/labstack/echo/v4"
-)
-
-type Hub struct {
-	Users       map[int]*User
-	mu          sync.RWMutex
-	Subscribers map[string]map[any]*Subscribers
-	logger      *slog.Logger
-}
-
-type User struct {
-	ID      int
-	Clients map[*Client]bool
-}
-
-type Client struct {
-	Conn   *websocket.Conn
-	Topics map[string]any
-	mu     sync.RWMutex
-}
-
-type Subscribers struct {
-	Clients map[*Client]bool
-}
-
-type OrderBookParams struct {
-	Pair      string `json:"pair"`
-	Precision int32  `json:"precision"`
-}
-
-type TradesParams struct {
-	Pair string `json:"pair"`
-}
-
-type TickerParams struct {
-	Pair string `json:"pair"`
-}
-
-type CandleStickParams struct {
-	Pair      string `json:"pair"`
-	Timeframe string `json:"timeframe"`
-}
-
-func NewHub(logger *slog.Logger) *Hub {
-	return &Hub{
-		Users:       make(map[int]*User),
-		Subscribers: make(map[string]map[any]*Subscribers),
-		logger:      logger,
-		mu:          sync.RWMutex{},
-	}
-}
-
-func (h *Hub) HandleWebsocket(c echo.Context) error {
-	userID, err := strconv.Atoi(c.Param("userID"))
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "invalid userID",
-		})
-	}
-
-	if userID < 1 {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "invalid userID",
-		})
-	}
-
-	conn, err := websocket.Accept(c.Response(), c.Request(), &websocket.AcceptOptions{
-		OriginPatterns: []string{"*"},
-	})
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "failed to upgrade connection to WebSocket",
-		})
-	}
-
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
-	user, ok := h.Users[userID]
-	if !ok {
-		h.Users[userID] = &User{
-			ID:      userID,
-			Clients: make(map[*Client]bool),
-		}
-		user = h.Users[userID]
-	}
-
-	var client = &Client{
-		Conn:   conn,
-		Topics: make(map[string]any),
-		mu:     sync.RWMutex{},
-	}
-
-	user.Clients[client] = true
-
-	go h.readPump(client, userID)
-
-	return nil
-}
-
-func (h *Hub) readPump(c *Client, userID int) {
-	defer func() {
-		c.Conn.Close(websocket.StatusNormalClosure, "normal closure")
-
-		h.mu.Lock()
-		defer h.mu.Unlock()
-		//delete subscriber
-		for topic, params := range c.Topics {
-			if subscribers, exists := h.Subscribers[topic][params]; exists {
-				delete(subscribers.Clients, c)
-			}
-		}
-
-		//delete client, if no clients left - delete user
-		if user, exists := h.Users[userID]; exists {
-			delete(user.Clients, c)
-			if len(h.Users[userID].Clients) == 0 {
-				delete(h.Users, userID)
-			}
-		}
-	}()
-
-	for {
-		_, msg, err := c.Conn.Read(context.Background())
-		if err != nil {
-			if websocket.CloseStatus(err) == websocket.StatusNormalClosure || websocket.CloseStatus(err) == websocket.StatusGoingAway || errors.Is(err, io.EOF) {
-				h.logger.Debug("client disconnected from websocket", "userID", userID)
-				return
-			}
-			h.logger.Error("failed to read websocket connection", "error", err)
-			return
-		}
-
-		var request models.SubscriptionRequest
-		if err := json.Unmarshal(msg, &request); err != nil {
-			h.logger.Error("failed unmarshal SubscriptionRequest", "error", err)
-			continue
-		}
-
-		valid, params, err := h.unmarshalParams(request)
-		if err != nil {
-			continue
-		}
-
-		if !valid {
-			messageJSON, err := json.Marshal(map[string]string{
-				"error": "invalid request",
-			})
-			if err != nil {
-				h.logger.Error("failed to marshal error message", "error", err)
-				continue
-			}
-
-			if err := c.Conn.Write(context.Background(), websocket.MessageText, messageJSON); err != nil {
-				h.logger.Error("failed to write error message to websocket", "error", err)
-				continue
-			}
-			continue
-		}
-
-		switch request.Action {
-		case "subscribe":
-			h.subscribeClient(c, request.Topic, params)
-
-		case "unsubscribe":
-			h.unsubscribeClient(c, request.Topic, params)
-
-		default:
-			h.logger.Error("attempt to perform a non-existent action")
-		}
-	}
-}
-
-func (h *Hub) unmarshalParams(request models.SubscriptionRequest) (bool, any, error) {
-	switch request.Topic {
-	case "orderBook":
-		var params OrderBookParams
-		if err := json.Unmarshal(request.Params, &params); err != nil {
-			h.logger.Error("failed to unmarshal orderBook params", "error", err)
-			return false, nil, err
-		}
-
-		if params.Pair == "" {
-			h.logger.Error("invalid parameters for orderBook subscription")
-			return false, nil, nil
-		}
-		return true, params, nil
-
-	case "trades":
-		var params TradesParams
-		if err := json.Unmarshal(request.Params, &params); err != nil {
-			h.logger.Error("failed to unmarshal trades params", "error", err)
-			return false, nil, err
-		}
-
-		if params.Pair == "" {
-			h.logger.Error("invalid parameters for trades subscription")
-			return false, nil, nil
-		}
-		return true, params, nil
-
-	case "ticker":
-		var params TickerParams
-		if err := json.Unmarshal(request.Params, &params); err != nil {
-			h.logger.Error("failed to unmarshal ticker params", "error", err)
-			return false, nil, err
-		}
-
-		if params.Pair == "" {
-			h.logger.Error("invalid parameters for ticker subscription")
-			return false, nil, nil
-		}
-		return true, params, nil
-
-	case "candleStick":
-		var params CandleStickParams
-		if err := json.Unmarshal(request.Params, &params); err != nil {
-			h.logger.Error("failed to unmarshal candleStick params", "error", err)
-			return false, nil, err
-		}
-
-		if params.Pair == "" || params.Timeframe == "" {
-			h.logger.Error("invalid parameters for candleStick subscription")
-			return false, nil, nil
-		}
-		return true, params, nil
-
-	default:
-		h.logger.Error("attempt to subscribe to a non-existent topic")
-		return false, nil, nil
-	}
-}
-
-func (h *Hub) subscribeClient(c *Client, topic string, params any) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
-	if _, exists := h.Subscribers[topic]; !exists {
-		messageJSON, err := json.Marshal(map[string]string{
-			"error": "no such topic exists",
-		})
-		if err != nil {
-			h.logger.Error("failed to marshal error message", "error", err)
-			return
-		}
-
-		if err := c.Conn.Write(context.Background(), websocket.MessageText, messageJSON); err != nil {
-			h.logger.Error("failed to write error message to websocket", "error", err)
-			return
-		}
-		return
-	}
-
-	if _, exists := h.Subscribers[topic][params]; !exists {
-		messageJSON, err := json.Marshal(map[string]string{
-			"error": "this topic does not have such parameters",
-		})
-		if err != nil {
-			h.logger.Error("failed to marshal error message", "error", err)
-			return
-		}
-
-		if err := c.Conn.Write(context.Background(), websocket.MessageText, messageJSON); err != nil {
-			h.logger.Error("failed to write error message to websocket", "error", err)
-			return
-		}
-		return
-	}
-
-	h.Subscribers[topic][params].Clients[c] = true
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.Topics[topic] = params
-
-	subscriptionMessage := struct {
-		Topic  string `json:"topic"`
-		Status string `json:"status"`
-	}{
-		Topic:  topic,
-		Status: "subscribed",
-	}
-
-	messageJSON, err := json.Marshal(subscriptionMessage)
-	if err != nil {
-		h.logger.Error("failed to marshal subscription message", "error", err)
-		return
-	}
-
-	if err := c.Conn.Write(context.Background(), websocket.MessageText, messageJSON); err != nil {
-		h.logger.Error("failed to write subscription message to websocket", "error", err)
-		return
-	}
-}
-
-func (h *Hub) unsubscribeClient(c *Client, topic string, params any) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
-	if subscribers, exists := h.Subscribers[topic][params]; exists {
-		delete(subscribers.Clients, c)
-	}
-
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	delete(c.Topics, topic)
-
-	subscriptionMessage := struct {
-		Topic  string `json:"topic"`
-		Status string `json:"status"`
-	}{
-		Topic:  topic,
-		Status: "unsubscribed",
-	}
-
-	messageJSON, err := json.Marshal(subscriptionMessage)
-	if err != nil {
-		h.logger.Error("failed to marshal subscription message", "error", err)
-		return
-	}
-
-	if err := c.Conn.Write(context.Background(), websocket.MessageText, messageJSON); err != nil {
-		h.logger.Error("failed to write subscription message to websocket", "error", err)
-		return
-	}
-}
-
-func (h *Hub) AddOrderBookSnapshotTopic(pair string, orderBookPricePrecisions []int32) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
-	if _, exists := h.Subscribers["orderBook"]; !exists {
-		h.Subscribers["orderBook"] = make(map[any]*Subscribers)
-	}
-
-	for _, pricePrecision := range orderBookPricePrecisions {
-		h.Subscribers["orderBook"][OrderBookParams{Pair: pair, Precision: pricePrecision}] = &Subscribers{Clients: map[*Client]bool{}}
-	}
-}
-
-func (h *Hub) RemoveOrderBookSnapshotTopic(pair string, pricePrecisions []int32) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
-	if topic, exists := h.Subscribers["orderBook"]; exists {
-		for _, pricePrecision := range pricePrecisions {
-			delete(topic, OrderBookParams{Pair: pair, Precision: pricePrecision})
-		}
-	}
-}
-
-func (h *Hub) AddTradesTopic(pair string) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
-	if _, exists := h.Subscribers["trades"]; !exists {
-		h.Subscribers["trades"] = make(map[any]*Subscribers)
-	}
-
-	h.Subscribers["trades"][TradesParams{Pair: pair}] = &Subscribers{Clients: map[*Client]bool{}}
-}
-
-func (h *Hub) RemoveTradesTopic(pair string) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
-	if topic, exists := h.Subscribers["trades"]; exists {
-		delete(topic, TradesParams{Pair: pair})
-	}
-}
-
-func (h *Hub) AddTickerTopic(pair string) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
-	if _, exists := h.Subscribers["ticker"]; !exists {
-		h.Subscribers["ticker"] = make(map[any]*Subscribers)
-	}
-
-	h.Subscribers["ticker"][TickerParams{Pair: pair}] = &Subscribers{Clients: map[*Client]bool{}}
-}
-
-func (h *Hub) RemoveTickerTopic(pair string) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
-	if topic, exists := h.Subscribers["ticker"]; exists {
-		delete(topic, TickerParams{Pair: pair})
-	}
-}
-
-func (h *Hub) AddCandleStickTopic(pair string, timeframes []string) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
-	if _, exists := h.Subscribers["candleStick"]; !exists {
-		h.Subscribers["candleStick"] = make(map[any]*Subscribers)
-	}
-
-	for _, timeframe := range timeframes {
-		h.Subscribers["candleStick"][CandleStickParams{Pair: pair, Timeframe: timeframe}] = &Subscribers{Clients: map[*Client]bool{}}
-	}
-}
-
-func (h *Hub) RemoveCandleStickTopic(pair string, candleStickTimeframes []string) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
-	if topic, exists := h.Subscribers["candleStick"]; exists {
-		for _, timeframe := range candleStickTimeframes {
-			delete(topic, CandleStickParams{Pair: pair, Timeframe: timeframe})
-		}
-	}
-}
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/coder/websocket"
+	"github.com/labstack/echo/v4"
+)
+
+type Hub struct {
+	Users       map[int]*User
+	mu          sync.RWMutex
+	Subscribers map[string]map[any]*Subscribers
+	pipeline    *pipeline
+	onDemand    func(topic, pair string)
+	// authenticate returns the user of a valid access token
+	authenticate func(accessToken string) (int, bool)
+	onDisconnect func(userID int)
+	logger       *slog.Logger
+}
+
+type User struct {
+	ID      int
+	Clients map[*Client]bool
+	// cancelOnDisconnect is set by a session that opted in and cleared once no authenticated client is left
+	cancelOnDisconnect bool
+}
+
+type Client struct {
+	Conn *websocket.Conn
+	// Topics holds the subscribed params of every topic, a client can subscribe to a topic for several pairs
+	Topics map[string]map[any]bool
+	mu     sync.RWMutex
+
+	send      chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// authenticated is set for connections opened with the access token of their user,
+	// only they receive the order updates of the user
+	authenticated bool
+}
+
+var (
+	clientSendBufferSize = 256
+	writeTimeout         = 10 * time.Second
+)
+
+type Subscribers struct {
+	Clients map[*Client]bool
+}
+
+type OrderBookParams struct {
+	Pair      string `json:"pair"`
+	Precision int32  `json:"precision"`
+}
+
+type TradesParams struct {
+	Pair string `json:"pair"`
+}
+
+type TickerParams struct {
+	Pair string `json:"pair"`
+}
+
+type CandleStickParams struct {
+	Pair      string `json:"pair"`
+	Timeframe string `json:"timeframe"`
+}
+
+func NewHub(logger *slog.Logger) *Hub {
+	return &Hub{
+		Users:       make(map[int]*User),
+		Subscribers: make(map[string]map[any]*Subscribers),
+		pipeline:    newPipeline(defaultPipelineWorkers()),
+		logger:      logger,
+		mu:          sync.RWMutex{},
+	}
+}
+
+func (h *Hub) HandleWebsocket(c echo.Context) error {
+	userID, err := strconv.Atoi(c.Param("userID"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid userID",
+		})
+	}
+
+	if userID < 1 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid userID",
+		})
+	}
+
+	authenticated, err := h.authenticateConnection(c, userID)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	cancelOnDisconnect := c.QueryParam("cancelOnDisconnect") == "true"
+	if cancelOnDisconnect && !authenticated {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "cancelOnDisconnect requires an access token",
+		})
+	}
+
+	conn, err := websocket.Accept(c.Response(), c.Request(), &websocket.AcceptOptions{
+		OriginPatterns: []string{"*"},
+	})
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "failed to upgrade connection to WebSocket",
+		})
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	user, ok := h.Users[userID]
+	if !ok {
+		h.Users[userID] = &User{
+			ID:      userID,
+			Clients: make(map[*Client]bool),
+		}
+		user = h.Users[userID]
+	}
+
+	var client = &Client{
+		Conn:   conn,
+		Topics: make(map[string]map[any]bool),
+		mu:     sync.RWMutex{},
+		send:   make(chan []byte, clientSendBufferSize),
+		done:   make(chan struct{}),
+
+		authenticated: authenticated,
+	}
+
+	user.Clients[client] = true
+	if cancelOnDisconnect {
+		user.cancelOnDisconnect = true
+	}
+
+	go h.readPump(client, userID)
+	go h.writePump(client, userID)
+
+	return nil
+}
+
+func (h *Hub) readPump(c *Client, userID int) {
+	defer func() {
+		c.close(websocket.StatusNormalClosure, "normal closure")
+
+		var (
+			topics          = make(map[string][]any)
+			cancelAllOrders bool
+		)
+
+		h.mu.Lock()
+		defer func() {
+			h.mu.Unlock()
+			for topic, paramsList := range topics {
+				for _, params := range paramsList {
+					h.notifyDemand(topic, paramsPair(params))
+				}
+			}
+			if cancelAllOrders && h.onDisconnect != nil {
+				go h.onDisconnect(userID)
+			}
+		}()
+		//delete subscriber
+		for topic, paramsSet := range c.Topics {
+			for params := range paramsSet {
+				if subscribers, exists := h.Subscribers[topic][params]; exists {
+					delete(subscribers.Clients, c)
+				}
+				topics[topic] = append(topics[topic], params)
+			}
+		}
+
+		//delete client, if no clients left - delete user
+		if user, exists := h.Users[userID]; exists {
+			delete(user.Clients, c)
+			if c.authenticated && !user.hasAuthenticatedClients() {
+				cancelAllOrders = user.cancelOnDisconnect
+				user.cancelOnDisconnect = false
+			}
+			if len(h.Users[userID].Clients) == 0 {
+				delete(h.Users, userID)
+			}
+		}
+	}()
+
+	for {
+		_, msg, err := c.Conn.Read(context.Background())
+		if err != nil {
+			if websocket.CloseStatus(err) == websocket.StatusNormalClosure || websocket.CloseStatus(err) == websocket.StatusGoingAway || errors.Is(err, io.EOF) {
+				h.logger.Debug("client disconnected from websocket", "userID", userID)
+				return
+			}
+			h.logger.Error("failed to read websocket connection", "error", err)
+			return
+		}
+
+		var request models.SubscriptionRequest
+		if err := json.Unmarshal(msg, &request); err != nil {
+			h.logger.Error("failed unmarshal SubscriptionRequest", "error", err)
+			continue
+		}
+
+		valid, params, err := h.unmarshalParams(request)
+		if err != nil {
+			continue
+		}
+
+		if !valid {
+			messageJSON, err := json.Marshal(map[string]string{
+				"error": "invalid request",
+			})
+			if err != nil {
+				h.logger.Error("failed to marshal error message", "error", err)
+				continue
+			}
+
+			c.enqueue(messageJSON)
+			continue
+		}
+
+		switch request.Action {
+		case "subscribe":
+			h.subscribeClient(c, request.Topic, params)
+			h.notifyDemand(request.Topic, paramsPair(params))
+
+		case "unsubscribe":
+			h.unsubscribeClient(c, request.Topic, params)
+			h.notifyDemand(request.Topic, paramsPair(params))
+
+		default:
+			h.logger.Error("attempt to perform a non-existent action")
+		}
+	}
+}
+
+// writePump is the only writer of the connection, messages are written in the order they were enqueued
+func (h *Hub) writePump(c *Client, userID int) {
+	for {
+		select {
+		case <-c.done:
+			return
+
+		case message := <-c.send:
+			ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+			err := c.Conn.Write(ctx, websocket.MessageText, message)
+			cancel()
+			if err != nil {
+				h.logger.Debug("failed to write message to websocket", "userID", userID, "error", err)
+				c.close(websocket.StatusInternalError, "write failed")
+				return
+			}
+		}
+	}
+}
+
+// enqueue never blocks the caller, a client that can not keep up with its queue is disconnected
+// instead of silently missing messages.
+func (c *Client) enqueue(message []byte) {
+	select {
+	case <-c.done:
+	case c.send <- message:
+	default:
+		c.close(websocket.StatusPolicyViolation, "client is too slow")
+	}
+}
+
+func (c *Client) close(code websocket.StatusCode, reason string) {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		go c.Conn.Close(code, reason)
+	})
+}
+
+func (h *Hub) unmarshalParams(request models.SubscriptionRequest) (bool, any, error) {
+	switch request.Topic {
+	case "orderBook":
+		var params OrderBookParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			h.logger.Error("failed to unmarshal orderBook params", "error", err)
+			return false, nil, err
+		}
+
+		if params.Pair == "" {
+			h.logger.Error("invalid parameters for orderBook subscription")
+			return false, nil, nil
+		}
+		return true, params, nil
+
+	case "trades":
+		var params TradesParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			h.logger.Error("failed to unmarshal trades params", "error", err)
+			return false, nil, err
+		}
+
+		if params.Pair == "" {
+			h.logger.Error("invalid parameters for trades subscription")
+			return false, nil, nil
+		}
+		return true, params, nil
+
+	case "ticker":
+		var params TickerParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			h.logger.Error("failed to unmarshal ticker params", "error", err)
+			return false, nil, err
+		}
+
+		if params.Pair == "" {
+			h.logger.Error("invalid parameters for ticker subscription")
+			return false, nil, nil
+		}
+		return true, params, nil
+
+	case "candleStick":
+		var params CandleStickParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			h.logger.Error("failed to unmarshal candleStick params", "error", err)
+			return false, nil, err
+		}
+
+		if params.Pair == "" || params.Timeframe == "" {
+			h.logger.Error("invalid parameters for candleStick subscription")
+			return false, nil, nil
+		}
+		return true, params, nil
+
+	default:
+		h.logger.Error("attempt to subscribe to a non-existent topic")
+		return false, nil, nil
+	}
+}
+
+func (h *Hub) subscribeClient(c *Client, topic string, params any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, exists := h.Subscribers[topic]; !exists {
+		messageJSON, err := json.Marshal(map[string]string{
+			"error": "no such topic exists",
+		})
+		if err != nil {
+			h.logger.Error("failed to marshal error message", "error", err)
+			return
+		}
+
+		c.enqueue(messageJSON)
+		return
+	}
+
+	if _, exists := h.Subscribers[topic][params]; !exists {
+		messageJSON, err := json.Marshal(map[string]string{
+			"error": "this topic does not have such parameters",
+		})
+		if err != nil {
+			h.logger.Error("failed to marshal error message", "error", err)
+			return
+		}
+
+		c.enqueue(messageJSON)
+		return
+	}
+
+	h.Subscribers[topic][params].Clients[c] = true
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Topics[topic] == nil {
+		c.Topics[topic] = make(map[any]bool)
+	}
+	c.Topics[topic][params] = true
+
+	subscriptionMessage := struct {
+		Topic  string `json:"topic"`
+		Status string `json:"status"`
+	}{
+		Topic:  topic,
+		Status: "subscribed",
+	}
+
+	messageJSON, err := json.Marshal(subscriptionMessage)
+	if err != nil {
+		h.logger.Error("failed to marshal subscription message", "error", err)
+		return
+	}
+
+	c.enqueue(messageJSON)
+}
+
+func (h *Hub) unsubscribeClient(c *Client, topic string, params any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if subscribers, exists := h.Subscribers[topic][params]; exists {
+		delete(subscribers.Clients, c)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.Topics[topic], params)
+	if len(c.Topics[topic]) == 0 {
+		delete(c.Topics, topic)
+	}
+
+	subscriptionMessage := struct {
+		Topic  string `json:"topic"`
+		Status string `json:"status"`
+	}{
+		Topic:  topic,
+		Status: "unsubscribed",
+	}
+
+	messageJSON, err := json.Marshal(subscriptionMessage)
+	if err != nil {
+		h.logger.Error("failed to marshal subscription message", "error", err)
+		return
+	}
+
+	c.enqueue(messageJSON)
+}
+
+func (h *Hub) AddOrderBookSnapshotTopic(pair string, orderBookPricePrecisions []int32) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, exists := h.Subscribers["orderBook"]; !exists {
+		h.Subscribers["orderBook"] = make(map[any]*Subscribers)
+	}
+
+	for _, pricePrecision := range orderBookPricePrecisions {
+		params := OrderBookParams{Pair: pair, Precision: pricePrecision}
+		if _, exists := h.Subscribers["orderBook"][params]; !exists {
+			h.Subscribers["orderBook"][params] = &Subscribers{Clients: map[*Client]bool{}}
+		}
+	}
+}
+
+func (h *Hub) RemoveOrderBookSnapshotTopic(pair string, pricePrecisions []int32) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if topic, exists := h.Subscribers["orderBook"]; exists {
+		for _, pricePrecision := range pricePrecisions {
+			delete(topic, OrderBookParams{Pair: pair, Precision: pricePrecision})
+		}
+	}
+}
+
+func (h *Hub) AddTradesTopic(pair string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, exists := h.Subscribers["trades"]; !exists {
+		h.Subscribers["trades"] = make(map[any]*Subscribers)
+	}
+
+	if _, exists := h.Subscribers["trades"][TradesParams{Pair: pair}]; !exists {
+		h.Subscribers["trades"][TradesParams{Pair: pair}] = &Subscribers{Clients: map[*Client]bool{}}
+	}
+}
+
+func (h *Hub) RemoveTradesTopic(pair string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if topic, exists := h.Subscribers["trades"]; exists {
+		delete(topic, TradesParams{Pair: pair})
+	}
+}
+
+func (h *Hub) AddTickerTopic(pair string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, exists := h.Subscribers["ticker"]; !exists {
+		h.Subscribers["ticker"] = make(map[any]*Subscribers)
+	}
+
+	if _, exists := h.Subscribers["ticker"][TickerParams{Pair: pair}]; !exists {
+		h.Subscribers["ticker"][TickerParams{Pair: pair}] = &Subscribers{Clients: map[*Client]bool{}}
+	}
+}
+
+func (h *Hub) RemoveTickerTopic(pair string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if topic, exists := h.Subscribers["ticker"]; exists {
+		delete(topic, TickerParams{Pair: pair})
+	}
+}
+
+func (h *Hub) AddCandleStickTopic(pair string, timeframes []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, exists := h.Subscribers["candleStick"]; !exists {
+		h.Subscribers["candleStick"] = make(map[any]*Subscribers)
+	}
+
+	for _, timeframe := range timeframes {
+		params := CandleStickParams{Pair: pair, Timeframe: timeframe}
+		if _, exists := h.Subscribers["candleStick"][params]; !exists {
+			h.Subscribers["candleStick"][params] = &Subscribers{Clients: map[*Client]bool{}}
+		}
+	}
+}
+
+func (h *Hub) RemoveCandleStickTopic(pair string, candleStickTimeframes []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if topic, exists := h.Subscribers["candleStick"]; exists {
+		for _, timeframe := range candleStickTimeframes {
+			delete(topic, CandleStickParams{Pair: pair, Timeframe: timeframe})
+		}
+	}
+}
+
+// SetDemandHandler registers the handler called after the subscribers of a topic and pair changed,
+// it must be set before clients connect. The handler is called without the hub lock held.
+func (h *Hub) SetDemandHandler(handler func(topic, pair string)) {
+	h.onDemand = handler
+}
+
+func (h *Hub) notifyDemand(topic, pair string) {
+	if h.onDemand != nil {
+		h.onDemand(topic, pair)
+	}
+}
+
+// SetAuthenticator registers the access token validation of websocket connections,
+// it must be set before clients connect. Without it only anonymous connections are accepted.
+func (h *Hub) SetAuthenticator(authenticate func(accessToken string) (int, bool)) {
+	h.authenticate = authenticate
+}
+
+// SetDisconnectHandler registers the handler called when the last authenticated connection
+// of a user is gone after one of the user's sessions opted in to cancelOnDisconnect,
+// it must be set before clients connect. The handler runs in its own goroutine.
+func (h *Hub) SetDisconnectHandler(handler func(userID int)) {
+	h.onDisconnect = handler
+}
+
+// authenticateConnection checks the optional access token of the connection, passed in the
+// Authorization header or, for browsers, in the token query parameter
+func (h *Hub) authenticateConnection(c echo.Context, userID int) (bool, error) {
+	accessToken := c.QueryParam("token")
+	if authHeader := c.Request().Header.Get("Authorization"); authHeader != "" {
+		accessToken = strings.TrimPrefix(authHeader, "Bearer ")
+	}
+
+	if accessToken == "" {
+		return false, nil
+	}
+
+	if h.authenticate == nil {
+		return false, errors.New("access tokens are not supported")
+	}
+
+	tokenUserID, ok := h.authenticate(accessToken)
+	if !ok || tokenUserID != userID {
+		return false, errors.New("invalid access token")
+	}
+	return true, nil
+}
+
+func (u *User) hasAuthenticatedClients() bool {
+	for client := range u.Clients {
+		if client.authenticated {
+			return true
+		}
+	}
+	return false
+}
+
+// SubscribersCount returns the number of clients subscribed to the topic for any params of the pair.
+func (h *Hub) SubscribersCount(topic, pair string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var count int
+	for params, subscribers := range h.Subscribers[topic] {
+		if paramsPair(params) == pair {
+			count += len(subscribers.Clients)
+		}
+	}
+	return count
+}
+
+// SyncPairTopics registers all topics of the pair and removes order book precisions
+// and candle stick timeframes that are no longer listed. Existing subscribers are kept.
+func (h *Hub) SyncPairTopics(pairParams models.PairParams) {
+	h.AddOrderBookSnapshotTopic(pairParams.Pair, pairParams.OrderBookPricePrecisions)
+	h.AddTradesTopic(pairParams.Pair)
+	h.AddTickerTopic(pairParams.Pair)
+	h.AddCandleStickTopic(pairParams.Pair, pairParams.CandleStickTimeframes)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for params := range h.Subscribers["orderBook"] {
+		if p := params.(OrderBookParams); p.Pair == pairParams.Pair && !slices.Contains(pairParams.OrderBookPricePrecisions, p.Precision) {
+			delete(h.Subscribers["orderBook"], params)
+		}
+	}
+
+	for params := range h.Subscribers["candleStick"] {
+		if p := params.(CandleStickParams); p.Pair == pairParams.Pair && !slices.Contains(pairParams.CandleStickTimeframes, p.Timeframe) {
+			delete(h.Subscribers["candleStick"], params)
+		}
+	}
+}
+
+func (h *Hub) RemovePairTopics(pair string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, topic := range h.Subscribers {
+		for params := range topic {
+			if paramsPair(params) == pair {
+				delete(topic, params)
+			}
+		}
+	}
+}
+
+// Pairs returns every pair that has at least one registered topic.
+func (h *Hub) Pairs() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var pairs = make(map[string]bool)
+	for _, topic := range h.Subscribers {
+		for params := range topic {
+			pairs[paramsPair(params)] = true
+		}
+	}
+
+	var result = make([]string, 0, len(pairs))
+	for pair := range pairs {
+		result = append(result, pair)
+	}
+	return result
+}
@@ -0,0 +1,44 @@
+package ws
+
+import (
+	"hash/fnv"
+	"runtime"
+)
+
+var pipelineQueueSize = 1024
+
+// pipeline runs submitted jobs in submission order per key and in parallel across keys.
+// Every key is pinned to one worker, so jobs of a key never overtake each other.
+type pipeline struct {
+	shards []chan func()
+}
+
+func newPipeline(workers int) *pipeline {
+	p := &pipeline{
+		shards: make([]chan func(), workers),
+	}
+
+	for i := range p.shards {
+		p.shards[i] = make(chan func(), pipelineQueueSize)
+		go p.work(p.shards[i])
+	}
+	return p
+}
+
+func defaultPipelineWorkers() int {
+	return 2 * runtime.GOMAXPROCS(0)
+}
+
+func (p *pipeline) work(jobs chan func()) {
+	for job := range jobs {
+		job()
+	}
+}
+
+// submit blocks while the worker queue of the key is full, which slows down
+// the producer instead of dropping or reordering messages
+func (p *pipeline) submit(key string, job func()) {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	p.shards[hash.Sum32()%uint32(len(p.shards))] <- job
+}
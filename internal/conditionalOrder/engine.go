@@ -0,0 +1,391 @@
+package conditionalOrder
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/big"
+	"sync"
+	"time"
+
+	ws "github.com/BazaarTrade/ApiGatewayService/internal/api/websocket"
+	"github.com/BazaarTrade/ApiGatewayService/internal/converter"
+	"github.com/BazaarTrade/ApiGatewayService/internal/decimal"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/orderRouter"
+	"github.com/BazaarTrade/ApiGatewayService/internal/repository"
+	"google.golang.org/grpc/status"
+)
+
+// syncInterval is how often trailing stops are persisted and orders of other gateways are picked up
+var syncInterval = 5 * time.Second
+
+var (
+	ErrNoLastPrice   = errors.New("trailing stop needs a stopPrice while the last price of the pair is unknown")
+	ErrWouldTrigger  = errors.New("stopPrice would trigger immediately")
+	ErrNotCancelable = errors.New("conditional order is not untriggered")
+)
+
+// Listener is notified when an untriggered order of this gateway is triggered or canceled
+type Listener interface {
+	OnConditionalOrderUpdate(conditionalOrder models.ConditionalOrder)
+}
+
+// Engine holds stop and trailing stop orders until the last trade price of their pair reaches
+// the stop price, then places the child order. Orders are stored in the repository,
+// so they survive restarts and are shared between gateways.
+type Engine struct {
+	db     repository.Repository
+	router *orderRouter.Router
+	hub    *ws.Hub
+
+	// untriggered orders by pair and id
+	orders map[string]map[int]*models.ConditionalOrder
+	// trailing stops whose stop price moved since the last sync
+	dirty     map[int]bool
+	listeners []Listener
+	// onWatch is called when a pair gets its first untriggered order or loses its last one
+	onWatch func(pair string, watched bool)
+	mu      sync.Mutex
+	logger  *slog.Logger
+}
+
+func New(db repository.Repository, router *orderRouter.Router, hub *ws.Hub, logger *slog.Logger) *Engine {
+	return &Engine{
+		db:     db,
+		router: router,
+		hub:    hub,
+		orders: make(map[string]map[int]*models.ConditionalOrder),
+		dirty:  make(map[int]bool),
+		logger: logger,
+	}
+}
+
+func (e *Engine) AddListener(listener Listener) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.listeners = append(e.listeners, listener)
+}
+
+// WatchedStreams are the quote streams stop prices are watched on
+var WatchedStreams = []string{"ticker", "trades"}
+
+// SetWatchHandler registers the handler called when a pair gets its first untriggered order or loses
+// its last one, so that WatchedStreams of the pair are kept open. Pairs already watched are reported
+// right away. The handler is called with the engine lock held and must not call back into the engine.
+func (e *Engine) SetWatchHandler(handler func(pair string, watched bool)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.onWatch = handler
+	for pair := range e.orders {
+		handler(pair, true)
+	}
+}
+
+// Load reads the untriggered orders from the repository. Orders that are no longer untriggered
+// there were triggered or canceled by another gateway and are dropped.
+func (e *Engine) Load() error {
+	conditionalOrders, err := e.db.GetUntriggeredConditionalOrders()
+	if err != nil {
+		return err
+	}
+
+	untriggered := make(map[int]bool, len(conditionalOrders))
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, conditionalOrder := range conditionalOrders {
+		untriggered[conditionalOrder.ID] = true
+		// the tracked trailing extreme may be newer than the stored one
+		if _, ok := e.orders[conditionalOrder.Pair][conditionalOrder.ID]; !ok {
+			e.add(conditionalOrder)
+		}
+	}
+
+	for pair, pairOrders := range e.orders {
+		for id := range pairOrders {
+			if !untriggered[id] {
+				e.remove(pair, id)
+			}
+		}
+	}
+	return nil
+}
+
+func (e *Engine) Run(ctx context.Context) {
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			e.flush()
+			return
+		case <-ticker.C:
+			e.flush()
+			if err := e.Load(); err != nil {
+				e.logger.Error("failed to load conditional orders", "error", err)
+			}
+		}
+	}
+}
+
+// Create stores a validated conditional order request, lastPrice is the last trade price of the pair and may be empty
+func (e *Engine) Create(req models.PlaceOrderReq, lastPrice string) (models.ConditionalOrder, error) {
+	conditionalOrder := models.ConditionalOrder{
+		UserID:        req.UserID,
+		IsBid:         req.IsBid,
+		Pair:          req.Pair,
+		Type:          req.Type,
+		Price:         req.Price,
+		Qty:           req.Qty,
+		StopPrice:     req.StopPrice,
+		TrailingDelta: req.TrailingDelta,
+		Status:        "untriggered",
+	}
+
+	last, hasLast := decimal.Parse(lastPrice)
+
+	if conditionalOrder.Type == "trailingStop" {
+		switch {
+		case hasLast:
+			conditionalOrder.ExtremePrice = lastPrice
+			if conditionalOrder.StopPrice == "" {
+				conditionalOrder.StopPrice = trail(last, conditionalOrder.TrailingDelta, conditionalOrder.IsBid)
+			}
+		case conditionalOrder.StopPrice != "":
+			// the extreme the given stop price trails
+			stopPrice, _ := decimal.Parse(conditionalOrder.StopPrice)
+			conditionalOrder.ExtremePrice = trail(stopPrice, conditionalOrder.TrailingDelta, !conditionalOrder.IsBid)
+		default:
+			return models.ConditionalOrder{}, ErrNoLastPrice
+		}
+	}
+
+	if hasLast && isTriggered(conditionalOrder, last) {
+		return models.ConditionalOrder{}, ErrWouldTrigger
+	}
+
+	conditionalOrder, err := e.db.CreateConditionalOrder(conditionalOrder)
+	if err != nil {
+		return models.ConditionalOrder{}, err
+	}
+
+	e.mu.Lock()
+	e.add(conditionalOrder)
+	e.mu.Unlock()
+
+	e.hub.BroadcastOrder(converter.ConditionalOrderToModelsOrder(conditionalOrder))
+	return conditionalOrder, nil
+}
+
+// Cancel cancels the untriggered order, the caller is expected to check that it belongs to the user
+func (e *Engine) Cancel(id int) (models.ConditionalOrder, error) {
+	ok, err := e.db.TransitConditionalOrder(id, "untriggered", "canceled")
+	if err != nil {
+		return models.ConditionalOrder{}, err
+	}
+	if !ok {
+		return models.ConditionalOrder{}, ErrNotCancelable
+	}
+
+	e.mu.Lock()
+	for pair := range e.orders {
+		e.remove(pair, id)
+	}
+	e.mu.Unlock()
+
+	conditionalOrder, err := e.db.GetConditionalOrder(id)
+	if err != nil {
+		return models.ConditionalOrder{}, err
+	}
+
+	e.publish(conditionalOrder)
+	return conditionalOrder, nil
+}
+
+func (e *Engine) OnTrades(trades []models.Trade) {
+	for _, trade := range trades {
+		e.evaluate(trade.Pair, trade.Price)
+	}
+}
+
+func (e *Engine) OnTicker(ticker models.Ticker) {
+	e.evaluate(ticker.Pair, ticker.LastPrice)
+}
+
+func (e *Engine) OnOrderBookSnapshot(models.OrderBookSnapshot, int32) {}
+
+func (e *Engine) OnCandleStick(models.CandleStick) {}
+
+// evaluate moves trailing stops along the price and triggers every order of the pair whose stop is reached
+func (e *Engine) evaluate(pair, lastPrice string) {
+	price, ok := decimal.Parse(lastPrice)
+	if !ok {
+		return
+	}
+
+	var triggered []models.ConditionalOrder
+
+	e.mu.Lock()
+	for id, conditionalOrder := range e.orders[pair] {
+		if conditionalOrder.Type == "trailingStop" {
+			extremePrice, _ := decimal.Parse(conditionalOrder.ExtremePrice)
+			if extremePrice == nil || (conditionalOrder.IsBid && price.Cmp(extremePrice) < 0) || (!conditionalOrder.IsBid && price.Cmp(extremePrice) > 0) {
+				conditionalOrder.ExtremePrice = lastPrice
+				conditionalOrder.StopPrice = trail(price, conditionalOrder.TrailingDelta, conditionalOrder.IsBid)
+				e.dirty[id] = true
+			}
+		}
+
+		if isTriggered(*conditionalOrder, price) {
+			triggered = append(triggered, *conditionalOrder)
+			e.remove(pair, id)
+		}
+	}
+	e.mu.Unlock()
+
+	for _, conditionalOrder := range triggered {
+		go e.trigger(conditionalOrder)
+	}
+}
+
+// trigger places the child order, the status transition makes sure it happens once across gateways
+func (e *Engine) trigger(conditionalOrder models.ConditionalOrder) {
+	ok, err := e.db.TransitConditionalOrder(conditionalOrder.ID, "untriggered", "triggered")
+	if err != nil || !ok {
+		return
+	}
+
+	conditionalOrder.Status = "triggered"
+	conditionalOrder.TriggeredAt = time.Now()
+
+	req := models.PlaceOrderReq{
+		UserID: conditionalOrder.UserID,
+		IsBid:  conditionalOrder.IsBid,
+		Pair:   conditionalOrder.Pair,
+		Qty:    conditionalOrder.Qty,
+		Type:   "market",
+	}
+	if conditionalOrder.Type == "stopLimit" {
+		req.Type = "limit"
+		req.Price = conditionalOrder.Price
+	}
+
+	order, err := e.router.PlaceOrder(req)
+	if err != nil {
+		e.logger.Error("failed to place triggered order", "conditionalOrderID", conditionalOrder.ID, "error", err)
+
+		conditionalOrder.Status = "rejected"
+		conditionalOrder.Error = "internal error in matching engine"
+		if st, _ := status.FromError(err); st.Message() != "" {
+			conditionalOrder.Error = st.Message()
+		}
+
+		if _, err := e.db.TransitConditionalOrder(conditionalOrder.ID, "triggered", "rejected"); err != nil {
+			e.logger.Error("failed to reject conditional order", "conditionalOrderID", conditionalOrder.ID, "error", err)
+		}
+	} else {
+		conditionalOrder.OrderID = order.ID
+	}
+
+	if err := e.db.UpdateConditionalOrder(conditionalOrder); err != nil {
+		e.logger.Error("failed to update conditional order", "conditionalOrderID", conditionalOrder.ID, "error", err)
+	}
+
+	e.publish(conditionalOrder)
+}
+
+// publish sends the triggered or canceled order to its owner and to every listener
+func (e *Engine) publish(conditionalOrder models.ConditionalOrder) {
+	e.hub.BroadcastOrder(converter.ConditionalOrderToModelsOrder(conditionalOrder))
+
+	e.mu.Lock()
+	listeners := e.listeners
+	e.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener.OnConditionalOrderUpdate(conditionalOrder)
+	}
+}
+
+// flush persists the trailing stops that moved
+func (e *Engine) flush() {
+	var moved []models.ConditionalOrder
+
+	e.mu.Lock()
+	for _, pairOrders := range e.orders {
+		for id, conditionalOrder := range pairOrders {
+			if e.dirty[id] {
+				moved = append(moved, *conditionalOrder)
+			}
+		}
+	}
+	clear(e.dirty)
+	e.mu.Unlock()
+
+	for _, conditionalOrder := range moved {
+		if err := e.db.UpdateConditionalOrder(conditionalOrder); err != nil {
+			e.logger.Error("failed to save trailing stop", "conditionalOrderID", conditionalOrder.ID, "error", err)
+		}
+	}
+}
+
+// add must be called with e.mu held
+func (e *Engine) add(conditionalOrder models.ConditionalOrder) {
+	pairOrders, ok := e.orders[conditionalOrder.Pair]
+	if !ok {
+		pairOrders = make(map[int]*models.ConditionalOrder)
+		e.orders[conditionalOrder.Pair] = pairOrders
+		if e.onWatch != nil {
+			e.onWatch(conditionalOrder.Pair, true)
+		}
+	}
+	pairOrders[conditionalOrder.ID] = &conditionalOrder
+}
+
+// remove must be called with e.mu held
+func (e *Engine) remove(pair string, id int) {
+	pairOrders, ok := e.orders[pair]
+	if !ok {
+		return
+	}
+
+	delete(pairOrders, id)
+	delete(e.dirty, id)
+	if len(pairOrders) == 0 {
+		delete(e.orders, pair)
+		if e.onWatch != nil {
+			e.onWatch(pair, false)
+		}
+	}
+}
+
+// isTriggered reports whether a buy stop is at or below the price or a sell stop at or above it
+func isTriggered(conditionalOrder models.ConditionalOrder, price *big.Rat) bool {
+	stopPrice, ok := decimal.Parse(conditionalOrder.StopPrice)
+	if !ok {
+		return false
+	}
+
+	if conditionalOrder.IsBid {
+		return price.Cmp(stopPrice) >= 0
+	}
+	return price.Cmp(stopPrice) <= 0
+}
+
+// trail is the stop price trailingDelta away from the price, above it for buys and below it for sells
+func trail(price *big.Rat, trailingDelta string, isBid bool) string {
+	delta, _ := decimal.Parse(trailingDelta)
+	if delta == nil {
+		return decimal.Format(price)
+	}
+
+	if isBid {
+		return decimal.Format(new(big.Rat).Add(price, delta))
+	}
+	return decimal.Format(new(big.Rat).Sub(price, delta))
+}
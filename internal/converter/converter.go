@@ -1,94 +1,260 @@
-package converter
-
-import (
-	"time"
-
-	"github.com/BazaarTrade/ApiGatewayService/internal/models"
-	"github.com/BazaarTrade/MatchingEngineProtoGen/pbM"
-	"github.com/BazaarTrade/QuoteProtoGen/pbQ"
-)
-
-func PbMOrderToModelsOrder(order *pbM.Order) models.Order {
-	return models.Order{
-		ID:         int(order.ID),
-		UserID:     int(order.UserID),
-		IsBid:      order.IsBid,
-		Pair:       order.Pair,
-		Price:      order.Price,
-		Qty:        order.Qty,
-		SizeFilled: order.SizeFilled,
-		Status:     order.Status,
-		Type:       order.Type,
-		CreatedAt:  order.CreatedAt.AsTime().Format(time.RFC3339),
-		ClosedAt:   order.ClosedAt.AsTime().Format(time.RFC3339),
-	}
-}
-
-func PbQTickerToModelsTicker(ticker *pbQ.Ticker) models.Ticker {
-	return models.Ticker{
-		Pair:      ticker.Pair,
-		LastPrice: ticker.LastPrice,
-		Change:    ticker.Change,
-		HighPrice: ticker.HighPrice,
-		LowPrice:  ticker.LowPrice,
-		Volume:    ticker.Volume,
-		Turnover:  ticker.Turnover,
-	}
-}
-
-func PbQTradeToModelsTrade(pbQTrades *pbQ.Trades) []models.Trade {
-	var trades = make([]models.Trade, 0, len(pbQTrades.Trades))
-	for _, pbQTrade := range pbQTrades.Trades {
-		trades = append(trades, models.Trade{
-			Pair:  pbQTrade.Pair,
-			IsBid: pbQTrade.IsBid,
-			Price: pbQTrade.Price,
-			Qty:   pbQTrade.Qty,
-			Time:  pbQTrade.Time.AsTime().Format(time.RFC3339),
-		})
-	}
-	return trades
-}
-
-func PbQCandleStickToModelsCandleStick(candleStick *pbQ.CandleStick) models.CandleStick {
-	return models.CandleStick{
-		ID:         int(candleStick.ID),
-		Pair:       candleStick.Pair,
-		Timeframe:  candleStick.Timeframe,
-		OpenTime:   candleStick.OpenTime.AsTime().Format(time.RFC3339),
-		CloseTime:  candleStick.CloseTime.AsTime().Format(time.RFC3339),
-		OpenPrice:  candleStick.OpenPrice,
-		ClosePrice: candleStick.ClosePrice,
-		HighPrice:  candleStick.HighPrice,
-		LowPrice:   candleStick.LowPrice,
-		Volume:     candleStick.Volume,
-		Turnover:   candleStick.Turnover,
-		IsClosed:   candleStick.IsClosed,
-	}
-}
-
-func PbQOBSToModelsOBS(OBS *pbQ.OrderBookSnapshot) models.OrderBookSnapshot {
-	var bids = make([]models.Limit, 0, len(OBS.Bids))
-	for _, bid := range OBS.Bids {
-		bids = append(bids, models.Limit{
-			Price: bid.Price,
-			Qty:   bid.Qty,
-		})
-	}
-
-	var asks = make([]models.Limit, 0, len(OBS.Asks))
-	for _, ask := range OBS.Asks {
-		asks = append(asks, models.Limit{
-			Price: ask.Price,
-			Qty:   ask.Qty,
-		})
-	}
-
-	return models.OrderBookSnapshot{
-		Pair:    OBS.Pair,
-		Bids:    bids,
-		Asks:    asks,
-		BidsQty: OBS.BidsQty,
-		AsksQty: OBS.AsksQty,
-	}
-}
+package converter
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/MatchingEngineProtoGen/pbM"
+	"github.com/BazaarTrade/QuoteProtoGen/pbQ"
+)
+
+func PbMOrderToModelsOrder(order *pbM.Order) models.Order {
+	return models.Order{
+		ID:         int(order.ID),
+		UserID:     int(order.UserID),
+		IsBid:      order.IsBid,
+		Pair:       order.Pair,
+		Price:      order.Price,
+		Qty:        order.Qty,
+		SizeFilled: order.SizeFilled,
+		Status:     order.Status,
+		Type:       order.Type,
+		CreatedAt:  order.CreatedAt.AsTime().Format(time.RFC3339),
+		ClosedAt:   order.ClosedAt.AsTime().Format(time.RFC3339),
+	}
+}
+
+func ModelsPlaceOrderReqToPbMPlaceOrderReq(req models.PlaceOrderReq) *pbM.PlaceOrderReq {
+	return &pbM.PlaceOrderReq{
+		UserID: int64(req.UserID),
+		IsBid:  req.IsBid,
+		Pair:   req.Pair,
+		Price:  req.Price,
+		Qty:    req.Qty,
+		Type:   req.Type,
+	}
+}
+
+// ConditionalOrderToModelsOrder shows the conditional order as an order, its ID is the child order once triggered
+func ConditionalOrderToModelsOrder(conditionalOrder models.ConditionalOrder) models.Order {
+	order := models.Order{
+		ID:                 conditionalOrder.OrderID,
+		UserID:             conditionalOrder.UserID,
+		IsBid:              conditionalOrder.IsBid,
+		Pair:               conditionalOrder.Pair,
+		Price:              conditionalOrder.Price,
+		Qty:                conditionalOrder.Qty,
+		SizeFilled:         "0",
+		Status:             conditionalOrder.Status,
+		Type:               conditionalOrder.Type,
+		CreatedAt:          conditionalOrder.CreatedAt.Format(time.RFC3339),
+		ConditionalOrderID: conditionalOrder.ID,
+		StopPrice:          conditionalOrder.StopPrice,
+		TrailingDelta:      conditionalOrder.TrailingDelta,
+	}
+	if !conditionalOrder.TriggeredAt.IsZero() {
+		order.ClosedAt = conditionalOrder.TriggeredAt.Format(time.RFC3339)
+	}
+	return order
+}
+
+// scheduledOrderStatuses name the job statuses of held orders the way clients see them
+var scheduledOrderStatuses = map[string]string{
+	"pending":  "pending",
+	"done":     "placed",
+	"failed":   "rejected",
+	"canceled": "canceled",
+}
+
+// ScheduledJobToModelsOrder shows an order held until its start time as an order, its ID is the placed order once placed
+func ScheduledJobToModelsOrder(job models.ScheduledJob) models.Order {
+	var req models.PlaceOrderReq
+	// the request was marshalled by the gateway, so it always unmarshals
+	_ = json.Unmarshal(job.Request, &req)
+
+	return models.Order{
+		ID:               job.OrderID,
+		UserID:           job.UserID,
+		IsBid:            req.IsBid,
+		Pair:             req.Pair,
+		Price:            req.Price,
+		Qty:              req.Qty,
+		SizeFilled:       "0",
+		Status:           scheduledOrderStatuses[job.Status],
+		Type:             req.Type,
+		CreatedAt:        job.CreatedAt.Format(time.RFC3339),
+		TimeInForce:      req.TimeInForce,
+		ExpireTime:       req.ExpireTime,
+		StartTime:        req.StartTime,
+		ScheduledOrderID: job.ID,
+	}
+}
+
+// AlgoOrderToModelsOrder shows a parent order worked by the gateway as an order, its child orders have their own IDs
+func AlgoOrderToModelsOrder(algoOrder models.AlgoOrder) models.Order {
+	order := models.Order{
+		UserID:        algoOrder.UserID,
+		IsBid:         algoOrder.IsBid,
+		Pair:          algoOrder.Pair,
+		Price:         algoOrder.Price,
+		Qty:           algoOrder.Qty,
+		SizeFilled:    algoOrder.SizeFilled,
+		Status:        algoOrder.Status,
+		Type:          algoOrder.Type,
+		CreatedAt:     algoOrder.CreatedAt.Format(time.RFC3339),
+		AlgoOrderID:   algoOrder.ID,
+		Duration:      int64(algoOrder.Duration / time.Second),
+		SliceInterval: int64(algoOrder.SliceInterval / time.Second),
+		VisibleQty:    algoOrder.VisibleQty,
+	}
+	if !algoOrder.ClosedAt.IsZero() {
+		order.ClosedAt = algoOrder.ClosedAt.Format(time.RFC3339)
+	}
+	return order
+}
+
+func OrderGroupReqToOrderGroup(req models.OrderGroupReq) models.OrderGroup {
+	return models.OrderGroup{
+		UserID:          req.UserID,
+		Type:            req.Type,
+		Pair:            req.Pair,
+		IsBid:           req.IsBid,
+		Qty:             req.Qty,
+		EntryPrice:      req.EntryPrice,
+		TakeProfitPrice: req.TakeProfitPrice,
+		StopPrice:       req.StopPrice,
+		StopLimitPrice:  req.StopLimitPrice,
+	}
+}
+
+// OrderGroupToLegReqs builds the order requests of the legs, the exit legs are for qty
+// and the stop loss is a conditional order
+func OrderGroupToLegReqs(orderGroup models.OrderGroup, qty string) (entry, takeProfit, stopLoss models.PlaceOrderReq) {
+	entry = models.PlaceOrderReq{
+		UserID: orderGroup.UserID,
+		IsBid:  !orderGroup.IsBid,
+		Pair:   orderGroup.Pair,
+		Qty:    orderGroup.Qty,
+		Type:   "market",
+	}
+	if orderGroup.EntryPrice != "" {
+		entry.Type = "limit"
+		entry.Price = orderGroup.EntryPrice
+	}
+
+	takeProfit = models.PlaceOrderReq{
+		UserID: orderGroup.UserID,
+		IsBid:  orderGroup.IsBid,
+		Pair:   orderGroup.Pair,
+		Price:  orderGroup.TakeProfitPrice,
+		Qty:    qty,
+		Type:   "limit",
+	}
+
+	stopLoss = models.PlaceOrderReq{
+		UserID:    orderGroup.UserID,
+		IsBid:     orderGroup.IsBid,
+		Pair:      orderGroup.Pair,
+		Qty:       qty,
+		Type:      "stopMarket",
+		StopPrice: orderGroup.StopPrice,
+	}
+	if orderGroup.StopLimitPrice != "" {
+		stopLoss.Type = "stopLimit"
+		stopLoss.Price = orderGroup.StopLimitPrice
+	}
+	return entry, takeProfit, stopLoss
+}
+
+// OrderGroupToModelsOrder shows the order group as an order together with its legs
+func OrderGroupToModelsOrder(orderGroup models.OrderGroup, legs []models.Order) models.Order {
+	order := models.Order{
+		UserID:          orderGroup.UserID,
+		IsBid:           orderGroup.IsBid,
+		Pair:            orderGroup.Pair,
+		Price:           orderGroup.EntryPrice,
+		Qty:             orderGroup.Qty,
+		Status:          orderGroup.Status,
+		Type:            orderGroup.Type,
+		CreatedAt:       orderGroup.CreatedAt.Format(time.RFC3339),
+		OrderGroupID:    orderGroup.ID,
+		TakeProfitPrice: orderGroup.TakeProfitPrice,
+		StopPrice:       orderGroup.StopPrice,
+		StopLimitPrice:  orderGroup.StopLimitPrice,
+		Legs:            legs,
+	}
+	if !orderGroup.ClosedAt.IsZero() {
+		order.ClosedAt = orderGroup.ClosedAt.Format(time.RFC3339)
+	}
+	return order
+}
+
+func PbQTickerToModelsTicker(ticker *pbQ.Ticker) models.Ticker {
+	return models.Ticker{
+		Pair:      ticker.Pair,
+		LastPrice: ticker.LastPrice,
+		Change:    ticker.Change,
+		HighPrice: ticker.HighPrice,
+		LowPrice:  ticker.LowPrice,
+		Volume:    ticker.Volume,
+		Turnover:  ticker.Turnover,
+	}
+}
+
+func PbQTradeToModelsTrade(pbQTrades *pbQ.Trades) []models.Trade {
+	var trades = make([]models.Trade, 0, len(pbQTrades.Trades))
+	for _, pbQTrade := range pbQTrades.Trades {
+		trades = append(trades, models.Trade{
+			Pair:  pbQTrade.Pair,
+			IsBid: pbQTrade.IsBid,
+			Price: pbQTrade.Price,
+			Qty:   pbQTrade.Qty,
+			Time:  pbQTrade.Time.AsTime().Format(time.RFC3339),
+		})
+	}
+	return trades
+}
+
+func PbQCandleStickToModelsCandleStick(candleStick *pbQ.CandleStick) models.CandleStick {
+	return models.CandleStick{
+		ID:         int(candleStick.ID),
+		Pair:       candleStick.Pair,
+		Timeframe:  candleStick.Timeframe,
+		OpenTime:   candleStick.OpenTime.AsTime().Format(time.RFC3339),
+		CloseTime:  candleStick.CloseTime.AsTime().Format(time.RFC3339),
+		OpenPrice:  candleStick.OpenPrice,
+		ClosePrice: candleStick.ClosePrice,
+		HighPrice:  candleStick.HighPrice,
+		LowPrice:   candleStick.LowPrice,
+		Volume:     candleStick.Volume,
+		Turnover:   candleStick.Turnover,
+		IsClosed:   candleStick.IsClosed,
+	}
+}
+
+func PbQOBSToModelsOBS(OBS *pbQ.OrderBookSnapshot) models.OrderBookSnapshot {
+	var bids = make([]models.Limit, 0, len(OBS.Bids))
+	for _, bid := range OBS.Bids {
+		bids = append(bids, models.Limit{
+			Price: bid.Price,
+			Qty:   bid.Qty,
+		})
+	}
+
+	var asks = make([]models.Limit, 0, len(OBS.Asks))
+	for _, ask := range OBS.Asks {
+		asks = append(asks, models.Limit{
+			Price: ask.Price,
+			Qty:   ask.Qty,
+		})
+	}
+
+	return models.OrderBookSnapshot{
+		Pair:    OBS.Pair,
+		Bids:    bids,
+		Asks:    asks,
+		BidsQty: OBS.BidsQty,
+		AsksQty: OBS.AsksQty,
+	}
+}
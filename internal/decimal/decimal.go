@@ -0,0 +1,63 @@
+package decimal
+
+import (
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// prices and quantities travel as plain decimal strings such as "0.015", exponents and fractions are rejected
+var decimalRegexp = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+func Parse(value string) (*big.Rat, bool) {
+	if !decimalRegexp.MatchString(value) {
+		return nil, false
+	}
+	return new(big.Rat).SetString(value)
+}
+
+func Format(r *big.Rat) string {
+	s := r.FloatString(18)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(s, "0")
+	}
+	return strings.TrimSuffix(s, ".")
+}
+
+// Step is the smallest increment with the given number of decimals, e.g. 0.01 for 2,
+// a negative precision gives tens, hundreds and so on
+func Step(precision int32) *big.Rat {
+	exponent := int64(precision)
+	if exponent < 0 {
+		exponent = -exponent
+	}
+
+	power := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(exponent), nil))
+	if precision < 0 {
+		return power
+	}
+	return power.Inv(power)
+}
+
+// IsMultiple reports whether r is a whole multiple of step
+func IsMultiple(r, step *big.Rat) bool {
+	return new(big.Rat).Quo(r, step).IsInt()
+}
+
+// FormatPrecision formats r with exactly precision decimals, none for a negative precision
+func FormatPrecision(r *big.Rat, precision int32) string {
+	return r.FloatString(int(max(precision, 0)))
+}
+
+// Round rounds r to a multiple of Step(precision), down or up
+func Round(r *big.Rat, precision int32, up bool) *big.Rat {
+	factor := new(big.Rat).Inv(Step(precision))
+
+	scaled := new(big.Rat).Mul(r, factor)
+	quotient, remainder := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+	if up && remainder.Sign() > 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+
+	return new(big.Rat).Quo(new(big.Rat).SetInt(quotient), factor)
+}
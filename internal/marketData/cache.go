@@ -0,0 +1,237 @@
+package marketData
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+)
+
+var recentTradesLimit = 100
+
+type Cache struct {
+	mu    sync.RWMutex
+	pairs map[string]*pairData
+}
+
+type pairData struct {
+	ticker       Ticker
+	orderBooks   map[int32]OrderBookSnapshot
+	trades       []models.Trade
+	tradesAt     string
+	candleSticks map[string]CandleStick
+	stale        map[string]bool
+}
+
+type Ticker struct {
+	models.Ticker
+	Stale     bool   `json:"stale"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+type Trades struct {
+	Pair      string         `json:"pair"`
+	Trades    []models.Trade `json:"trades"`
+	Stale     bool           `json:"stale"`
+	UpdatedAt string         `json:"updatedAt"`
+}
+
+type CandleStick struct {
+	models.CandleStick
+	Stale     bool   `json:"stale"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+type OrderBookSnapshot struct {
+	models.OrderBookSnapshot
+	Precision int32  `json:"precision"`
+	Stale     bool   `json:"stale"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+func New() *Cache {
+	return &Cache{
+		pairs: make(map[string]*pairData),
+	}
+}
+
+// pair must be called with c.mu held for writing
+func (c *Cache) pair(pair string) *pairData {
+	data, ok := c.pairs[pair]
+	if !ok {
+		data = &pairData{
+			orderBooks:   make(map[int32]OrderBookSnapshot),
+			candleSticks: make(map[string]CandleStick),
+			stale:        make(map[string]bool),
+		}
+		c.pairs[pair] = data
+	}
+	return data
+}
+
+func (c *Cache) SetTicker(ticker models.Ticker) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.pair(ticker.Pair)
+	data.ticker = Ticker{
+		Ticker:    ticker,
+		UpdatedAt: time.Now().Format(time.RFC3339Nano),
+	}
+	data.stale["ticker"] = false
+}
+
+func (c *Cache) SetOrderBookSnapshot(pOBS models.OrderBookSnapshot, precision int32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.pair(pOBS.Pair)
+	data.orderBooks[precision] = OrderBookSnapshot{
+		OrderBookSnapshot: pOBS,
+		Precision:         precision,
+		UpdatedAt:         time.Now().Format(time.RFC3339Nano),
+	}
+	data.stale["orderBook"] = false
+}
+
+// AddTrades keeps the last recentTradesLimit trades of the pair
+func (c *Cache) AddTrades(trades []models.Trade) {
+	if len(trades) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.pair(trades[0].Pair)
+	data.trades = append(data.trades, trades...)
+	if len(data.trades) > recentTradesLimit {
+		data.trades = append([]models.Trade(nil), data.trades[len(data.trades)-recentTradesLimit:]...)
+	}
+	data.tradesAt = time.Now().Format(time.RFC3339Nano)
+	data.stale["trades"] = false
+}
+
+func (c *Cache) SetCandleStick(candleStick models.CandleStick) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.pair(candleStick.Pair)
+	data.candleSticks[candleStick.Timeframe] = CandleStick{
+		CandleStick: candleStick,
+		UpdatedAt:   time.Now().Format(time.RFC3339Nano),
+	}
+	data.stale["candleStick"] = false
+}
+
+// SetStale marks everything cached for the pair and stream as stale or fresh.
+// Stream names are the websocket topic names.
+func (c *Cache) SetStale(pair, stream string, stale bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pair(pair).stale[stream] = stale
+}
+
+func (c *Cache) Ticker(pair string) (Ticker, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	data, ok := c.pairs[pair]
+	if !ok || data.ticker.Pair == "" {
+		return Ticker{}, false
+	}
+
+	ticker := data.ticker
+	ticker.Stale = data.stale["ticker"]
+	return ticker, true
+}
+
+func (c *Cache) Tickers() []Ticker {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var tickers = make([]Ticker, 0, len(c.pairs))
+	for _, data := range c.pairs {
+		if data.ticker.Pair == "" {
+			continue
+		}
+
+		ticker := data.ticker
+		ticker.Stale = data.stale["ticker"]
+		tickers = append(tickers, ticker)
+	}
+
+	slices.SortFunc(tickers, func(a, b Ticker) int {
+		return strings.Compare(a.Pair, b.Pair)
+	})
+	return tickers
+}
+
+// RecentTrades returns trades from the newest to the oldest
+func (c *Cache) RecentTrades(pair string) (Trades, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	data, ok := c.pairs[pair]
+	if !ok || data.tradesAt == "" {
+		return Trades{}, false
+	}
+
+	var trades = make([]models.Trade, len(data.trades))
+	for i, trade := range data.trades {
+		trades[len(trades)-1-i] = trade
+	}
+
+	return Trades{
+		Pair:      pair,
+		Trades:    trades,
+		Stale:     data.stale["trades"],
+		UpdatedAt: data.tradesAt,
+	}, true
+}
+
+func (c *Cache) CurrentCandleStick(pair, timeframe string) (CandleStick, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	data, ok := c.pairs[pair]
+	if !ok {
+		return CandleStick{}, false
+	}
+
+	candleStick, ok := data.candleSticks[timeframe]
+	if !ok {
+		return CandleStick{}, false
+	}
+
+	candleStick.Stale = data.stale["candleStick"]
+	return candleStick, true
+}
+
+func (c *Cache) OrderBookSnapshot(pair string, precision int32) (OrderBookSnapshot, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	data, ok := c.pairs[pair]
+	if !ok {
+		return OrderBookSnapshot{}, false
+	}
+
+	pOBS, ok := data.orderBooks[precision]
+	if !ok {
+		return OrderBookSnapshot{}, false
+	}
+
+	pOBS.Stale = data.stale["orderBook"]
+	return pOBS, true
+}
+
+func (c *Cache) RemovePair(pair string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.pairs, pair)
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AdminTokenMiddleware lets through requests bearing ADMIN_TOKEN, admin routes are disabled without one
+func AdminTokenMiddleware(next echo.HandlerFunc, ADMIN_TOKEN string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if ADMIN_TOKEN == "" {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "admin routes are disabled",
+			})
+		}
+
+		token, ok := strings.CutPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(ADMIN_TOKEN)) != 1 {
+			return c.JSON(http.StatusUnauthorized, map[string]string{
+				"error": "invalid admin token",
+			})
+		}
+		return next(c)
+	}
+}
@@ -1,108 +1,369 @@
-package models
-
-import "encoding/json"
-
-type Order struct {
-	ID         int    `json:"orderID"`
-	UserID     int    `json:"userID"`
-	IsBid      bool   `json:"isBid"`
-	Pair       string `json:"pair"`
-	Price      string `json:"price"`
-	Qty        string `json:"qty"`
-	SizeFilled string `json:"sizeFilled"`
-	Status     string `json:"status"`
-	Type       string `json:"type"`
-	CreatedAt  string `json:"createdAt"`
-	ClosedAt   string `json:"closedAt"`
-}
-
-type PlaceOrderReq struct {
-	UserID int    `json:"userID"`
-	IsBid  bool   `json:"isBid"`
-	Pair   string `json:"pair"`
-	Price  string `json:"price"`
-	Qty    string `json:"qty"`
-	Type   string `json:"type"` // Market или Limit
-}
-
-type SubscriptionRequest struct {
-	Action string          `json:"action"`
-	Topic  string          `json:"topic"`
-	Params json.RawMessage `json:"params"`
-}
-
-type OrderBookSnapshot struct {
-	Pair    string  `json:"pair"`
-	Bids    []Limit `json:"bids"`
-	Asks    []Limit `json:"asks"`
-	BidsQty string  `json:"bidsQty"`
-	AsksQty string  `json:"asksQty"`
-}
-
-type Limit struct {
-	Price string `json:"price"`
-	Qty   string `json:"qty"`
-}
-
-type Trade struct {
-	Pair  string `json:"pair"`
-	IsBid bool   `json:"isBid"`
-	Price string `json:"price"`
-	Qty   string `json:"qty"`
-	Time  string `json:"time"`
-}
-
-type PairParams struct {
-	Pair                     string   `json:"pair"`
-	OrderBookPricePrecisions []int32  `json:"orderBookPricePrecisions"`
-	QtyPrecision             int32    `json:"qtyPrecision"`
-	CandleStickTimeframes    []string `json:"candleStickTimeframes"`
-}
-
-type Ticker struct {
-	Pair      string `json:"pair"`
-	LastPrice string `json:"lastPrice"`
-	Change    string `json:"change"`
-	HighPrice string `json:"highPrice"`
-	LowPrice  string `json:"lowPrice"`
-	Volume    string `json:"volume"`
-	Turnover  string `json:"turnover"`
-}
-
-type CandleStick struct {
-	ID         int    `json:"ID"`
-	Pair       string `json:"pair"`
-	Timeframe  string `json:"timeframe"`
-	OpenTime   string `json:"openTime"`
-	CloseTime  string `json:"closeTime"`
-	OpenPrice  string `json:"openPrice"`
-	ClosePrice string `json:"closePrice"`
-	HighPrice  string `json:"highPrice"`
-	LowPrice   string `json:"lowPrice"`
-	Volume     string `json:"volume"`
-	Turnover   string `json:"turnover"`
-	IsClosed   bool   `json:"isClosed"`
-}
-
-type CandleStickHistoryRequest struct {
-	Pair      string `json:"pair"`
-	Timeframe string `json:"timeframe"`
-	CandleID  int    `json:"candleID"`
-	Limit     int    `json:"limit"`
-}
-
-type RegisterRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
-}
-
-type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
-}
-
-type ChangePasswordRequest struct {
-	Email       string `json:"email"`
-	OldPassword string `json:"oldPassword"`
-	NewPassword string `json:"newPassword"`
-}
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Order struct {
+	ID         int    `json:"orderID"`
+	UserID     int    `json:"userID"`
+	IsBid      bool   `json:"isBid"`
+	Pair       string `json:"pair"`
+	Price      string `json:"price"`
+	Qty        string `json:"qty"`
+	SizeFilled string `json:"sizeFilled"`
+	Status     string `json:"status"`
+	Type       string `json:"type"`
+	CreatedAt  string `json:"createdAt"`
+	ClosedAt   string `json:"closedAt"`
+
+	ClientOrderID string `json:"clientOrderId,omitempty"`
+
+	// set for conditional orders managed by the gateway
+	ConditionalOrderID int    `json:"conditionalOrderID,omitempty"`
+	StopPrice          string `json:"stopPrice,omitempty"`
+	TrailingDelta      string `json:"trailingDelta,omitempty"`
+
+	TimeInForce string `json:"timeInForce,omitempty"`
+	ExpireTime  string `json:"expireTime,omitempty"`
+	StartTime   string `json:"startTime,omitempty"`
+	PostOnly    bool   `json:"postOnly,omitempty"`
+	// set for orders held by the gateway until their start time
+	ScheduledOrderID int `json:"scheduledOrderID,omitempty"`
+
+	// set for algo orders worked by the gateway, durations are in seconds
+	AlgoOrderID   int    `json:"algoOrderID,omitempty"`
+	Duration      int64  `json:"duration,omitempty"`
+	SliceInterval int64  `json:"sliceInterval,omitempty"`
+	VisibleQty    string `json:"visibleQty,omitempty"`
+
+	// set for oco and bracket order groups and their legs
+	OrderGroupID    int     `json:"orderGroupID,omitempty"`
+	OrderGroupRole  string  `json:"orderGroupRole,omitempty"`
+	TakeProfitPrice string  `json:"takeProfitPrice,omitempty"`
+	StopLimitPrice  string  `json:"stopLimitPrice,omitempty"`
+	Legs            []Order `json:"legs,omitempty"`
+}
+
+type PlaceOrderReq struct {
+	UserID int    `json:"userID"`
+	IsBid  bool   `json:"isBid"`
+	Pair   string `json:"pair"`
+	Price  string `json:"price"`
+	Qty    string `json:"qty"`
+	Type   string `json:"type"` // Market или Limit
+
+	ClientOrderID string `json:"clientOrderId"`
+
+	// conditional orders only
+	StopPrice     string `json:"stopPrice"`
+	TrailingDelta string `json:"trailingDelta"`
+
+	// GTC (default), IOC, FOK, GTD until ExpireTime or GAT held until StartTime, times are RFC3339
+	TimeInForce string `json:"timeInForce"`
+	ExpireTime  string `json:"expireTime"`
+	StartTime   string `json:"startTime"`
+
+	// PostOnly rejects a limit order that would match on placement instead of resting in the book
+	PostOnly bool `json:"postOnly"`
+}
+
+// OrderFilter selects orders of a user, empty fields match every order. Orders are sorted
+// from the newest by (createdAt, id) and After continues right after the last order of a page.
+type OrderFilter struct {
+	UserID int
+	Pair   string
+	Side   string // buy or sell
+	Type   string
+	Status string
+	From   time.Time
+	To     time.Time
+	After  *OrderCursor
+	Limit  int
+}
+
+// OrderCursor is the position of an order in the order history
+type OrderCursor struct {
+	CreatedAt string `json:"createdAt"`
+	ID        int    `json:"id"`
+}
+
+// Execution is one fill of an order of the user, IsBid is the side of that order
+type Execution struct {
+	TradeID int    `json:"tradeID"`
+	OrderID int    `json:"orderID"`
+	Pair    string `json:"pair"`
+	IsBid   bool   `json:"isBid"`
+	Price   string `json:"price"`
+	Qty     string `json:"qty"`
+	Role    string `json:"role"` // maker or taker
+	Time    string `json:"time"`
+}
+
+// ExecutionFilter selects executions of a user from the newest by (time, tradeID, role),
+// After continues right after the last execution of a page
+type ExecutionFilter struct {
+	UserID int
+	Pair   string
+	From   time.Time
+	To     time.Time
+	After  *ExecutionCursor
+	Limit  int
+}
+
+// ExecutionCursor is the position of an execution in the trade history, a self trade has
+// a maker and a taker execution with the same trade ID
+type ExecutionCursor struct {
+	Time    string `json:"time"`
+	TradeID int    `json:"tradeID"`
+	Role    string `json:"role"`
+}
+
+// ConditionalOrder is a stop or trailing stop order held by the gateway until its stop price
+// is reached, then the child order is placed in the matching engine.
+type ConditionalOrder struct {
+	ID     int
+	UserID int
+	IsBid  bool
+	Pair   string
+	Type   string // stopLimit, stopMarket or trailingStop
+	Price  string // limit price of the stopLimit child order
+	Qty    string
+
+	StopPrice     string
+	TrailingDelta string
+	// ExtremePrice is the best price seen since a trailing stop was placed, the stop price trails it
+	ExtremePrice string
+
+	Status      string // untriggered, triggered, rejected or canceled
+	OrderID     int    // the child order once triggered
+	Error       string // why the child order was rejected
+	CreatedAt   time.Time
+	TriggeredAt time.Time
+}
+
+// AlgoOrderReq is a parent order for the gateway to work, durations are in seconds
+type AlgoOrderReq struct {
+	UserID int    `json:"userID"`
+	IsBid  bool   `json:"isBid"`
+	Pair   string `json:"pair"`
+	Type   string `json:"type"` // twap or iceberg
+	// Price is the limit price of the child orders, twap children are market orders without it
+	Price string `json:"price"`
+	Qty   string `json:"qty"`
+
+	// twap only
+	Duration      int64 `json:"duration"`
+	SliceInterval int64 `json:"sliceInterval"`
+
+	// iceberg only, the qty of the slice resting in the book
+	VisibleQty string `json:"visibleQty"`
+}
+
+// AlgoOrder is a parent order the gateway works by placing child orders in the matching engine.
+// A twap order places a slice of Qty every SliceInterval until Duration is over, an iceberg
+// order keeps a slice of VisibleQty resting until Qty is filled. A claimed order is locked
+// until LockedUntil, so only one gateway works it at a time.
+type AlgoOrder struct {
+	ID     int
+	UserID int
+	IsBid  bool
+	Pair   string
+	Type   string // twap or iceberg
+	Price  string
+	Qty    string
+
+	Duration      time.Duration
+	SliceInterval time.Duration
+	VisibleQty    string
+	SlicesPlaced  int
+	// SizeFilled is the filled qty of all child orders
+	SizeFilled string
+
+	Status string // running, paused, completed, canceled or failed
+	Error  string
+	// NextSliceAt is when a twap slice is due or an iceberg is next checked for a missing slice
+	NextSliceAt time.Time
+	// EndAt is when the last twap slice is over, pauses move it along with NextSliceAt
+	EndAt       time.Time
+	PausedAt    time.Time
+	LockedUntil time.Time
+	CreatedAt   time.Time
+	ClosedAt    time.Time
+}
+
+// OrderGroupReq places an oco or a bracket order group. An oco group is a takeProfit limit leg and
+// a stopLoss stop leg on the same side. A bracket group places an entry order on the other side first,
+// its exit legs are then placed as an oco for the filled qty.
+type OrderGroupReq struct {
+	UserID int    `json:"userID"`
+	Type   string `json:"type"` // oco or bracket
+	Pair   string `json:"pair"`
+	// IsBid is the side of the exit legs
+	IsBid bool   `json:"isBid"`
+	Qty   string `json:"qty"`
+
+	// bracket only, the entry is a market order without it
+	EntryPrice      string `json:"entryPrice"`
+	TakeProfitPrice string `json:"takeProfitPrice"`
+	StopPrice       string `json:"stopPrice"`
+	// StopLimitPrice makes the stop loss a stopLimit order, it is a stopMarket order without it
+	StopLimitPrice string `json:"stopLimitPrice"`
+}
+
+// OrderGroup is an oco or bracket order group. Once a take profit leg fills or a stop loss leg
+// triggers, the gateway cancels the other exit leg.
+type OrderGroup struct {
+	ID     int
+	UserID int
+	Type   string // oco or bracket
+	Pair   string
+	IsBid  bool
+	Qty    string
+
+	EntryPrice      string
+	TakeProfitPrice string
+	StopPrice       string
+	StopLimitPrice  string
+
+	// pending until the entry of a bracket is filled, active while the exit legs rest
+	Status    string // pending, active, completed, canceled or failed
+	Error     string
+	CreatedAt time.Time
+	ClosedAt  time.Time
+}
+
+// OrderGroupLeg is an order of an order group, a stop loss leg is a conditional order
+type OrderGroupLeg struct {
+	OrderGroupID       int
+	Role               string // entry, takeProfit or stopLoss
+	OrderID            int
+	ConditionalOrderID int
+}
+
+// RiskLimits are the pre-trade risk limits of a pair, empty limits are not checked
+type RiskLimits struct {
+	// PriceBand is the largest distance of a limit price from the last price as a fraction of it, "0.1" allows 10%
+	PriceBand   string `json:"priceBand"`
+	MaxQty      string `json:"maxQty"`
+	MaxNotional string `json:"maxNotional"`
+	// MaxUserExposure caps the notional of the open orders of a user in the pair together with the new order
+	MaxUserExposure string `json:"maxUserExposure"`
+}
+
+// ScheduledJob is order work the gateway has to do at RunAt: place a held order from Request
+// or cancel the order OrderID at its expiry. A claimed job is locked until LockedUntil,
+// so it is retried by any gateway if the claiming one stops before finishing it.
+type ScheduledJob struct {
+	ID          int
+	Kind        string // place, cancel or cancelAll
+	UserID      int
+	OrderID     int
+	Request     json.RawMessage
+	RunAt       time.Time
+	LockedUntil time.Time
+	Status      string // pending, done, failed or canceled
+	Error       string
+	CreatedAt   time.Time
+}
+
+// ClientOrder maps the client order ID and idempotency key of a placement request to the placed order.
+// OrderID is 0 and Response is empty while the request is in progress.
+type ClientOrder struct {
+	ID             int
+	UserID         int
+	ClientOrderID  string
+	IdempotencyKey string
+	OrderID        int
+	Response       json.RawMessage
+	CreatedAt      time.Time
+}
+
+type SubscriptionRequest struct {
+	Action string          `json:"action"`
+	Topic  string          `json:"topic"`
+	Params json.RawMessage `json:"params"`
+}
+
+type OrderBookSnapshot struct {
+	Pair    string  `json:"pair"`
+	Bids    []Limit `json:"bids"`
+	Asks    []Limit `json:"asks"`
+	BidsQty string  `json:"bidsQty"`
+	AsksQty string  `json:"asksQty"`
+}
+
+type Limit struct {
+	Price string `json:"price"`
+	Qty   string `json:"qty"`
+}
+
+type Trade struct {
+	Pair  string `json:"pair"`
+	IsBid bool   `json:"isBid"`
+	Price string `json:"price"`
+	Qty   string `json:"qty"`
+	Time  string `json:"time"`
+}
+
+type PairParams struct {
+	Pair                     string   `json:"pair"`
+	OrderBookPricePrecisions []int32  `json:"orderBookPricePrecisions"`
+	QtyPrecision             int32    `json:"qtyPrecision"`
+	CandleStickTimeframes    []string `json:"candleStickTimeframes"`
+
+	// order limits are owned by the gateway, empty means no limit
+	MinQty      string `json:"minQty,omitempty"`
+	MaxQty      string `json:"maxQty,omitempty"`
+	MinNotional string `json:"minNotional,omitempty"`
+	MaxNotional string `json:"maxNotional,omitempty"`
+}
+
+type Ticker struct {
+	Pair      string `json:"pair"`
+	LastPrice string `json:"lastPrice"`
+	Change    string `json:"change"`
+	HighPrice string `json:"highPrice"`
+	LowPrice  string `json:"lowPrice"`
+	Volume    string `json:"volume"`
+	Turnover  string `json:"turnover"`
+}
+
+type CandleStick struct {
+	ID         int    `json:"ID"`
+	Pair       string `json:"pair"`
+	Timeframe  string `json:"timeframe"`
+	OpenTime   string `json:"openTime"`
+	CloseTime  string `json:"closeTime"`
+	OpenPrice  string `json:"openPrice"`
+	ClosePrice string `json:"closePrice"`
+	HighPrice  string `json:"highPrice"`
+	LowPrice   string `json:"lowPrice"`
+	Volume     string `json:"volume"`
+	Turnover   string `json:"turnover"`
+	IsClosed   bool   `json:"isClosed"`
+}
+
+type CandleStickHistoryRequest struct {
+	Pair      string `json:"pair"`
+	Timeframe string `json:"timeframe"`
+	CandleID  int    `json:"candleID"`
+	Limit     int    `json:"limit"`
+}
+
+type RegisterRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type ChangePasswordRequest struct {
+	Email       string `json:"email"`
+	OldPassword string `json:"oldPassword"`
+	NewPassword string `json:"newPassword"`
+}
@@ -0,0 +1,487 @@
+package orderGroup
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/big"
+	"sync"
+	"time"
+
+	ws "github.com/BazaarTrade/ApiGatewayService/internal/api/websocket"
+	"github.com/BazaarTrade/ApiGatewayService/internal/conditionalOrder"
+	"github.com/BazaarTrade/ApiGatewayService/internal/converter"
+	"github.com/BazaarTrade/ApiGatewayService/internal/decimal"
+	"github.com/BazaarTrade/ApiGatewayService/internal/marketData"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/orderRouter"
+	"github.com/BazaarTrade/ApiGatewayService/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// syncInterval is how often open groups are checked for leg updates that did not reach the gateway
+var syncInterval = 5 * time.Second
+
+var (
+	ErrClosed         = errors.New("order group is already closed")
+	ErrLegNotCanceled = errors.New("a leg of the order group could not be canceled")
+)
+
+// Engine links the legs of oco and bracket order groups. Once the take profit leg of an active group
+// fills, even partly, the stop loss leg is canceled, and once the stop loss triggers, the take profit
+// leg is canceled. A leg canceled by its owner cancels the whole group. The entry of a bracket group
+// is placed right away, its exit legs once it is filled or canceled after a partial fill.
+// Groups are stored in the repository, so they survive restarts and are shared between gateways.
+type Engine struct {
+	db                     repository.Repository
+	router                 *orderRouter.Router
+	conditionalOrderEngine *conditionalOrder.Engine
+	hub                    *ws.Hub
+	cache                  *marketData.Cache
+
+	// orders and conditionalOrders map the legs of open groups to their group
+	orders            map[int]int
+	conditionalOrders map[int]int
+	mu                sync.Mutex
+	// evaluateMu keeps a fill and a trigger reported at once from both closing the group
+	evaluateMu sync.Mutex
+	logger     *slog.Logger
+}
+
+func New(db repository.Repository, router *orderRouter.Router, conditionalOrderEngine *conditionalOrder.Engine, hub *ws.Hub, cache *marketData.Cache, logger *slog.Logger) *Engine {
+	return &Engine{
+		db:                     db,
+		router:                 router,
+		conditionalOrderEngine: conditionalOrderEngine,
+		hub:                    hub,
+		cache:                  cache,
+		orders:                 make(map[int]int),
+		conditionalOrders:      make(map[int]int),
+		logger:                 logger,
+	}
+}
+
+// Load maps the legs of the open groups, so that their updates are handled even if they
+// were placed before a restart or by another gateway
+func (e *Engine) Load() error {
+	orderGroups, err := e.db.GetOpenOrderGroups()
+	if err != nil {
+		return err
+	}
+
+	var (
+		orders            = make(map[int]int)
+		conditionalOrders = make(map[int]int)
+	)
+	for _, orderGroup := range orderGroups {
+		legs, err := e.db.GetOrderGroupLegs(orderGroup.ID)
+		if err != nil {
+			return err
+		}
+		for _, leg := range legs {
+			if leg.ConditionalOrderID != 0 {
+				conditionalOrders[leg.ConditionalOrderID] = orderGroup.ID
+			} else {
+				orders[leg.OrderID] = orderGroup.ID
+			}
+		}
+	}
+
+	e.mu.Lock()
+	e.orders = orders
+	e.conditionalOrders = conditionalOrders
+	e.mu.Unlock()
+	return nil
+}
+
+func (e *Engine) Run(ctx context.Context) {
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.sync()
+		}
+	}
+}
+
+// sync evaluates every open group and maps the legs placed by other gateways
+func (e *Engine) sync() {
+	orderGroups, err := e.db.GetOpenOrderGroups()
+	if err != nil {
+		e.logger.Error("failed to get open order groups", "error", err)
+		return
+	}
+
+	for _, orderGroup := range orderGroups {
+		e.evaluate(orderGroup.ID, nil)
+	}
+
+	if err := e.Load(); err != nil {
+		e.logger.Error("failed to load order groups", "error", err)
+	}
+}
+
+// Create stores a validated order group request and places its first legs. A group whose legs
+// are rejected is stored as failed and the error is returned.
+func (e *Engine) Create(req models.OrderGroupReq) (models.Order, error) {
+	orderGroup := converter.OrderGroupReqToOrderGroup(req)
+	orderGroup.Status = "active"
+	if orderGroup.Type == "bracket" {
+		orderGroup.Status = "pending"
+	}
+
+	orderGroup, err := e.db.CreateOrderGroup(orderGroup)
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	e.evaluateMu.Lock()
+	defer e.evaluateMu.Unlock()
+
+	var placed models.Order
+	if orderGroup.Type == "bracket" {
+		entry, _, _ := converter.OrderGroupToLegReqs(orderGroup, orderGroup.Qty)
+		placed, err = e.router.PlaceOrder(entry)
+		if err == nil {
+			e.addLeg(models.OrderGroupLeg{OrderGroupID: orderGroup.ID, Role: "entry", OrderID: placed.ID})
+		}
+	} else {
+		placed, err = e.placeExits(orderGroup, orderGroup.Qty)
+	}
+
+	if err != nil {
+		e.transit(&orderGroup, orderGroup.Status, "failed", failure(err))
+		e.broadcast(orderGroup, nil)
+		return models.Order{}, err
+	}
+
+	e.evaluateLocked(orderGroup.ID, &placed)
+	return e.Order(orderGroup.ID)
+}
+
+// Cancel closes a pending or active group and cancels its resting legs, the caller is expected to check
+// that the group belongs to the user
+func (e *Engine) Cancel(id int) (models.Order, error) {
+	e.evaluateMu.Lock()
+	defer e.evaluateMu.Unlock()
+
+	orderGroup, err := e.db.GetOrderGroup(id)
+	if err != nil {
+		return models.Order{}, err
+	}
+	if orderGroup.Status != "pending" && orderGroup.Status != "active" {
+		return models.Order{}, ErrClosed
+	}
+
+	legs, err := e.legOrders(id, nil)
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	if !e.cancelLegs(orderGroup, legs, "") {
+		return models.Order{}, ErrLegNotCanceled
+	}
+	if !e.transit(&orderGroup, orderGroup.Status, "canceled", "") {
+		return models.Order{}, ErrClosed
+	}
+
+	e.broadcast(orderGroup, nil)
+	return e.Order(id)
+}
+
+// IsLeg reports whether the order is a resting leg of an open group
+func (e *Engine) IsLeg(orderID int) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, ok := e.orders[orderID]
+	return ok
+}
+
+// Order shows the group as an order together with the current state of its legs
+func (e *Engine) Order(id int) (models.Order, error) {
+	orderGroup, err := e.db.GetOrderGroup(id)
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	legs, err := e.legOrders(id, nil)
+	if err != nil {
+		return models.Order{}, err
+	}
+	return converter.OrderGroupToModelsOrder(orderGroup, legs), nil
+}
+
+// OnOrderUpdate evaluates the group of an entry or take profit leg
+func (e *Engine) OnOrderUpdate(order models.Order) {
+	e.mu.Lock()
+	orderGroupID, ok := e.orders[order.ID]
+	if ok && order.Status != "filling" {
+		delete(e.orders, order.ID)
+	}
+	e.mu.Unlock()
+
+	if ok {
+		go e.evaluate(orderGroupID, &order)
+	}
+}
+
+// OnConditionalOrderUpdate evaluates the group of a stop loss leg that was triggered or canceled
+func (e *Engine) OnConditionalOrderUpdate(conditionalOrder models.ConditionalOrder) {
+	e.mu.Lock()
+	orderGroupID, ok := e.conditionalOrders[conditionalOrder.ID]
+	if ok && conditionalOrder.Status != "untriggered" {
+		delete(e.conditionalOrders, conditionalOrder.ID)
+	}
+	e.mu.Unlock()
+
+	if ok {
+		go e.evaluate(orderGroupID, nil)
+	}
+}
+
+func (e *Engine) evaluate(orderGroupID int, update *models.Order) {
+	e.evaluateMu.Lock()
+	defer e.evaluateMu.Unlock()
+
+	e.evaluateLocked(orderGroupID, update)
+}
+
+// evaluateLocked acts on the current state of the legs of an open group, update is the latest state
+// of a leg order and may be nil. It must be called with e.evaluateMu held.
+func (e *Engine) evaluateLocked(orderGroupID int, update *models.Order) {
+	orderGroup, err := e.db.GetOrderGroup(orderGroupID)
+	if err != nil {
+		e.logger.Error("failed to get order group", "orderGroupID", orderGroupID, "error", err)
+		return
+	}
+	if orderGroup.Status != "pending" && orderGroup.Status != "active" {
+		return
+	}
+
+	legs, err := e.legOrders(orderGroupID, update)
+	if err != nil {
+		e.logger.Error("failed to get legs of order group", "orderGroupID", orderGroupID, "error", err)
+		return
+	}
+
+	var entry, takeProfit, stopLoss *models.Order
+	for i := range legs {
+		switch legs[i].OrderGroupRole {
+		case "entry":
+			entry = &legs[i]
+		case "takeProfit":
+			takeProfit = &legs[i]
+		case "stopLoss":
+			stopLoss = &legs[i]
+		}
+	}
+
+	var changed bool
+	switch orderGroup.Status {
+	case "pending":
+		if entry == nil {
+			return
+		}
+
+		filled := sizeFilled(*entry)
+		switch {
+		case entry.Status == "filled" || (entry.Status == "canceled" && filled.Sign() > 0):
+			if !e.transit(&orderGroup, "pending", "active", "") {
+				return
+			}
+
+			placed, err := e.placeExits(orderGroup, decimal.Format(filled))
+			if err != nil {
+				e.logger.Error("failed to place exit legs of order group", "orderGroupID", orderGroupID, "error", err)
+				e.transit(&orderGroup, "active", "failed", failure(err))
+				e.broadcast(orderGroup, nil)
+				return
+			}
+
+			e.broadcast(orderGroup, nil)
+			// the take profit may have filled on placement
+			e.evaluateLocked(orderGroupID, &placed)
+			return
+
+		case entry.Status == "canceled":
+			changed = e.transit(&orderGroup, "pending", "canceled", "")
+		}
+
+	case "active":
+		switch {
+		case takeProfit != nil && sizeFilled(*takeProfit).Sign() > 0:
+			changed = e.cancelLegs(orderGroup, legs, "takeProfit") && e.transit(&orderGroup, "active", "completed", "")
+		case stopLoss != nil && (stopLoss.Status == "triggered" || stopLoss.Status == "rejected"):
+			changed = e.cancelLegs(orderGroup, legs, "stopLoss") && e.transit(&orderGroup, "active", "completed", "")
+		case (takeProfit != nil && takeProfit.Status == "canceled") || (stopLoss != nil && stopLoss.Status == "canceled"):
+			// a leg canceled by its owner
+			changed = e.cancelLegs(orderGroup, legs, "") && e.transit(&orderGroup, "active", "canceled", "")
+		}
+	}
+
+	if changed {
+		e.broadcast(orderGroup, update)
+	}
+}
+
+// placeExits places the stop loss and then the take profit leg for qty and returns the take profit order.
+// The stop loss is canceled again if the take profit is rejected.
+func (e *Engine) placeExits(orderGroup models.OrderGroup, qty string) (models.Order, error) {
+	_, takeProfitReq, stopLossReq := converter.OrderGroupToLegReqs(orderGroup, qty)
+
+	ticker, _ := e.cache.Ticker(orderGroup.Pair)
+	stopLoss, err := e.conditionalOrderEngine.Create(stopLossReq, ticker.LastPrice)
+	if err != nil {
+		return models.Order{}, err
+	}
+	e.addLeg(models.OrderGroupLeg{OrderGroupID: orderGroup.ID, Role: "stopLoss", ConditionalOrderID: stopLoss.ID})
+
+	takeProfit, err := e.router.PlaceOrder(takeProfitReq)
+	if err != nil {
+		if _, err := e.conditionalOrderEngine.Cancel(stopLoss.ID); err != nil {
+			e.logger.Error("failed to cancel stop loss of order group", "orderGroupID", orderGroup.ID, "conditionalOrderID", stopLoss.ID, "error", err)
+		}
+		return models.Order{}, err
+	}
+	e.addLeg(models.OrderGroupLeg{OrderGroupID: orderGroup.ID, Role: "takeProfit", OrderID: takeProfit.ID})
+	return takeProfit, nil
+}
+
+// cancelLegs cancels the resting legs of the group except the one with the role keep. It returns false
+// if a leg could not be canceled for now, the group is then evaluated again by the next sync.
+func (e *Engine) cancelLegs(orderGroup models.OrderGroup, legs []models.Order, keep string) bool {
+	for _, leg := range legs {
+		if leg.OrderGroupRole == keep {
+			continue
+		}
+
+		switch {
+		case leg.ConditionalOrderID != 0 && leg.Status == "untriggered":
+			_, err := e.conditionalOrderEngine.Cancel(leg.ConditionalOrderID)
+			if errors.Is(err, conditionalOrder.ErrNotCancelable) {
+				e.logger.Warn("stop loss of order group was triggered before it could be canceled", "orderGroupID", orderGroup.ID, "conditionalOrderID", leg.ConditionalOrderID)
+			} else if err != nil {
+				e.logger.Error("failed to cancel stop loss of order group", "orderGroupID", orderGroup.ID, "conditionalOrderID", leg.ConditionalOrderID, "error", err)
+				return false
+			}
+
+		case leg.ConditionalOrderID == 0 && leg.Status == "filling":
+			if _, err := e.router.CancelOrder(leg.ID); err != nil {
+				e.logger.Error("failed to cancel leg of order group", "orderGroupID", orderGroup.ID, "orderID", leg.ID, "error", err)
+				// a rejected cancel means the order is closed already
+				if st, ok := status.FromError(err); !ok || st.Code() == codes.Unavailable || st.Code() == codes.DeadlineExceeded {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// legOrders returns the legs of the group as orders with update in place of the stored order,
+// update may be nil. A stop loss leg is shown as its conditional order.
+func (e *Engine) legOrders(orderGroupID int, update *models.Order) ([]models.Order, error) {
+	legs, err := e.db.GetOrderGroupLegs(orderGroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	var orders = make([]models.Order, 0, len(legs))
+	for _, leg := range legs {
+		var order models.Order
+		switch {
+		case leg.ConditionalOrderID != 0:
+			conditionalOrder, err := e.db.GetConditionalOrder(leg.ConditionalOrderID)
+			if err != nil {
+				return nil, err
+			}
+			order = converter.ConditionalOrderToModelsOrder(conditionalOrder)
+
+		case update != nil && update.ID == leg.OrderID:
+			order = *update
+
+		default:
+			order, err = e.db.GetOrderByID(leg.OrderID)
+			if err != nil && !errors.Is(err, repository.ErrNotFound) {
+				return nil, err
+			}
+			// the order may not be stored yet
+			order.ID = leg.OrderID
+		}
+
+		order.OrderGroupID = orderGroupID
+		order.OrderGroupRole = leg.Role
+		orders = append(orders, order)
+	}
+	return orders, nil
+}
+
+// transit moves the group from status from to status to, it returns false if another gateway or request
+// changed its status first
+func (e *Engine) transit(orderGroup *models.OrderGroup, from, to, message string) bool {
+	next := *orderGroup
+	next.Status = to
+	next.Error = message
+	if to != "active" {
+		next.ClosedAt = time.Now()
+	}
+
+	ok, err := e.db.UpdateOrderGroup(next, from)
+	if err != nil {
+		e.logger.Error("failed to update order group", "orderGroupID", orderGroup.ID, "error", err)
+		return false
+	}
+	if ok {
+		*orderGroup = next
+	}
+	return ok
+}
+
+func (e *Engine) addLeg(leg models.OrderGroupLeg) {
+	if err := e.db.AddOrderGroupLeg(leg); err != nil {
+		e.logger.Error("failed to save leg of order group", "orderGroupID", leg.OrderGroupID, "role", leg.Role, "error", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if leg.ConditionalOrderID != 0 {
+		e.conditionalOrders[leg.ConditionalOrderID] = leg.OrderGroupID
+	} else {
+		e.orders[leg.OrderID] = leg.OrderGroupID
+	}
+}
+
+// broadcast sends the group with its legs to the owner, update may be nil
+func (e *Engine) broadcast(orderGroup models.OrderGroup, update *models.Order) {
+	legs, err := e.legOrders(orderGroup.ID, update)
+	if err != nil {
+		e.logger.Error("failed to get legs of order group", "orderGroupID", orderGroup.ID, "error", err)
+	}
+
+	e.hub.BroadcastOrder(converter.OrderGroupToModelsOrder(orderGroup, legs))
+}
+
+// failure is the reason a leg was rejected as shown to the owner
+func failure(err error) string {
+	if errors.Is(err, conditionalOrder.ErrWouldTrigger) {
+		return err.Error()
+	}
+	if st, ok := status.FromError(err); ok && st.Message() != "" {
+		return st.Message()
+	}
+	return "internal error"
+}
+
+func sizeFilled(order models.Order) *big.Rat {
+	if filled, ok := decimal.Parse(order.SizeFilled); ok {
+		return filled
+	}
+	return new(big.Rat)
+}
@@ -0,0 +1,177 @@
+package orderRouter
+
+import (
+	"log/slog"
+	"sync"
+
+	mClient "github.com/BazaarTrade/ApiGatewayService/internal/api/gRPC/matchingEngineClient"
+	ws "github.com/BazaarTrade/ApiGatewayService/internal/api/websocket"
+	"github.com/BazaarTrade/ApiGatewayService/internal/converter"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/riskChecker"
+	"github.com/BazaarTrade/MatchingEngineProtoGen/pbM"
+)
+
+var cancelConcurrency = 8
+
+// Listener is notified about every order update that passes through the gateway
+type Listener interface {
+	OnOrderUpdate(order models.Order)
+}
+
+// Router is the single path orders take to the matching engine, so that every resulting
+// order update reaches the owner's websocket and the gateway's own listeners.
+// Requests are expected to be validated and authorized by the caller, the router runs the risk checks.
+type Router struct {
+	mClient     *mClient.Client
+	hub         *ws.Hub
+	riskChecker *riskChecker.Checker
+	listeners   []Listener
+	// hidden orders are iceberg slices, their updates reach the listeners but not the owner
+	hidden map[int]bool
+	mu     sync.RWMutex
+	logger *slog.Logger
+}
+
+func New(mClient *mClient.Client, hub *ws.Hub, riskChecker *riskChecker.Checker, logger *slog.Logger) *Router {
+	return &Router{
+		mClient:     mClient,
+		hub:         hub,
+		riskChecker: riskChecker,
+		hidden:      make(map[int]bool),
+		logger:      logger,
+	}
+}
+
+func (r *Router) AddListener(listener Listener) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.listeners = append(r.listeners, listener)
+}
+
+// PlaceOrder places the order and publishes it together with the maker orders it matched
+func (r *Router) PlaceOrder(req models.PlaceOrderReq) (models.Order, error) {
+	return r.placeOrder(req, false, 0)
+}
+
+// PlaceReplacementOrder is PlaceOrder for an order replacing replacedOrderID, which the risk check leaves out
+// even if it is still listed as open
+func (r *Router) PlaceReplacementOrder(req models.PlaceOrderReq, replacedOrderID int) (models.Order, error) {
+	return r.placeOrder(req, false, replacedOrderID)
+}
+
+// PlaceHiddenOrder places an order whose updates are only published to the listeners while it rests,
+// the listener that placed it reports it to the owner as part of its parent order
+func (r *Router) PlaceHiddenOrder(req models.PlaceOrderReq) (models.Order, error) {
+	return r.placeOrder(req, true, 0)
+}
+
+func (r *Router) placeOrder(req models.PlaceOrderReq, hidden bool, replacedOrderID int) (models.Order, error) {
+	if err := r.riskChecker.Check(req, replacedOrderID); err != nil {
+		return models.Order{}, err
+	}
+
+	order, matchOrders, err := r.mClient.PlaceOrder(converter.ModelsPlaceOrderReqToPbMPlaceOrderReq(req))
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	order.ClientOrderID = req.ClientOrderID
+	if hidden {
+		r.Hide(order.ID)
+	}
+
+	r.Publish(order)
+	for _, matchOrder := range matchOrders {
+		r.Publish(matchOrder)
+	}
+	return order, nil
+}
+
+func (r *Router) CancelOrder(orderID int) (models.Order, error) {
+	order, err := r.mClient.CancelOrder(&pbM.OrderID{OrderID: int64(orderID)})
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	r.Publish(order)
+	return order, nil
+}
+
+// CancelFailure is an order CancelOrders failed to cancel
+type CancelFailure struct {
+	OrderID int
+	Err     error
+}
+
+// CancelOrders cancels the orders with bounded concurrency and returns the canceled ones
+func (r *Router) CancelOrders(orderIDs []int) ([]models.Order, []CancelFailure) {
+	var (
+		canceled  = []models.Order{}
+		failed    = []CancelFailure{}
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		semaphore = make(chan struct{}, cancelConcurrency)
+	)
+	for _, orderID := range orderIDs {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+
+			order, err := r.CancelOrder(orderID)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				failed = append(failed, CancelFailure{OrderID: orderID, Err: err})
+				return
+			}
+			canceled = append(canceled, order)
+		}()
+	}
+	wg.Wait()
+
+	return canceled, failed
+}
+
+// Hide keeps the updates of the resting order from its owner, e.g. for iceberg slices placed before a restart
+func (r *Router) Hide(orderID int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.hidden[orderID] = true
+}
+
+func (r *Router) IsHidden(orderID int) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.hidden[orderID]
+}
+
+// Publish sends the order update to the owner unless the order is hidden, and to every listener
+func (r *Router) Publish(order models.Order) {
+	r.mu.Lock()
+	hidden := r.hidden[order.ID]
+	if hidden && order.Status != "filling" {
+		delete(r.hidden, order.ID)
+	}
+	r.mu.Unlock()
+
+	if !hidden {
+		r.hub.BroadcastOrder(order)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, listener := range r.listeners {
+		listener.OnOrderUpdate(order)
+	}
+}
@@ -0,0 +1,99 @@
+package orderState
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/decimal"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+)
+
+// Tracker keeps the last update of every order that passed through the gateway for maxAge,
+// so lookups of recently active orders are served without the database.
+// Updates made by other gateways are not seen, which maxAge bounds.
+type Tracker struct {
+	maxAge time.Duration
+	orders map[int]trackedOrder
+	mu     sync.RWMutex
+}
+
+type trackedOrder struct {
+	order     models.Order
+	updatedAt time.Time
+}
+
+func New(maxAge time.Duration) *Tracker {
+	return &Tracker{
+		maxAge: maxAge,
+		orders: make(map[int]trackedOrder),
+	}
+}
+
+// OnOrderUpdate is the order router listener. Updates of one order may be published concurrently,
+// so an update older than the tracked one is ignored.
+func (t *Tracker) OnOrderUpdate(order models.Order) {
+	if t.maxAge <= 0 || order.ID == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if tracked, ok := t.orders[order.ID]; ok && isOlder(order, tracked.order) {
+		return
+	}
+
+	t.orders[order.ID] = trackedOrder{
+		order:     order,
+		updatedAt: time.Now(),
+	}
+}
+
+// Order returns the order if it was updated within maxAge
+func (t *Tracker) Order(orderID int) (models.Order, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	tracked, ok := t.orders[orderID]
+	if !ok || time.Since(tracked.updatedAt) > t.maxAge {
+		return models.Order{}, false
+	}
+	return tracked.order, true
+}
+
+// Run evicts the orders that are no longer fresh
+func (t *Tracker) Run(ctx context.Context) {
+	if t.maxAge <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(t.maxAge)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.mu.Lock()
+			for orderID, tracked := range t.orders {
+				if time.Since(tracked.updatedAt) > t.maxAge {
+					delete(t.orders, orderID)
+				}
+			}
+			t.mu.Unlock()
+		}
+	}
+}
+
+// isOlder reports whether update precedes tracked: a closed order never reopens and fills only grow
+func isOlder(update, tracked models.Order) bool {
+	if tracked.Status != "filling" {
+		return update.Status == "filling"
+	}
+
+	updateFilled, ok1 := decimal.Parse(update.SizeFilled)
+	trackedFilled, ok2 := decimal.Parse(tracked.SizeFilled)
+	return update.Status == "filling" && ok1 && ok2 && updateFilled.Cmp(trackedFilled) < 0
+}
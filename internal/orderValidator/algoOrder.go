@@ -0,0 +1,130 @@
+package orderValidator
+
+import (
+	"math/big"
+	"slices"
+	"strconv"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/decimal"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+)
+
+var (
+	// MaxAlgoOrderDuration is in seconds
+	MaxAlgoOrderDuration int64 = 7 * 24 * 60 * 60
+	MaxAlgoOrderSlices   int64 = 1000
+)
+
+// ValidateAlgoOrder checks the parent order against the parameters of its pair, an empty
+// pairParams.Pair means the pair is not listed. The total qty may exceed the pair's maximum,
+// an average twap slice or the visible qty of an iceberg may not.
+func ValidateAlgoOrder(req models.AlgoOrderReq, pairParams models.PairParams) []FieldError {
+	var fieldErrors []FieldError
+	reject := func(field, reason string) {
+		fieldErrors = append(fieldErrors, FieldError{Field: field, Error: reason})
+	}
+
+	var slicesCount int64
+	switch req.Type {
+	case "twap":
+		slicesCount = validateSlices(req, reject)
+		if req.VisibleQty != "" {
+			reject("visibleQty", "is only allowed for iceberg orders")
+		}
+	case "iceberg":
+		if req.Duration != 0 {
+			reject("duration", "is only allowed for twap orders")
+		}
+		if req.SliceInterval != 0 {
+			reject("sliceInterval", "is only allowed for twap orders")
+		}
+		if req.Price == "" {
+			reject("price", "is required for iceberg orders")
+		}
+	default:
+		reject("type", "must be twap or iceberg")
+	}
+
+	if pairParams.Pair == "" {
+		reject("pair", "is not listed")
+		return fieldErrors
+	}
+
+	lotSize := decimal.Step(pairParams.QtyPrecision)
+	minQty := lotSize
+	if pairMinQty, ok := decimal.Parse(pairParams.MinQty); ok && pairMinQty.Cmp(minQty) > 0 {
+		minQty = pairMinQty
+	}
+	maxQty, hasMaxQty := decimal.Parse(pairParams.MaxQty)
+
+	qty, ok := decimal.Parse(req.Qty)
+	switch {
+	case !ok:
+		reject("qty", "must be a decimal number")
+	case qty.Sign() <= 0:
+		reject("qty", "must be greater than 0")
+	case !decimal.IsMultiple(qty, lotSize):
+		reject("qty", "must be a multiple of the lot size "+decimal.Format(lotSize))
+	case slicesCount > 0:
+		sliceQty := new(big.Rat).Quo(qty, new(big.Rat).SetInt64(slicesCount))
+		if sliceQty.Cmp(minQty) < 0 {
+			reject("qty", "must be at least "+decimal.Format(minQty)+" per slice")
+		}
+		if hasMaxQty && sliceQty.Cmp(maxQty) > 0 {
+			reject("qty", "must not be greater than "+pairParams.MaxQty+" per slice")
+		}
+	}
+
+	if req.Type == "iceberg" {
+		visibleQty, ok := decimal.Parse(req.VisibleQty)
+		switch {
+		case !ok:
+			reject("visibleQty", "must be a decimal number")
+		case !decimal.IsMultiple(visibleQty, lotSize):
+			reject("visibleQty", "must be a multiple of the lot size "+decimal.Format(lotSize))
+		case visibleQty.Cmp(minQty) < 0:
+			reject("visibleQty", "must not be less than "+decimal.Format(minQty))
+		case hasMaxQty && visibleQty.Cmp(maxQty) > 0:
+			reject("visibleQty", "must not be greater than "+pairParams.MaxQty)
+		case qty != nil && visibleQty.Cmp(qty) >= 0:
+			reject("visibleQty", "must be less than qty")
+		}
+	}
+
+	if req.Price != "" {
+		price, ok := decimal.Parse(req.Price)
+		switch {
+		case !ok:
+			reject("price", "must be a decimal number")
+		case price.Sign() <= 0:
+			reject("price", "must be greater than 0")
+		case len(pairParams.OrderBookPricePrecisions) > 0:
+			// the finest order book precision is the tick size
+			if tick := decimal.Step(slices.Max(pairParams.OrderBookPricePrecisions)); !decimal.IsMultiple(price, tick) {
+				reject("price", "must be a multiple of the tick size "+decimal.Format(tick))
+			}
+		}
+	}
+
+	return fieldErrors
+}
+
+// validateSlices checks the duration and slice interval of a twap order and returns its number of slices,
+// 0 if they are invalid
+func validateSlices(req models.AlgoOrderReq, reject func(field, reason string)) int64 {
+	switch {
+	case req.Duration <= 0 || req.Duration > MaxAlgoOrderDuration:
+		reject("duration", "must be between 1 and "+strconv.FormatInt(MaxAlgoOrderDuration, 10)+" seconds")
+		return 0
+	case req.SliceInterval <= 0 || req.SliceInterval > req.Duration:
+		reject("sliceInterval", "must be between 1 second and the duration")
+		return 0
+	}
+
+	slicesCount := (req.Duration + req.SliceInterval - 1) / req.SliceInterval
+	if slicesCount > MaxAlgoOrderSlices {
+		reject("sliceInterval", "must split the duration into at most "+strconv.FormatInt(MaxAlgoOrderSlices, 10)+" slices")
+		return 0
+	}
+	return slicesCount
+}
@@ -0,0 +1,74 @@
+package orderValidator
+
+import (
+	"slices"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/converter"
+	"github.com/BazaarTrade/ApiGatewayService/internal/decimal"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+)
+
+// ValidateOrderGroup checks every leg of the order group like a single order and that the take profit
+// and stop loss are on the right sides of each other and of the entry price
+func ValidateOrderGroup(req models.OrderGroupReq, pairParams models.PairParams, lastPrice string) []FieldError {
+	var fieldErrors []FieldError
+	reject := func(field, reason string) {
+		fieldError := FieldError{Field: field, Error: reason}
+		// the legs share the pair and qty, so their errors repeat
+		if !slices.Contains(fieldErrors, fieldError) {
+			fieldErrors = append(fieldErrors, fieldError)
+		}
+	}
+
+	switch req.Type {
+	case "oco":
+		if req.EntryPrice != "" {
+			reject("entryPrice", "is only allowed for bracket orders")
+		}
+	case "bracket":
+	default:
+		reject("type", "must be oco or bracket")
+	}
+
+	entry, takeProfit, stopLoss := converter.OrderGroupToLegReqs(converter.OrderGroupReqToOrderGroup(req), req.Qty)
+
+	validateLeg := func(legReq models.PlaceOrderReq, priceField string) {
+		for _, fieldError := range Validate(legReq, pairParams, lastPrice) {
+			if fieldError.Field == "price" {
+				fieldError.Field = priceField
+			}
+			reject(fieldError.Field, fieldError.Error)
+		}
+	}
+	if req.Type == "bracket" {
+		validateLeg(entry, "entryPrice")
+	}
+	validateLeg(takeProfit, "takeProfitPrice")
+	validateLeg(stopLoss, "stopLimitPrice")
+
+	takeProfitPrice, hasTakeProfit := decimal.Parse(req.TakeProfitPrice)
+	stopPrice, hasStop := decimal.Parse(req.StopPrice)
+	if !hasTakeProfit || !hasStop {
+		return fieldErrors
+	}
+
+	// a sell take profit is above the stop loss, a buy take profit below it
+	side := 1
+	if req.IsBid {
+		side = -1
+	}
+	if takeProfitPrice.Cmp(stopPrice) != side {
+		if req.IsBid {
+			reject("takeProfitPrice", "must be below stopPrice for buy exits")
+		} else {
+			reject("takeProfitPrice", "must be above stopPrice for sell exits")
+		}
+	}
+
+	if entryPrice, ok := decimal.Parse(req.EntryPrice); ok && req.Type == "bracket" {
+		if takeProfitPrice.Cmp(entryPrice) != side || entryPrice.Cmp(stopPrice) != side {
+			reject("entryPrice", "must be between takeProfitPrice and stopPrice")
+		}
+	}
+	return fieldErrors
+}
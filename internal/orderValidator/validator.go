@@ -0,0 +1,193 @@
+package orderValidator
+
+import (
+	"math/big"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/decimal"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+)
+
+const MaxClientOrderIDLength = 64
+
+// FieldError describes why a field of an order request was rejected
+type FieldError struct {
+	Field string `json:"field"`
+	Error string `json:"error"`
+}
+
+// IsConditional reports whether orders of the type are held by the gateway until a stop price is reached
+func IsConditional(orderType string) bool {
+	return orderType == "stopLimit" || orderType == "stopMarket" || orderType == "trailingStop"
+}
+
+// Validate checks the order against the parameters of its pair, an empty pairParams.Pair means
+// the pair is not listed. lastPrice estimates the notional of market orders and may be empty.
+func Validate(req models.PlaceOrderReq, pairParams models.PairParams, lastPrice string) []FieldError {
+	var fieldErrors []FieldError
+	reject := func(field, reason string) {
+		fieldErrors = append(fieldErrors, FieldError{Field: field, Error: reason})
+	}
+
+	if len(req.ClientOrderID) > MaxClientOrderIDLength {
+		reject("clientOrderId", "must not be longer than "+strconv.Itoa(MaxClientOrderIDLength)+" characters")
+	}
+
+	if req.Type != "market" && req.Type != "limit" && !IsConditional(req.Type) {
+		reject("type", "must be market, limit, stopLimit, stopMarket or trailingStop")
+	}
+
+	validateTimeInForce(req, reject)
+
+	if req.PostOnly {
+		if req.Type != "limit" {
+			reject("postOnly", "is only allowed for limit orders")
+		}
+		if req.TimeInForce == "IOC" || req.TimeInForce == "FOK" || req.TimeInForce == "GAT" {
+			reject("postOnly", "is not allowed with timeInForce "+req.TimeInForce)
+		}
+	}
+
+	if pairParams.Pair == "" {
+		reject("pair", "is not listed")
+		return fieldErrors
+	}
+
+	qty, ok := decimal.Parse(req.Qty)
+	switch {
+	case !ok:
+		reject("qty", "must be a decimal number")
+	case qty.Sign() <= 0:
+		reject("qty", "must be greater than 0")
+	case !decimal.IsMultiple(qty, decimal.Step(pairParams.QtyPrecision)):
+		reject("qty", "must be a multiple of the lot size "+decimal.Format(decimal.Step(pairParams.QtyPrecision)))
+	default:
+		if minQty, ok := decimal.Parse(pairParams.MinQty); ok && qty.Cmp(minQty) < 0 {
+			reject("qty", "must not be less than "+pairParams.MinQty)
+		}
+		if maxQty, ok := decimal.Parse(pairParams.MaxQty); ok && qty.Cmp(maxQty) > 0 {
+			reject("qty", "must not be greater than "+pairParams.MaxQty)
+		}
+	}
+
+	// the finest order book precision is the tick size
+	var tick *big.Rat
+	if len(pairParams.OrderBookPricePrecisions) > 0 {
+		tick = decimal.Step(slices.Max(pairParams.OrderBookPricePrecisions))
+	}
+
+	checkPrice := func(field, value string) *big.Rat {
+		price, ok := decimal.Parse(value)
+		switch {
+		case !ok:
+			reject(field, "must be a decimal number")
+			return nil
+		case price.Sign() <= 0:
+			reject(field, "must be greater than 0")
+			return nil
+		case tick != nil && !decimal.IsMultiple(price, tick):
+			reject(field, "must be a multiple of the tick size "+decimal.Format(tick))
+		}
+		return price
+	}
+
+	var price *big.Rat
+	if req.Type == "limit" || req.Type == "stopLimit" || req.Price != "" {
+		price = checkPrice("price", req.Price)
+	}
+
+	switch req.Type {
+	case "stopLimit", "stopMarket":
+		stopPrice := checkPrice("stopPrice", req.StopPrice)
+		if req.Type == "stopMarket" {
+			price = stopPrice
+		}
+
+	case "trailingStop":
+		if req.StopPrice != "" {
+			checkPrice("stopPrice", req.StopPrice)
+		}
+		checkPrice("trailingDelta", req.TrailingDelta)
+
+	default:
+		if req.StopPrice != "" {
+			reject("stopPrice", "is only allowed for conditional orders")
+		}
+		if req.TrailingDelta != "" {
+			reject("trailingDelta", "is only allowed for trailing stop orders")
+		}
+	}
+
+	if req.Type == "market" || req.Type == "trailingStop" {
+		price, _ = decimal.Parse(lastPrice)
+	}
+
+	// the notional of a market order without a known last price can not be checked
+	if qty != nil && qty.Sign() > 0 && price != nil {
+		notional := new(big.Rat).Mul(qty, price)
+		if minNotional, ok := decimal.Parse(pairParams.MinNotional); ok && notional.Cmp(minNotional) < 0 {
+			reject("qty", "notional must not be less than "+pairParams.MinNotional)
+		}
+		if maxNotional, ok := decimal.Parse(pairParams.MaxNotional); ok && notional.Cmp(maxNotional) > 0 {
+			reject("qty", "notional must not be greater than "+pairParams.MaxNotional)
+		}
+	}
+
+	return fieldErrors
+}
+
+func validateTimeInForce(req models.PlaceOrderReq, reject func(field, reason string)) {
+	now := time.Now()
+	parseFuture := func(field, value string) (time.Time, bool) {
+		t, err := time.Parse(time.RFC3339, value)
+		switch {
+		case value == "":
+			reject(field, "is required")
+			return time.Time{}, false
+		case err != nil:
+			reject(field, "must be an RFC3339 time")
+			return time.Time{}, false
+		case !t.After(now):
+			reject(field, "must be in the future")
+			return time.Time{}, false
+		}
+		return t, true
+	}
+
+	switch req.TimeInForce {
+	case "", "GTC", "IOC", "FOK":
+	case "GTD":
+		if req.Type != "limit" {
+			reject("timeInForce", "GTD is only allowed for limit orders")
+		}
+	case "GAT":
+	default:
+		reject("timeInForce", "must be GTC, IOC, FOK, GTD or GAT")
+		return
+	}
+
+	if IsConditional(req.Type) && req.TimeInForce != "" && req.TimeInForce != "GTC" {
+		reject("timeInForce", "only GTC is allowed for conditional orders")
+	}
+
+	var startTime time.Time
+	if req.TimeInForce == "GAT" {
+		startTime, _ = parseFuture("startTime", req.StartTime)
+	} else if req.StartTime != "" {
+		reject("startTime", "is only allowed with timeInForce GAT")
+	}
+
+	switch {
+	case req.TimeInForce == "GTD" || (req.TimeInForce == "GAT" && req.ExpireTime != ""):
+		if req.TimeInForce == "GAT" && req.Type != "limit" {
+			reject("expireTime", "is only allowed for limit orders")
+		}
+		if expireTime, ok := parseFuture("expireTime", req.ExpireTime); ok && !startTime.IsZero() && !expireTime.After(startTime) {
+			reject("expireTime", "must be after startTime")
+		}
+	case req.ExpireTime != "":
+		reject("expireTime", "is only allowed with timeInForce GTD or GAT")
+	}
+}
@@ -0,0 +1,175 @@
+package rateLimiter
+
+import (
+	"context"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// idleEviction is how often buckets that refilled completely are dropped
+var idleEviction = time.Minute
+
+// Limit is a token bucket of Burst tokens refilled at Rate tokens per second, a zero Rate disables it
+type Limit struct {
+	Burst float64
+	Rate  float64
+}
+
+type Usage struct {
+	Kind            string  `json:"kind"`
+	Burst           float64 `json:"burst"`
+	RefillPerSecond float64 `json:"refillPerSecond"`
+	Remaining       float64 `json:"remaining"`
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// Limiter keeps a token bucket per user and kind of request, requests take as many tokens as their weight
+type Limiter struct {
+	limits  map[string]Limit
+	buckets map[string]*bucket
+	mu      sync.Mutex
+}
+
+func New(limits map[string]Limit) *Limiter {
+	return &Limiter{
+		limits:  limits,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Limit returns the limit of the kind, false if requests of the kind are not limited
+func (l *Limiter) Limit(kind string) (Limit, bool) {
+	limit, ok := l.limits[kind]
+	return limit, ok && limit.Rate > 0
+}
+
+// Take takes weight tokens if the bucket holds them, otherwise it returns how long until it does
+func (l *Limiter) Take(userID int, kind string, weight float64) (Usage, time.Duration, bool) {
+	usages, retryAfter, ok := l.TakeAll(userID, map[string]float64{kind: weight})
+	return usages[0], retryAfter, ok
+}
+
+// TakeAll takes the weights of several kinds at once, from every bucket or, if one of them does not hold
+// its weight, from none. It returns the usages sorted by kind and how long until every bucket holds its weight.
+func (l *Limiter) TakeAll(userID int, weights map[string]float64) ([]Usage, time.Duration, bool) {
+	kinds := slices.Sorted(maps.Keys(weights))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	var (
+		buckets    = make([]*bucket, len(kinds))
+		retryAfter time.Duration
+		ok         = true
+	)
+	for i, kind := range kinds {
+		limit, enabled := l.limits[kind]
+		if !enabled || limit.Rate <= 0 {
+			continue
+		}
+
+		buckets[i] = l.refill(userID, kind, limit, now)
+		if missing := weights[kind] - buckets[i].tokens; missing > 0 {
+			retryAfter = max(retryAfter, time.Duration(missing/limit.Rate*float64(time.Second)))
+			ok = false
+		}
+	}
+
+	var usages = make([]Usage, 0, len(kinds))
+	for i, kind := range kinds {
+		if buckets[i] == nil {
+			usages = append(usages, Usage{Kind: kind})
+			continue
+		}
+
+		if ok {
+			buckets[i].tokens -= weights[kind]
+		}
+		usages = append(usages, usage(kind, l.limits[kind], buckets[i]))
+	}
+	return usages, retryAfter, ok
+}
+
+// Usage returns the state of every bucket of the user, sorted by kind
+func (l *Limiter) Usage(userID int) []Usage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	var usages []Usage
+	for kind, limit := range l.limits {
+		if limit.Rate > 0 {
+			usages = append(usages, usage(kind, limit, l.refill(userID, kind, limit, now)))
+		}
+	}
+
+	slices.SortFunc(usages, func(a, b Usage) int {
+		return strings.Compare(a.Kind, b.Kind)
+	})
+	return usages
+}
+
+func (l *Limiter) Run(ctx context.Context) {
+	ticker := time.NewTicker(idleEviction)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.evictFull()
+		}
+	}
+}
+
+// evictFull drops the buckets that would be full by now, a missing bucket is a full one
+func (l *Limiter) evictFull() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for key, b := range l.buckets {
+		limit := l.limits[key[strings.IndexByte(key, '|')+1:]]
+		if b.tokens+now.Sub(b.updatedAt).Seconds()*limit.Rate >= limit.Burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// refill must be called with l.mu held
+func (l *Limiter) refill(userID int, kind string, limit Limit, now time.Time) *bucket {
+	key := strconv.Itoa(userID) + "|" + kind
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{
+			tokens:    limit.Burst,
+			updatedAt: now,
+		}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(limit.Burst, b.tokens+now.Sub(b.updatedAt).Seconds()*limit.Rate)
+	b.updatedAt = now
+	return b
+}
+
+func usage(kind string, limit Limit, b *bucket) Usage {
+	return Usage{
+		Kind:            kind,
+		Burst:           limit.Burst,
+		RefillPerSecond: limit.Rate,
+		Remaining:       math.Floor(b.tokens),
+	}
+}
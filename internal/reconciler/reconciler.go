@@ -0,0 +1,160 @@
+package reconciler
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	qClient "github.com/BazaarTrade/ApiGatewayService/internal/api/gRPC/quoteClient"
+	ws "github.com/BazaarTrade/ApiGatewayService/internal/api/websocket"
+	"github.com/BazaarTrade/ApiGatewayService/internal/marketData"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/repository"
+)
+
+var retry = time.Second * 5
+
+// Reconciler keeps stream readers and hub topics in sync with the pairs stored in the database.
+type Reconciler struct {
+	db       repository.Repository
+	qClient  *qClient.Client
+	hub      *ws.Hub
+	cache    *marketData.Cache
+	interval time.Duration
+	trigger  chan struct{}
+	pairs    map[string]models.PairParams
+	pairsMu  sync.RWMutex
+	// replayed reports pairs fed by a replayed recording, they are kept even if they are not listed
+	replayed func(pair string) bool
+	mu       sync.Mutex
+	logger   *slog.Logger
+}
+
+func New(db repository.Repository, qClient *qClient.Client, hub *ws.Hub, cache *marketData.Cache, interval time.Duration, logger *slog.Logger) *Reconciler {
+	return &Reconciler{
+		db:       db,
+		qClient:  qClient,
+		hub:      hub,
+		cache:    cache,
+		interval: interval,
+		trigger:  make(chan struct{}, 1),
+		pairs:    make(map[string]models.PairParams),
+		logger:   logger,
+	}
+}
+
+// SetReplayedPairs keeps the pairs replayed reports from being removed, call it before Run.
+func (r *Reconciler) SetReplayedPairs(replayed func(pair string) bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.replayed = replayed
+}
+
+// Run reconciles every interval, on Trigger and on pairs_changed notifications from other replicas.
+func (r *Reconciler) Run(ctx context.Context) {
+	go r.listen(ctx)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.trigger:
+		}
+
+		if err := r.Reconcile(); err != nil {
+			r.logger.Error("failed to reconcile pairs", "error", err)
+		}
+	}
+}
+
+func (r *Reconciler) listen(ctx context.Context) {
+	for {
+		err := r.db.ListenPairsChanged(ctx, r.Trigger)
+		if ctx.Err() != nil {
+			return
+		}
+
+		r.logger.Warn("pairs_changed listener stopped", "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+	}
+}
+
+// Trigger schedules a reconcile without blocking, repeated calls are coalesced.
+func (r *Reconciler) Trigger() {
+	select {
+	case r.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Reconcile is safe to call concurrently, calls are serialized.
+func (r *Reconciler) Reconcile() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pairsParams, err := r.db.GetPairsParams()
+	if err != nil {
+		return err
+	}
+
+	var pairs = make(map[string]models.PairParams, len(pairsParams))
+	for _, pairParams := range pairsParams {
+		pairs[pairParams.Pair] = pairParams
+		r.hub.SyncPairTopics(pairParams)
+		r.qClient.StartStreamReaders(pairParams.Pair)
+	}
+
+	r.pairsMu.Lock()
+	r.pairs = pairs
+	r.pairsMu.Unlock()
+
+	for _, pair := range append(r.qClient.RunningPairs(), r.hub.Pairs()...) {
+		if _, ok := pairs[pair]; !ok {
+			if r.replayed != nil && r.replayed(pair) {
+				continue
+			}
+
+			r.logger.Info("removing pair that is no longer listed", "pair", pair)
+			r.removePair(pair)
+		}
+	}
+	return nil
+}
+
+// RemovePair stops the pair stream readers and drops its topics and cached data.
+func (r *Reconciler) RemovePair(pair string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.removePair(pair)
+}
+
+func (r *Reconciler) removePair(pair string) {
+	r.pairsMu.Lock()
+	delete(r.pairs, pair)
+	r.pairsMu.Unlock()
+
+	r.qClient.StopStreamReadersByPair(pair)
+	r.hub.RemovePairTopics(pair)
+	r.cache.RemovePair(pair)
+}
+
+// PairParams returns the parameters of a listed pair as of the last reconcile.
+func (r *Reconciler) PairParams(pair string) (models.PairParams, bool) {
+	r.pairsMu.RLock()
+	defer r.pairsMu.RUnlock()
+
+	pairParams, ok := r.pairs[pair]
+	return pairParams, ok
+}
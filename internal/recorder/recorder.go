@@ -0,0 +1,150 @@
+package recorder
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+)
+
+// Record is one line of a recording, Type is the websocket topic name of the message.
+type Record struct {
+	Time      time.Time       `json:"time"`
+	Type      string          `json:"type"`
+	Precision int32           `json:"precision,omitempty"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Recorder writes every received quote stream message to JSONL files in dir,
+// a new file is started when the current one exceeds maxFileSize or gets older than rotateInterval.
+type Recorder struct {
+	dir            string
+	maxFileSize    int64
+	rotateInterval time.Duration
+
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	mu       sync.Mutex
+	logger   *slog.Logger
+}
+
+func New(dir string, maxFileSize int64, rotateInterval time.Duration, logger *slog.Logger) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		logger.Error("failed to create recordings directory", "error", err)
+		return nil, err
+	}
+
+	return &Recorder{
+		dir:            dir,
+		maxFileSize:    maxFileSize,
+		rotateInterval: rotateInterval,
+		logger:         logger,
+	}, nil
+}
+
+func (r *Recorder) OnOrderBookSnapshot(pOBS models.OrderBookSnapshot, precision int32) {
+	r.record("orderBook", precision, pOBS)
+}
+
+func (r *Recorder) OnTrades(trades []models.Trade) {
+	r.record("trades", 0, trades)
+}
+
+func (r *Recorder) OnTicker(ticker models.Ticker) {
+	r.record("ticker", 0, ticker)
+}
+
+func (r *Recorder) OnCandleStick(candleStick models.CandleStick) {
+	r.record("candleStick", 0, candleStick)
+}
+
+func (r *Recorder) record(recordType string, precision int32, data any) {
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		r.logger.Error("failed to marshal recorded message", "type", recordType, "error", err)
+		return
+	}
+
+	line, err := json.Marshal(Record{
+		Time:      time.Now(),
+		Type:      recordType,
+		Precision: precision,
+		Data:      dataJSON,
+	})
+	if err != nil {
+		r.logger.Error("failed to marshal record", "type", recordType, "error", err)
+		return
+	}
+	line = append(line, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.rotateIfNeeded(int64(len(line))); err != nil {
+		r.logger.Error("failed to rotate recording file", "error", err)
+		return
+	}
+
+	n, err := r.file.Write(line)
+	r.size += int64(n)
+	if err != nil {
+		r.logger.Error("failed to write record", "error", err)
+	}
+}
+
+// rotateIfNeeded must be called with r.mu held
+func (r *Recorder) rotateIfNeeded(nextWrite int64) error {
+	if r.file != nil {
+		tooBig := r.maxFileSize > 0 && r.size+nextWrite > r.maxFileSize
+		tooOld := r.rotateInterval > 0 && time.Since(r.openedAt) > r.rotateInterval
+		if !tooBig && !tooOld {
+			return nil
+		}
+
+		if err := r.closeFile(); err != nil {
+			return err
+		}
+	}
+
+	name := filepath.Join(r.dir, fmt.Sprintf("marketData-%s.jsonl", time.Now().UTC().Format("20060102T150405.000000000")))
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	r.file = file
+	r.size = 0
+	r.openedAt = time.Now()
+
+	r.logger.Info("started new recording file", "file", name)
+	return nil
+}
+
+// closeFile must be called with r.mu held
+func (r *Recorder) closeFile() error {
+	if r.file == nil {
+		return nil
+	}
+
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+
+	r.file = nil
+	return nil
+}
+
+func (r *Recorder) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.closeFile(); err != nil {
+		r.logger.Error("failed to close recording file", "error", err)
+	}
+}
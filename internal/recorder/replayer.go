@@ -0,0 +1,207 @@
+package recorder
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	ws "github.com/BazaarTrade/ApiGatewayService/internal/api/websocket"
+	"github.com/BazaarTrade/ApiGatewayService/internal/marketData"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+)
+
+var maxRecordSize = 16 * 1024 * 1024
+
+// Replayer feeds a recording into the hub and the market data cache,
+// so the gateway can serve market data without a quote service.
+type Replayer struct {
+	path   string
+	speed  float64
+	hub    *ws.Hub
+	cache  *marketData.Cache
+	pairs  map[string]bool // pairs replayed so far
+	mu     sync.RWMutex
+	logger *slog.Logger
+}
+
+// NewReplayer replays path, a recording file or a directory of them. Speed 1 keeps the
+// original pace, 10 plays ten times faster and 0 sends records without any delay.
+func NewReplayer(path string, speed float64, hub *ws.Hub, cache *marketData.Cache, logger *slog.Logger) *Replayer {
+	return &Replayer{
+		path:   path,
+		speed:  speed,
+		hub:    hub,
+		cache:  cache,
+		pairs:  make(map[string]bool),
+		logger: logger,
+	}
+}
+
+// HasPair reports whether the pair was replayed, its topics are fed by the replayer and not by the quote service.
+func (r *Replayer) HasPair(pair string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.pairs[pair]
+}
+
+// addPair is called before the topics of the pair are added, so a pair in the hub is already known
+func (r *Replayer) addPair(pair string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pairs[pair] = true
+}
+
+func (r *Replayer) Run(ctx context.Context) error {
+	files, err := r.files()
+	if err != nil {
+		r.logger.Error("failed to list recording files", "error", err)
+		return err
+	}
+
+	var previous time.Time
+	for _, file := range files {
+		r.logger.Info("replaying recording file", "file", file)
+
+		previous, err = r.replayFile(ctx, file, previous)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.logger.Info("replay finished", "path", r.path)
+	return nil
+}
+
+func (r *Replayer) files() ([]string, error) {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return []string{r.path}, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(r.path, "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+
+	// file names start with the creation time, so lexical order is chronological
+	sort.Strings(files)
+	return files, nil
+}
+
+func (r *Replayer) replayFile(ctx context.Context, name string, previous time.Time) (time.Time, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		r.logger.Error("failed to open recording file", "file", name, "error", err)
+		return previous, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxRecordSize)
+
+	for line := 1; scanner.Scan(); line++ {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			r.logger.Error("failed to unmarshal record", "file", name, "line", line, "error", err)
+			continue
+		}
+
+		if !previous.IsZero() && r.speed > 0 {
+			delay := time.Duration(float64(record.Time.Sub(previous)) / r.speed)
+			if delay > 0 {
+				select {
+				case <-ctx.Done():
+					return previous, ctx.Err()
+				case <-time.After(delay):
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return previous, ctx.Err()
+		default:
+		}
+
+		previous = record.Time
+
+		if err := r.dispatch(record); err != nil {
+			r.logger.Error("failed to replay record", "file", name, "line", line, "error", err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		r.logger.Error("failed to read recording file", "file", name, "error", err)
+		return previous, err
+	}
+	return previous, nil
+}
+
+func (r *Replayer) dispatch(record Record) error {
+	switch record.Type {
+	case "orderBook":
+		var pOBS models.OrderBookSnapshot
+		if err := json.Unmarshal(record.Data, &pOBS); err != nil {
+			return err
+		}
+
+		r.addPair(pOBS.Pair)
+		r.hub.AddOrderBookSnapshotTopic(pOBS.Pair, []int32{record.Precision})
+		r.cache.SetOrderBookSnapshot(pOBS, record.Precision)
+		r.hub.BroadcastPrecisedOrderBookSnapshot(pOBS, record.Precision)
+
+	case "trades":
+		var trades []models.Trade
+		if err := json.Unmarshal(record.Data, &trades); err != nil {
+			return err
+		}
+
+		if len(trades) == 0 {
+			return nil
+		}
+
+		r.addPair(trades[0].Pair)
+		r.hub.AddTradesTopic(trades[0].Pair)
+		r.cache.AddTrades(trades)
+		r.hub.BroadcastPrecisedTrades(trades)
+
+	case "ticker":
+		var ticker models.Ticker
+		if err := json.Unmarshal(record.Data, &ticker); err != nil {
+			return err
+		}
+
+		r.addPair(ticker.Pair)
+		r.hub.AddTickerTopic(ticker.Pair)
+		r.cache.SetTicker(ticker)
+		r.hub.BroadcastTicker(ticker)
+
+	case "candleStick":
+		var candleStick models.CandleStick
+		if err := json.Unmarshal(record.Data, &candleStick); err != nil {
+			return err
+		}
+
+		r.addPair(candleStick.Pair)
+		r.hub.AddCandleStickTopic(candleStick.Pair, []string{candleStick.Timeframe})
+		r.cache.SetCandleStick(candleStick)
+		r.hub.BroadcastCandleStick(candleStick)
+
+	default:
+		return fmt.Errorf("unknown record type %q", record.Type)
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package postgresPgx
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+// durations are stored in milliseconds
+const algoOrderColumns = `id, userID, isBid, pair, type, price, qty, duration, sliceInterval, visibleQty, slicesPlaced, sizeFilled, status, error, nextSliceAt, endAt, pausedAt, lockedUntil, createdAt, closedAt`
+
+func (p *Postgres) CreateAlgoOrder(algoOrder models.AlgoOrder) (models.AlgoOrder, error) {
+	return p.scanAlgoOrder(p.db.QueryRow(context.Background(), `
+	INSERT INTO apiGateway.algoOrders (userID, isBid, pair, type, price, qty, duration, sliceInterval, visibleQty, sizeFilled, status, nextSliceAt, endAt)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	RETURNING `+algoOrderColumns,
+		algoOrder.UserID,
+		algoOrder.IsBid,
+		algoOrder.Pair,
+		algoOrder.Type,
+		algoOrder.Price,
+		algoOrder.Qty,
+		algoOrder.Duration.Milliseconds(),
+		algoOrder.SliceInterval.Milliseconds(),
+		algoOrder.VisibleQty,
+		algoOrder.SizeFilled,
+		algoOrder.Status,
+		algoOrder.NextSliceAt,
+		nullTime(algoOrder.EndAt),
+	))
+}
+
+func (p *Postgres) GetAlgoOrder(id int) (models.AlgoOrder, error) {
+	return p.scanAlgoOrder(p.db.QueryRow(context.Background(), `
+	SELECT `+algoOrderColumns+`
+	FROM apiGateway.algoOrders
+	WHERE id = $1
+	`, id))
+}
+
+func (p *Postgres) GetAlgoOrdersByUser(userID int) ([]models.AlgoOrder, error) {
+	return p.queryAlgoOrders(`
+	SELECT `+algoOrderColumns+`
+	FROM apiGateway.algoOrders
+	WHERE userID = $1
+	ORDER BY id
+	`, userID)
+}
+
+func (p *Postgres) GetOpenAlgoOrders() ([]models.AlgoOrder, error) {
+	return p.queryAlgoOrders(`
+	SELECT ` + algoOrderColumns + `
+	FROM apiGateway.algoOrders
+	WHERE status IN ('running', 'paused')
+	ORDER BY id
+	`)
+}
+
+func (p *Postgres) ClaimDueAlgoOrders(now, lockedUntil time.Time, limit int) ([]models.AlgoOrder, error) {
+	return p.queryAlgoOrders(`
+	UPDATE apiGateway.algoOrders
+	SET lockedUntil = $2
+	WHERE id IN (
+		SELECT id
+		FROM apiGateway.algoOrders
+		WHERE status = 'running' AND nextSliceAt <= $1 AND (lockedUntil IS NULL OR lockedUntil <= $1)
+		ORDER BY nextSliceAt
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING `+algoOrderColumns, now, lockedUntil, limit)
+}
+
+func (p *Postgres) ClaimAlgoOrder(id int, now, lockedUntil time.Time) (models.AlgoOrder, bool, error) {
+	algoOrder, err := p.scanAlgoOrder(p.db.QueryRow(context.Background(), `
+	UPDATE apiGateway.algoOrders
+	SET lockedUntil = $3
+	WHERE id = $1 AND status = 'running' AND (lockedUntil IS NULL OR lockedUntil <= $2)
+	RETURNING `+algoOrderColumns, id, now, lockedUntil))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return models.AlgoOrder{}, false, nil
+		}
+		return models.AlgoOrder{}, false, err
+	}
+	return algoOrder, true, nil
+}
+
+func (p *Postgres) UpdateAlgoOrder(algoOrder models.AlgoOrder, status string) (bool, error) {
+	tag, err := p.db.Exec(context.Background(), `
+	UPDATE apiGateway.algoOrders
+	SET slicesPlaced = $3, sizeFilled = $4, status = $5, error = $6, nextSliceAt = $7, endAt = $8, pausedAt = $9, closedAt = $10, lockedUntil = NULL
+	WHERE id = $1 AND status = $2
+	`,
+		algoOrder.ID,
+		status,
+		algoOrder.SlicesPlaced,
+		algoOrder.SizeFilled,
+		algoOrder.Status,
+		algoOrder.Error,
+		algoOrder.NextSliceAt,
+		nullTime(algoOrder.EndAt),
+		nullTime(algoOrder.PausedAt),
+		nullTime(algoOrder.ClosedAt),
+	)
+	if err != nil {
+		p.logger.Error("failed to update algo order", "error", err)
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (p *Postgres) UpdateClaimedAlgoOrder(algoOrder models.AlgoOrder) (bool, error) {
+	tag, err := p.db.Exec(context.Background(), `
+	UPDATE apiGateway.algoOrders
+	SET slicesPlaced = $3, sizeFilled = $4, status = $5, error = $6, nextSliceAt = $7, endAt = $8, pausedAt = $9, closedAt = $10, lockedUntil = NULL
+	WHERE id = $1 AND status = 'running' AND lockedUntil = $2
+	`,
+		algoOrder.ID,
+		algoOrder.LockedUntil,
+		algoOrder.SlicesPlaced,
+		algoOrder.SizeFilled,
+		algoOrder.Status,
+		algoOrder.Error,
+		algoOrder.NextSliceAt,
+		nullTime(algoOrder.EndAt),
+		nullTime(algoOrder.PausedAt),
+		nullTime(algoOrder.ClosedAt),
+	)
+	if err != nil {
+		p.logger.Error("failed to update claimed algo order", "error", err)
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (p *Postgres) UpdateAlgoOrderSizeFilled(id int, sizeFilled string) error {
+	_, err := p.db.Exec(context.Background(), `
+	UPDATE apiGateway.algoOrders
+	SET sizeFilled = $2
+	WHERE id = $1
+	`, id, sizeFilled)
+	if err != nil {
+		p.logger.Error("failed to update filled size of algo order", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (p *Postgres) AddAlgoOrderChild(algoOrderID, orderID int) error {
+	_, err := p.db.Exec(context.Background(), `
+	INSERT INTO apiGateway.algoOrderChildren (orderID, algoOrderID)
+	VALUES ($1, $2)
+	`, orderID, algoOrderID)
+	if err != nil {
+		p.logger.Error("failed to insert algo order child", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (p *Postgres) GetAlgoOrderChildren(algoOrderID int) ([]models.Order, error) {
+	rows, err := p.db.Query(context.Background(), `
+	SELECT o.id, o.userID, o.isBid, o.pair, o.price, o.qty, o.sizeFilled, o.status, o.type, o.createdAt, o.closedAt
+	FROM apiGateway.algoOrderChildren c
+	JOIN matchingEngine.orders o ON o.id = c.orderID
+	WHERE c.algoOrderID = $1
+	ORDER BY o.id
+	`, algoOrderID)
+	if err != nil {
+		p.logger.Error("failed to select algo order children", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []models.Order
+	for rows.Next() {
+		order, err := p.scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
+
+func (p *Postgres) queryAlgoOrders(query string, args ...any) ([]models.AlgoOrder, error) {
+	rows, err := p.db.Query(context.Background(), query, args...)
+	if err != nil {
+		p.logger.Error("failed to select algo orders", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var algoOrders []models.AlgoOrder
+	for rows.Next() {
+		algoOrder, err := p.scanAlgoOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		algoOrders = append(algoOrders, algoOrder)
+	}
+	return algoOrders, rows.Err()
+}
+
+func (p *Postgres) scanAlgoOrder(row pgx.Row) (models.AlgoOrder, error) {
+	var (
+		algoOrder                              models.AlgoOrder
+		duration, sliceInterval                int64
+		endAt, pausedAt, lockedUntil, closedAt *time.Time
+	)
+	err := row.Scan(
+		&algoOrder.ID,
+		&algoOrder.UserID,
+		&algoOrder.IsBid,
+		&algoOrder.Pair,
+		&algoOrder.Type,
+		&algoOrder.Price,
+		&algoOrder.Qty,
+		&duration,
+		&sliceInterval,
+		&algoOrder.VisibleQty,
+		&algoOrder.SlicesPlaced,
+		&algoOrder.SizeFilled,
+		&algoOrder.Status,
+		&algoOrder.Error,
+		&algoOrder.NextSliceAt,
+		&endAt,
+		&pausedAt,
+		&lockedUntil,
+		&algoOrder.CreatedAt,
+		&closedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.AlgoOrder{}, repository.ErrNotFound
+		}
+		p.logger.Error("failed to scan algo order", "error", err)
+		return models.AlgoOrder{}, err
+	}
+
+	algoOrder.Duration = time.Duration(duration) * time.Millisecond
+	algoOrder.SliceInterval = time.Duration(sliceInterval) * time.Millisecond
+	if endAt != nil {
+		algoOrder.EndAt = *endAt
+	}
+	if pausedAt != nil {
+		algoOrder.PausedAt = *pausedAt
+	}
+	if lockedUntil != nil {
+		algoOrder.LockedUntil = *lockedUntil
+	}
+	if closedAt != nil {
+		algoOrder.ClosedAt = *closedAt
+	}
+	return algoOrder, nil
+}
+
+// nullTime stores the zero time as NULL
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,100 @@
+package postgresPgx
+
+import (
+	"context"
+	"errors"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+func (p *Postgres) ReserveClientOrder(clientOrder models.ClientOrder) (models.ClientOrder, bool, error) {
+	err := p.db.QueryRow(context.Background(), `
+	INSERT INTO apiGateway.clientOrders (userID, clientOrderID, idempotencyKey)
+	VALUES ($1, NULLIF($2, ''), NULLIF($3, ''))
+	ON CONFLICT DO NOTHING
+	RETURNING id, createdAt
+	`, clientOrder.UserID, clientOrder.ClientOrderID, clientOrder.IdempotencyKey).Scan(&clientOrder.ID, &clientOrder.CreatedAt)
+	if err == nil {
+		return clientOrder, true, nil
+	}
+
+	if !errors.Is(err, pgx.ErrNoRows) {
+		p.logger.Error("failed to insert client order", "error", err)
+		return models.ClientOrder{}, false, err
+	}
+
+	existing, err := p.scanClientOrder(p.db.QueryRow(context.Background(), `
+	SELECT id, userID, COALESCE(clientOrderID, ''), COALESCE(idempotencyKey, ''), COALESCE(orderID, 0), response, createdAt
+	FROM apiGateway.clientOrders
+	WHERE userID = $1 AND (clientOrderID = NULLIF($2, '') OR idempotencyKey = NULLIF($3, ''))
+	LIMIT 1
+	`, clientOrder.UserID, clientOrder.ClientOrderID, clientOrder.IdempotencyKey))
+	if err != nil {
+		return models.ClientOrder{}, false, err
+	}
+	return existing, false, nil
+}
+
+func (p *Postgres) CompleteClientOrder(id, orderID int, response []byte) error {
+	_, err := p.db.Exec(context.Background(), `
+	UPDATE apiGateway.clientOrders
+	SET orderID = $2, response = $3
+	WHERE id = $1
+	`, id, orderID, response)
+	if err != nil {
+		p.logger.Error("failed to update client order", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (p *Postgres) DeleteClientOrder(id int) error {
+	_, err := p.db.Exec(context.Background(), `
+	DELETE FROM apiGateway.clientOrders
+	WHERE id = $1
+	`, id)
+	if err != nil {
+		p.logger.Error("failed to delete client order", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (p *Postgres) GetClientOrder(userID int, clientOrderID string) (models.ClientOrder, error) {
+	return p.scanClientOrder(p.db.QueryRow(context.Background(), `
+	SELECT id, userID, COALESCE(clientOrderID, ''), COALESCE(idempotencyKey, ''), COALESCE(orderID, 0), response, createdAt
+	FROM apiGateway.clientOrders
+	WHERE userID = $1 AND clientOrderID = $2
+	`, userID, clientOrderID))
+}
+
+func (p *Postgres) GetClientOrderByOrderID(orderID int) (models.ClientOrder, error) {
+	return p.scanClientOrder(p.db.QueryRow(context.Background(), `
+	SELECT id, userID, COALESCE(clientOrderID, ''), COALESCE(idempotencyKey, ''), COALESCE(orderID, 0), response, createdAt
+	FROM apiGateway.clientOrders
+	WHERE orderID = $1
+	`, orderID))
+}
+
+func (p *Postgres) scanClientOrder(row pgx.Row) (models.ClientOrder, error) {
+	var clientOrder models.ClientOrder
+	err := row.Scan(
+		&clientOrder.ID,
+		&clientOrder.UserID,
+		&clientOrder.ClientOrderID,
+		&clientOrder.IdempotencyKey,
+		&clientOrder.OrderID,
+		&clientOrder.Response,
+		&clientOrder.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ClientOrder{}, repository.ErrNotFound
+		}
+		p.logger.Error("failed to scan client order", "error", err)
+		return models.ClientOrder{}, err
+	}
+	return clientOrder, nil
+}
@@ -0,0 +1,150 @@
+package postgresPgx
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+const conditionalOrderColumns = `id, userID, isBid, pair, type, price, qty, stopPrice, trailingDelta, extremePrice, status, orderID, error, createdAt, triggeredAt`
+
+func (p *Postgres) CreateConditionalOrder(conditionalOrder models.ConditionalOrder) (models.ConditionalOrder, error) {
+	return p.scanConditionalOrder(p.db.QueryRow(context.Background(), `
+	INSERT INTO apiGateway.conditionalOrders (userID, isBid, pair, type, price, qty, stopPrice, trailingDelta, extremePrice, status)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	RETURNING `+conditionalOrderColumns,
+		conditionalOrder.UserID,
+		conditionalOrder.IsBid,
+		conditionalOrder.Pair,
+		conditionalOrder.Type,
+		conditionalOrder.Price,
+		conditionalOrder.Qty,
+		conditionalOrder.StopPrice,
+		conditionalOrder.TrailingDelta,
+		conditionalOrder.ExtremePrice,
+		conditionalOrder.Status,
+	))
+}
+
+func (p *Postgres) GetConditionalOrder(id int) (models.ConditionalOrder, error) {
+	return p.scanConditionalOrder(p.db.QueryRow(context.Background(), `
+	SELECT `+conditionalOrderColumns+`
+	FROM apiGateway.conditionalOrders
+	WHERE id = $1
+	`, id))
+}
+
+func (p *Postgres) GetConditionalOrdersByUser(userID int) ([]models.ConditionalOrder, error) {
+	return p.queryConditionalOrders(`
+	SELECT `+conditionalOrderColumns+`
+	FROM apiGateway.conditionalOrders
+	WHERE userID = $1
+	ORDER BY id
+	`, userID)
+}
+
+func (p *Postgres) GetUntriggeredConditionalOrders() ([]models.ConditionalOrder, error) {
+	return p.queryConditionalOrders(`
+	SELECT ` + conditionalOrderColumns + `
+	FROM apiGateway.conditionalOrders
+	WHERE status = 'untriggered'
+	ORDER BY id
+	`)
+}
+
+func (p *Postgres) UpdateConditionalOrder(conditionalOrder models.ConditionalOrder) error {
+	var triggeredAt *time.Time
+	if !conditionalOrder.TriggeredAt.IsZero() {
+		triggeredAt = &conditionalOrder.TriggeredAt
+	}
+
+	_, err := p.db.Exec(context.Background(), `
+	UPDATE apiGateway.conditionalOrders
+	SET stopPrice = $2, extremePrice = $3, orderID = $4, error = $5, triggeredAt = $6
+	WHERE id = $1
+	`,
+		conditionalOrder.ID,
+		conditionalOrder.StopPrice,
+		conditionalOrder.ExtremePrice,
+		conditionalOrder.OrderID,
+		conditionalOrder.Error,
+		triggeredAt,
+	)
+	if err != nil {
+		p.logger.Error("failed to update conditional order", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (p *Postgres) TransitConditionalOrder(id int, from, to string) (bool, error) {
+	tag, err := p.db.Exec(context.Background(), `
+	UPDATE apiGateway.conditionalOrders
+	SET status = $3
+	WHERE id = $1 AND status = $2
+	`, id, from, to)
+	if err != nil {
+		p.logger.Error("failed to update conditional order status", "error", err)
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (p *Postgres) queryConditionalOrders(query string, args ...any) ([]models.ConditionalOrder, error) {
+	rows, err := p.db.Query(context.Background(), query, args...)
+	if err != nil {
+		p.logger.Error("failed to select conditional orders", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var conditionalOrders []models.ConditionalOrder
+	for rows.Next() {
+		conditionalOrder, err := p.scanConditionalOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		conditionalOrders = append(conditionalOrders, conditionalOrder)
+	}
+	return conditionalOrders, rows.Err()
+}
+
+func (p *Postgres) scanConditionalOrder(row pgx.Row) (models.ConditionalOrder, error) {
+	var (
+		conditionalOrder models.ConditionalOrder
+		triggeredAt      *time.Time
+	)
+	err := row.Scan(
+		&conditionalOrder.ID,
+		&conditionalOrder.UserID,
+		&conditionalOrder.IsBid,
+		&conditionalOrder.Pair,
+		&conditionalOrder.Type,
+		&conditionalOrder.Price,
+		&conditionalOrder.Qty,
+		&conditionalOrder.StopPrice,
+		&conditionalOrder.TrailingDelta,
+		&conditionalOrder.ExtremePrice,
+		&conditionalOrder.Status,
+		&conditionalOrder.OrderID,
+		&conditionalOrder.Error,
+		&conditionalOrder.CreatedAt,
+		&triggeredAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ConditionalOrder{}, repository.ErrNotFound
+		}
+		p.logger.Error("failed to scan conditional order", "error", err)
+		return models.ConditionalOrder{}, err
+	}
+
+	if triggeredAt != nil {
+		conditionalOrder.TriggeredAt = *triggeredAt
+	}
+	return conditionalOrder, nil
+}
@@ -0,0 +1,140 @@
+package postgresPgx
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/repository"
+)
+
+// tradesColumns are the columns of matchingEngine.trades GetExecutions reads. The table belongs to the matching
+// engine and its schema is not part of this repository, so the gateway expects one row per trade with
+//
+//	id BIGINT, pair TEXT, price TEXT, qty TEXT, isBid BOOLEAN (the side of the taker), createdAt TIMESTAMP,
+//	takerOrderID BIGINT, takerUserID BIGINT, makerOrderID BIGINT, makerUserID BIGINT
+//
+// and checks at start that the table has them. The names are lowercase like all unquoted identifiers.
+var tradesColumns = []string{"id", "pair", "price", "qty", "isbid", "createdat", "takerorderid", "takeruserid", "makerorderid", "makeruserid"}
+
+// checkTradesColumns reports whether matchingEngine.trades has every column of tradesColumns,
+// the missing ones are logged
+func (p *Postgres) checkTradesColumns() bool {
+	rows, err := p.db.Query(context.Background(), `
+	SELECT column_name
+	FROM information_schema.columns
+	WHERE table_schema = 'matchingengine' AND table_name = 'trades'
+	`)
+	if err != nil {
+		p.logger.Error("failed to select columns of matching engine trades", "error", err)
+		return false
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			p.logger.Error("failed to scan column of matching engine trades", "error", err)
+			return false
+		}
+		columns = append(columns, column)
+	}
+	if err := rows.Err(); err != nil {
+		p.logger.Error("failed to select columns of matching engine trades", "error", err)
+		return false
+	}
+
+	var missing []string
+	for _, column := range tradesColumns {
+		if !slices.Contains(columns, column) {
+			missing = append(missing, column)
+		}
+	}
+	if len(missing) > 0 {
+		p.logger.Error("matching engine trades lack the columns of the trade history, it is disabled", "missing", missing)
+		return false
+	}
+	return true
+}
+
+// GetExecutions reads the trades of the matching engine, every trade is an execution
+// of the taker order and of the maker order. It returns repository.ErrUnavailable
+// if matchingEngine.trades does not have tradesColumns.
+func (p *Postgres) GetExecutions(filter models.ExecutionFilter) ([]models.Execution, error) {
+	if !p.tradesAvailable {
+		return nil, repository.ErrUnavailable
+	}
+
+	var (
+		conditions []string
+		args       []any
+	)
+	where := func(format string, values ...any) {
+		for _, value := range values {
+			args = append(args, value)
+			format = strings.Replace(format, "?", "$"+strconv.Itoa(len(args)), 1)
+		}
+		conditions = append(conditions, format)
+	}
+
+	where("userID = ?", filter.UserID)
+	if filter.Pair != "" {
+		where("pair = ?", filter.Pair)
+	}
+	if !filter.From.IsZero() {
+		where("createdAt >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		where("createdAt < ?", filter.To)
+	}
+	if filter.After != nil {
+		where("(createdAt, id, role) < (?, ?, ?)", filter.After.Time, filter.After.TradeID, filter.After.Role)
+	}
+
+	query := `
+	SELECT id, orderID, pair, isBid, price, qty, role, createdAt
+	FROM (
+		SELECT id, takerOrderID AS orderID, takerUserID AS userID, pair, isBid, price, qty, 'taker' AS role, createdAt
+		FROM matchingEngine.trades
+		UNION ALL
+		SELECT id, makerOrderID, makerUserID, pair, NOT isBid, price, qty, 'maker', createdAt
+		FROM matchingEngine.trades
+	) executions
+	WHERE ` + strings.Join(conditions, " AND ") + `
+	ORDER BY createdAt DESC, id DESC, role DESC`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += ` LIMIT $` + strconv.Itoa(len(args))
+	}
+
+	rows, err := p.db.Query(context.Background(), query, args...)
+	if err != nil {
+		p.logger.Error("failed to select executions", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var executions []models.Execution
+	for rows.Next() {
+		var execution models.Execution
+		err := rows.Scan(
+			&execution.TradeID,
+			&execution.OrderID,
+			&execution.Pair,
+			&execution.IsBid,
+			&execution.Price,
+			&execution.Qty,
+			&execution.Role,
+			&execution.Time,
+		)
+		if err != nil {
+			p.logger.Error("failed to scan execution", "error", err)
+			return nil, err
+		}
+		executions = append(executions, execution)
+	}
+	return executions, rows.Err()
+}
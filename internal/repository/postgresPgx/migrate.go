@@ -0,0 +1,136 @@
+package postgresPgx
+
+import "context"
+
+// migrations create the tables owned by the gateway, every statement must be safe to run on each start
+var migrations = []string{
+	`CREATE SCHEMA IF NOT EXISTS apiGateway`,
+	`CREATE TABLE IF NOT EXISTS apiGateway.clientOrders (
+		id             BIGSERIAL PRIMARY KEY,
+		userID         BIGINT NOT NULL,
+		clientOrderID  TEXT,
+		idempotencyKey TEXT,
+		orderID        BIGINT,
+		response       JSONB,
+		createdAt      TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS clientOrders_userID_clientOrderID_idx ON apiGateway.clientOrders (userID, clientOrderID)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS clientOrders_userID_idempotencyKey_idx ON apiGateway.clientOrders (userID, idempotencyKey)`,
+	`CREATE INDEX IF NOT EXISTS clientOrders_orderID_idx ON apiGateway.clientOrders (orderID)`,
+	`CREATE TABLE IF NOT EXISTS apiGateway.pairLimits (
+		pair        TEXT PRIMARY KEY,
+		minQty      TEXT NOT NULL DEFAULT '',
+		maxQty      TEXT NOT NULL DEFAULT '',
+		minNotional TEXT NOT NULL DEFAULT '',
+		maxNotional TEXT NOT NULL DEFAULT ''
+	)`,
+	`CREATE TABLE IF NOT EXISTS apiGateway.conditionalOrders (
+		id            BIGSERIAL PRIMARY KEY,
+		userID        BIGINT NOT NULL,
+		isBid         BOOLEAN NOT NULL,
+		pair          TEXT NOT NULL,
+		type          TEXT NOT NULL,
+		price         TEXT NOT NULL DEFAULT '',
+		qty           TEXT NOT NULL,
+		stopPrice     TEXT NOT NULL DEFAULT '',
+		trailingDelta TEXT NOT NULL DEFAULT '',
+		extremePrice  TEXT NOT NULL DEFAULT '',
+		status        TEXT NOT NULL,
+		orderID       BIGINT NOT NULL DEFAULT 0,
+		error         TEXT NOT NULL DEFAULT '',
+		createdAt     TIMESTAMPTZ NOT NULL DEFAULT now(),
+		triggeredAt   TIMESTAMPTZ
+	)`,
+	`CREATE INDEX IF NOT EXISTS conditionalOrders_userID_idx ON apiGateway.conditionalOrders (userID)`,
+	`CREATE INDEX IF NOT EXISTS conditionalOrders_status_idx ON apiGateway.conditionalOrders (status)`,
+	`CREATE TABLE IF NOT EXISTS apiGateway.scheduledJobs (
+		id          BIGSERIAL PRIMARY KEY,
+		kind        TEXT NOT NULL,
+		userID      BIGINT NOT NULL,
+		orderID     BIGINT NOT NULL DEFAULT 0,
+		request     JSONB,
+		runAt       TIMESTAMPTZ NOT NULL,
+		lockedUntil TIMESTAMPTZ,
+		status      TEXT NOT NULL DEFAULT 'pending',
+		error       TEXT NOT NULL DEFAULT '',
+		createdAt   TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`,
+	`CREATE INDEX IF NOT EXISTS scheduledJobs_status_runAt_idx ON apiGateway.scheduledJobs (status, runAt)`,
+	`CREATE INDEX IF NOT EXISTS scheduledJobs_userID_idx ON apiGateway.scheduledJobs (userID)`,
+	`CREATE TABLE IF NOT EXISTS apiGateway.algoOrders (
+		id            BIGSERIAL PRIMARY KEY,
+		userID        BIGINT NOT NULL,
+		isBid         BOOLEAN NOT NULL,
+		pair          TEXT NOT NULL,
+		type          TEXT NOT NULL,
+		price         TEXT NOT NULL DEFAULT '',
+		qty           TEXT NOT NULL,
+		duration      BIGINT NOT NULL,
+		sliceInterval BIGINT NOT NULL,
+		slicesPlaced  INTEGER NOT NULL DEFAULT 0,
+		sizeFilled    TEXT NOT NULL DEFAULT '0',
+		status        TEXT NOT NULL,
+		error         TEXT NOT NULL DEFAULT '',
+		nextSliceAt   TIMESTAMPTZ NOT NULL,
+		endAt         TIMESTAMPTZ NOT NULL,
+		pausedAt      TIMESTAMPTZ,
+		lockedUntil   TIMESTAMPTZ,
+		createdAt     TIMESTAMPTZ NOT NULL DEFAULT now(),
+		closedAt      TIMESTAMPTZ
+	)`,
+	`CREATE INDEX IF NOT EXISTS algoOrders_userID_idx ON apiGateway.algoOrders (userID)`,
+	`CREATE INDEX IF NOT EXISTS algoOrders_status_nextSliceAt_idx ON apiGateway.algoOrders (status, nextSliceAt)`,
+	`CREATE TABLE IF NOT EXISTS apiGateway.algoOrderChildren (
+		orderID     BIGINT PRIMARY KEY,
+		algoOrderID BIGINT NOT NULL REFERENCES apiGateway.algoOrders (id)
+	)`,
+	`CREATE INDEX IF NOT EXISTS algoOrderChildren_algoOrderID_idx ON apiGateway.algoOrderChildren (algoOrderID)`,
+	`ALTER TABLE apiGateway.algoOrders ADD COLUMN IF NOT EXISTS visibleQty TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE apiGateway.algoOrders ALTER COLUMN endAt DROP NOT NULL`,
+	`CREATE TABLE IF NOT EXISTS apiGateway.orderGroups (
+		id              BIGSERIAL PRIMARY KEY,
+		userID          BIGINT NOT NULL,
+		type            TEXT NOT NULL,
+		pair            TEXT NOT NULL,
+		isBid           BOOLEAN NOT NULL,
+		qty             TEXT NOT NULL,
+		entryPrice      TEXT NOT NULL DEFAULT '',
+		takeProfitPrice TEXT NOT NULL,
+		stopPrice       TEXT NOT NULL,
+		stopLimitPrice  TEXT NOT NULL DEFAULT '',
+		status          TEXT NOT NULL,
+		error           TEXT NOT NULL DEFAULT '',
+		createdAt       TIMESTAMPTZ NOT NULL DEFAULT now(),
+		closedAt        TIMESTAMPTZ
+	)`,
+	`CREATE INDEX IF NOT EXISTS orderGroups_userID_idx ON apiGateway.orderGroups (userID)`,
+	`CREATE INDEX IF NOT EXISTS orderGroups_status_idx ON apiGateway.orderGroups (status)`,
+	`CREATE TABLE IF NOT EXISTS apiGateway.orderGroupLegs (
+		id                 BIGSERIAL PRIMARY KEY,
+		orderGroupID       BIGINT NOT NULL REFERENCES apiGateway.orderGroups (id),
+		role               TEXT NOT NULL,
+		orderID            BIGINT NOT NULL DEFAULT 0,
+		conditionalOrderID BIGINT NOT NULL DEFAULT 0
+	)`,
+	`CREATE INDEX IF NOT EXISTS orderGroupLegs_orderGroupID_idx ON apiGateway.orderGroupLegs (orderGroupID)`,
+	`CREATE TABLE IF NOT EXISTS apiGateway.riskLimits (
+		pair            TEXT PRIMARY KEY,
+		priceBand       TEXT NOT NULL DEFAULT '',
+		maxQty          TEXT NOT NULL DEFAULT '',
+		maxNotional     TEXT NOT NULL DEFAULT '',
+		maxUserExposure TEXT NOT NULL DEFAULT ''
+	)`,
+	`CREATE TABLE IF NOT EXISTS apiGateway.postOnlyOrders (
+		orderID BIGINT PRIMARY KEY
+	)`,
+}
+
+func (p *Postgres) migrate() error {
+	for _, migration := range migrations {
+		if _, err := p.db.Exec(context.Background(), migration); err != nil {
+			p.logger.Error("failed to run migration", "migration", migration, "error", err)
+			return err
+		}
+	}
+	return nil
+}
@@ -1,81 +1,171 @@
-package postgresPgx
-
-import (
-	"context"
-
-	"github.com/BazaarTrade/ApiGatewayService/internal/models"
-)
-
-func (p *Postgres) GetClosedOrdersByUser(userID int) ([]models.Order, error) {
-	rows, err := p.db.Query(context.Background(), `
-	SELECT id, userID, isBid, pair, price, qty, sizeFilled, status, type, createdAt, closedAt
-	FROM matchingEngine.orders
-	WHERE userID = $1
-	`, userID)
-	if err != nil {
-		p.logger.Error("failed to select orders", "error", err)
-		return nil, err
-	}
-	defer rows.Close()
-
-	var orders []models.Order
-	for rows.Next() {
-		var order models.Order
-		err := rows.Scan(
-			&order.ID,
-			&order.UserID,
-			&order.IsBid,
-			&order.Pair,
-			&order.Price,
-			&order.Qty,
-			&order.SizeFilled,
-			&order.Status,
-			&order.Type,
-			&order.CreatedAt,
-			&order.ClosedAt,
-		)
-		if err != nil {
-			p.logger.Error("failed to scan order", "error", err)
-			return nil, err
-		}
-		orders = append(orders, order)
-	}
-	return orders, nil
-}
-
-func (p *Postgres) GetNotFilledOrdersByUser(userID int) ([]models.Order, error) {
-	rows, err := p.db.Query(context.Background(), `
-	SELECT id, userID, isBid, pair, price, qty, sizeFilled, status, type, createdAt, closedAt
-	FROM matchingEngine.orders
-	WHERE userID = $1 AND status IN ('filling', 'filled', 'canceled')
-	`, userID)
-	if err != nil {
-		p.logger.Error("failed to select orders", "error", err)
-		return nil, err
-	}
-	defer rows.Close()
-
-	var orders []models.Order
-	for rows.Next() {
-		var order models.Order
-		err := rows.Scan(
-			&order.ID,
-			&order.UserID,
-			&order.IsBid,
-			&order.Pair,
-			&order.Price,
-			&order.Qty,
-			&order.SizeFilled,
-			&order.Status,
-			&order.Type,
-			&order.CreatedAt,
-			&order.ClosedAt,
-		)
-		if err != nil {
-			p.logger.Error("failed to scan order", "error", err)
-			return nil, err
-		}
-		orders = append(orders, order)
-	}
-	return orders, nil
-}
+package postgresPgx
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+func (p *Postgres) GetOpenOrders(filter models.OrderFilter) ([]models.Order, error) {
+	return p.queryOrders(`status = 'filling'`, filter)
+}
+
+func (p *Postgres) GetClosedOrders(filter models.OrderFilter) ([]models.Order, error) {
+	return p.queryOrders(`status <> 'filling'`, filter)
+}
+
+func (p *Postgres) CountOpenOrders(userID int, pair string, exceptOrderID int) (map[string]int, error) {
+	rows, err := p.db.Query(context.Background(), `
+	SELECT pair, count(*)
+	FROM (
+		SELECT o.pair
+		FROM matchingEngine.orders o
+		WHERE o.userID = $1 AND o.status = 'filling' AND o.id <> $3
+			AND NOT EXISTS (SELECT 1 FROM apiGateway.algoOrderChildren c WHERE c.orderID = o.id)
+		UNION ALL
+		SELECT pair
+		FROM apiGateway.conditionalOrders
+		WHERE userID = $1 AND status = 'untriggered'
+		UNION ALL
+		SELECT request->>'pair'
+		FROM apiGateway.scheduledJobs
+		WHERE userID = $1 AND kind = 'place' AND status = 'pending'
+		UNION ALL
+		SELECT pair
+		FROM apiGateway.algoOrders
+		WHERE userID = $1 AND status IN ('running', 'paused')
+	) openOrders
+	WHERE $2 = '' OR pair = $2
+	GROUP BY pair
+	`, userID, pair, exceptOrderID)
+	if err != nil {
+		p.logger.Error("failed to count open orders", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var byPair = make(map[string]int)
+	for rows.Next() {
+		var (
+			orderPair string
+			count     int
+		)
+		if err := rows.Scan(&orderPair, &count); err != nil {
+			p.logger.Error("failed to scan open orders count", "error", err)
+			return nil, err
+		}
+		byPair[orderPair] = count
+	}
+	return byPair, rows.Err()
+}
+
+// queryOrders selects the orders matching the filter from the newest, condition is a fixed SQL condition
+func (p *Postgres) queryOrders(condition string, filter models.OrderFilter) ([]models.Order, error) {
+	var (
+		conditions = []string{condition}
+		args       []any
+	)
+	where := func(format string, values ...any) {
+		for _, value := range values {
+			args = append(args, value)
+			format = strings.Replace(format, "?", "$"+strconv.Itoa(len(args)), 1)
+		}
+		conditions = append(conditions, format)
+	}
+
+	where("userID = ?", filter.UserID)
+	if filter.Pair != "" {
+		where("pair = ?", filter.Pair)
+	}
+	if filter.Side != "" {
+		where("isBid = ?", filter.Side == "buy")
+	}
+	if filter.Type != "" {
+		where("type = ?", filter.Type)
+	}
+	if filter.Status != "" {
+		where("status = ?", filter.Status)
+	}
+	if !filter.From.IsZero() {
+		where("createdAt >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		where("createdAt < ?", filter.To)
+	}
+	if filter.After != nil {
+		where("(createdAt, id) < (?, ?)", filter.After.CreatedAt, filter.After.ID)
+	}
+
+	query := `
+	SELECT id, userID, isBid, pair, price, qty, sizeFilled, status, type, createdAt, closedAt
+	FROM matchingEngine.orders
+	WHERE ` + strings.Join(conditions, " AND ") + `
+	ORDER BY createdAt DESC, id DESC`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += ` LIMIT $` + strconv.Itoa(len(args))
+	}
+
+	rows, err := p.db.Query(context.Background(), query, args...)
+	if err != nil {
+		p.logger.Error("failed to select orders", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []models.Order
+	for rows.Next() {
+		order, err := p.scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
+
+func (p *Postgres) GetOrderByID(orderID int) (models.Order, error) {
+	return p.scanOrder(p.db.QueryRow(context.Background(), `
+	SELECT id, userID, isBid, pair, price, qty, sizeFilled, status, type, createdAt, closedAt
+	FROM matchingEngine.orders
+	WHERE id = $1
+	`, orderID))
+}
+
+func (p *Postgres) GetUserOrder(userID, orderID int) (models.Order, error) {
+	return p.scanOrder(p.db.QueryRow(context.Background(), `
+	SELECT id, userID, isBid, pair, price, qty, sizeFilled, status, type, createdAt, closedAt
+	FROM matchingEngine.orders
+	WHERE id = $1 AND userID = $2
+	`, orderID, userID))
+}
+
+func (p *Postgres) scanOrder(row pgx.Row) (models.Order, error) {
+	var order models.Order
+	err := row.Scan(
+		&order.ID,
+		&order.UserID,
+		&order.IsBid,
+		&order.Pair,
+		&order.Price,
+		&order.Qty,
+		&order.SizeFilled,
+		&order.Status,
+		&order.Type,
+		&order.CreatedAt,
+		&order.ClosedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Order{}, repository.ErrNotFound
+		}
+		p.logger.Error("failed to scan order", "error", err)
+		return models.Order{}, err
+	}
+	return order, nil
+}
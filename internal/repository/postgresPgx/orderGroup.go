@@ -0,0 +1,167 @@
+package postgresPgx
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+const orderGroupColumns = `id, userID, type, pair, isBid, qty, entryPrice, takeProfitPrice, stopPrice, stopLimitPrice, status, error, createdAt, closedAt`
+
+func (p *Postgres) CreateOrderGroup(orderGroup models.OrderGroup) (models.OrderGroup, error) {
+	return p.scanOrderGroup(p.db.QueryRow(context.Background(), `
+	INSERT INTO apiGateway.orderGroups (userID, type, pair, isBid, qty, entryPrice, takeProfitPrice, stopPrice, stopLimitPrice, status)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	RETURNING `+orderGroupColumns,
+		orderGroup.UserID,
+		orderGroup.Type,
+		orderGroup.Pair,
+		orderGroup.IsBid,
+		orderGroup.Qty,
+		orderGroup.EntryPrice,
+		orderGroup.TakeProfitPrice,
+		orderGroup.StopPrice,
+		orderGroup.StopLimitPrice,
+		orderGroup.Status,
+	))
+}
+
+func (p *Postgres) GetOrderGroup(id int) (models.OrderGroup, error) {
+	return p.scanOrderGroup(p.db.QueryRow(context.Background(), `
+	SELECT `+orderGroupColumns+`
+	FROM apiGateway.orderGroups
+	WHERE id = $1
+	`, id))
+}
+
+func (p *Postgres) GetOrderGroupsByUser(userID int) ([]models.OrderGroup, error) {
+	return p.queryOrderGroups(`
+	SELECT `+orderGroupColumns+`
+	FROM apiGateway.orderGroups
+	WHERE userID = $1
+	ORDER BY id
+	`, userID)
+}
+
+func (p *Postgres) GetOpenOrderGroups() ([]models.OrderGroup, error) {
+	return p.queryOrderGroups(`
+	SELECT ` + orderGroupColumns + `
+	FROM apiGateway.orderGroups
+	WHERE status IN ('pending', 'active')
+	ORDER BY id
+	`)
+}
+
+func (p *Postgres) UpdateOrderGroup(orderGroup models.OrderGroup, status string) (bool, error) {
+	tag, err := p.db.Exec(context.Background(), `
+	UPDATE apiGateway.orderGroups
+	SET status = $3, error = $4, closedAt = $5
+	WHERE id = $1 AND status = $2
+	`,
+		orderGroup.ID,
+		status,
+		orderGroup.Status,
+		orderGroup.Error,
+		nullTime(orderGroup.ClosedAt),
+	)
+	if err != nil {
+		p.logger.Error("failed to update order group", "error", err)
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (p *Postgres) AddOrderGroupLeg(leg models.OrderGroupLeg) error {
+	_, err := p.db.Exec(context.Background(), `
+	INSERT INTO apiGateway.orderGroupLegs (orderGroupID, role, orderID, conditionalOrderID)
+	VALUES ($1, $2, $3, $4)
+	`, leg.OrderGroupID, leg.Role, leg.OrderID, leg.ConditionalOrderID)
+	if err != nil {
+		p.logger.Error("failed to insert order group leg", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (p *Postgres) GetOrderGroupLegs(orderGroupID int) ([]models.OrderGroupLeg, error) {
+	rows, err := p.db.Query(context.Background(), `
+	SELECT orderGroupID, role, orderID, conditionalOrderID
+	FROM apiGateway.orderGroupLegs
+	WHERE orderGroupID = $1
+	ORDER BY id
+	`, orderGroupID)
+	if err != nil {
+		p.logger.Error("failed to select order group legs", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var legs []models.OrderGroupLeg
+	for rows.Next() {
+		var leg models.OrderGroupLeg
+		if err := rows.Scan(&leg.OrderGroupID, &leg.Role, &leg.OrderID, &leg.ConditionalOrderID); err != nil {
+			p.logger.Error("failed to scan order group leg", "error", err)
+			return nil, err
+		}
+		legs = append(legs, leg)
+	}
+	return legs, rows.Err()
+}
+
+func (p *Postgres) queryOrderGroups(query string, args ...any) ([]models.OrderGroup, error) {
+	rows, err := p.db.Query(context.Background(), query, args...)
+	if err != nil {
+		p.logger.Error("failed to select order groups", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orderGroups []models.OrderGroup
+	for rows.Next() {
+		orderGroup, err := p.scanOrderGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		orderGroups = append(orderGroups, orderGroup)
+	}
+	return orderGroups, rows.Err()
+}
+
+func (p *Postgres) scanOrderGroup(row pgx.Row) (models.OrderGroup, error) {
+	var (
+		orderGroup models.OrderGroup
+		closedAt   *time.Time
+	)
+	err := row.Scan(
+		&orderGroup.ID,
+		&orderGroup.UserID,
+		&orderGroup.Type,
+		&orderGroup.Pair,
+		&orderGroup.IsBid,
+		&orderGroup.Qty,
+		&orderGroup.EntryPrice,
+		&orderGroup.TakeProfitPrice,
+		&orderGroup.StopPrice,
+		&orderGroup.StopLimitPrice,
+		&orderGroup.Status,
+		&orderGroup.Error,
+		&orderGroup.CreatedAt,
+		&closedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.OrderGroup{}, repository.ErrNotFound
+		}
+		p.logger.Error("failed to scan order group", "error", err)
+		return models.OrderGroup{}, err
+	}
+
+	if closedAt != nil {
+		orderGroup.ClosedAt = *closedAt
+	}
+	return orderGroup, nil
+}
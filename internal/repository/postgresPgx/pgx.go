@@ -1,30 +1,40 @@
-package postgresPgx
-
-import (
-	"context"
-	"log/slog"
-
-	"github.com/jackc/pgx/v5/pgxpool"
-)
-
-type Postgres struct {
-	db     *pgxpool.Pool
-	logger *slog.Logger
-}
-
-func NewPostgres(DB_CONNECTION string, logger *slog.Logger) (*Postgres, error) {
-	conn, err := pgxpool.New(context.Background(), DB_CONNECTION)
-	if err != nil {
-		logger.Error("failed to create pgxPool connection", "error", err)
-		return nil, err
-	}
-
-	return &Postgres{
-		db:     conn,
-		logger: logger,
-	}, nil
-}
-
-func (p *Postgres) Close() {
-	p.db.Close()
-}
+package postgresPgx
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Postgres struct {
+	db *pgxpool.Pool
+	// tradesAvailable is set if matchingEngine.trades has the columns GetExecutions reads
+	tradesAvailable bool
+	logger          *slog.Logger
+}
+
+func NewPostgres(DB_CONNECTION string, logger *slog.Logger) (*Postgres, error) {
+	conn, err := pgxpool.New(context.Background(), DB_CONNECTION)
+	if err != nil {
+		logger.Error("failed to create pgxPool connection", "error", err)
+		return nil, err
+	}
+
+	p := &Postgres{
+		db:     conn,
+		logger: logger,
+	}
+
+	if err := p.migrate(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	p.tradesAvailable = p.checkTradesColumns()
+	return p, nil
+}
+
+func (p *Postgres) Close() {
+	p.db.Close()
+}
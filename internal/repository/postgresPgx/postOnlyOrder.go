@@ -0,0 +1,37 @@
+package postgresPgx
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (p *Postgres) AddPostOnlyOrder(orderID int) error {
+	_, err := p.db.Exec(context.Background(), `
+	INSERT INTO apiGateway.postOnlyOrders (orderID)
+	VALUES ($1)
+	ON CONFLICT DO NOTHING
+	`, orderID)
+	if err != nil {
+		p.logger.Error("failed to insert post-only order", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (p *Postgres) IsPostOnlyOrder(orderID int) (bool, error) {
+	err := p.db.QueryRow(context.Background(), `
+	SELECT orderID
+	FROM apiGateway.postOnlyOrders
+	WHERE orderID = $1
+	`, orderID).Scan(&orderID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		p.logger.Error("failed to select post-only order", "error", err)
+		return false, err
+	}
+	return true, nil
+}
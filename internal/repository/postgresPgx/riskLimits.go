@@ -0,0 +1,64 @@
+package postgresPgx
+
+import (
+	"context"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/repository"
+)
+
+func (p *Postgres) GetRiskLimits() (map[string]models.RiskLimits, error) {
+	rows, err := p.db.Query(context.Background(), `
+	SELECT pair, priceBand, maxQty, maxNotional, maxUserExposure
+	FROM apiGateway.riskLimits
+	`)
+	if err != nil {
+		p.logger.Error("failed to select risk limits", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var riskLimitsByPair = make(map[string]models.RiskLimits)
+	for rows.Next() {
+		var (
+			pair       string
+			riskLimits models.RiskLimits
+		)
+		if err := rows.Scan(&pair, &riskLimits.PriceBand, &riskLimits.MaxQty, &riskLimits.MaxNotional, &riskLimits.MaxUserExposure); err != nil {
+			p.logger.Error("failed to scan risk limits", "error", err)
+			return nil, err
+		}
+		riskLimitsByPair[pair] = riskLimits
+	}
+	return riskLimitsByPair, rows.Err()
+}
+
+func (p *Postgres) SaveRiskLimits(pair string, riskLimits models.RiskLimits) error {
+	_, err := p.db.Exec(context.Background(), `
+	INSERT INTO apiGateway.riskLimits
+	(pair, priceBand, maxQty, maxNotional, maxUserExposure)
+	VALUES($1, $2, $3, $4, $5)
+	ON CONFLICT (pair) DO UPDATE
+	SET priceBand = $2, maxQty = $3, maxNotional = $4, maxUserExposure = $5
+	`, pair, riskLimits.PriceBand, riskLimits.MaxQty, riskLimits.MaxNotional, riskLimits.MaxUserExposure)
+	if err != nil {
+		p.logger.Error("failed to save risk limits", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (p *Postgres) DeleteRiskLimits(pair string) error {
+	tag, err := p.db.Exec(context.Background(), `
+	DELETE FROM apiGateway.riskLimits
+	WHERE pair = $1
+	`, pair)
+	if err != nil {
+		p.logger.Error("failed to delete risk limits", "error", err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
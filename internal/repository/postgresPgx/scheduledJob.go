@@ -0,0 +1,139 @@
+package postgresPgx
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+const scheduledJobColumns = `id, kind, userID, orderID, request, runAt, lockedUntil, status, error, createdAt`
+
+func (p *Postgres) CreateScheduledJob(job models.ScheduledJob) (models.ScheduledJob, error) {
+	var request []byte
+	if len(job.Request) > 0 {
+		request = job.Request
+	}
+
+	return p.scanScheduledJob(p.db.QueryRow(context.Background(), `
+	INSERT INTO apiGateway.scheduledJobs (kind, userID, orderID, request, runAt)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING `+scheduledJobColumns,
+		job.Kind,
+		job.UserID,
+		job.OrderID,
+		request,
+		job.RunAt,
+	))
+}
+
+func (p *Postgres) GetScheduledJob(id int) (models.ScheduledJob, error) {
+	return p.scanScheduledJob(p.db.QueryRow(context.Background(), `
+	SELECT `+scheduledJobColumns+`
+	FROM apiGateway.scheduledJobs
+	WHERE id = $1
+	`, id))
+}
+
+func (p *Postgres) GetPendingScheduledJobsByUser(userID int, kind string) ([]models.ScheduledJob, error) {
+	return p.queryScheduledJobs(`
+	SELECT `+scheduledJobColumns+`
+	FROM apiGateway.scheduledJobs
+	WHERE userID = $1 AND kind = $2 AND status = 'pending'
+	ORDER BY runAt
+	`, userID, kind)
+}
+
+func (p *Postgres) ClaimDueScheduledJobs(now, lockedUntil time.Time, limit int) ([]models.ScheduledJob, error) {
+	return p.queryScheduledJobs(`
+	UPDATE apiGateway.scheduledJobs
+	SET lockedUntil = $2
+	WHERE id IN (
+		SELECT id
+		FROM apiGateway.scheduledJobs
+		WHERE status = 'pending' AND runAt <= $1 AND (lockedUntil IS NULL OR lockedUntil <= $1)
+		ORDER BY runAt
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING `+scheduledJobColumns, now, lockedUntil, limit)
+}
+
+func (p *Postgres) FinishScheduledJob(job models.ScheduledJob) (bool, error) {
+	tag, err := p.db.Exec(context.Background(), `
+	UPDATE apiGateway.scheduledJobs
+	SET status = $2, error = $3, orderID = $4, lockedUntil = NULL
+	WHERE id = $1 AND lockedUntil = $5
+	`, job.ID, job.Status, job.Error, job.OrderID, job.LockedUntil)
+	if err != nil {
+		p.logger.Error("failed to finish scheduled job", "error", err)
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (p *Postgres) CancelScheduledJob(id int) (bool, error) {
+	tag, err := p.db.Exec(context.Background(), `
+	UPDATE apiGateway.scheduledJobs
+	SET status = 'canceled'
+	WHERE id = $1 AND status = 'pending' AND (lockedUntil IS NULL OR lockedUntil <= now())
+	`, id)
+	if err != nil {
+		p.logger.Error("failed to cancel scheduled job", "error", err)
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (p *Postgres) queryScheduledJobs(query string, args ...any) ([]models.ScheduledJob, error) {
+	rows, err := p.db.Query(context.Background(), query, args...)
+	if err != nil {
+		p.logger.Error("failed to select scheduled jobs", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []models.ScheduledJob
+	for rows.Next() {
+		job, err := p.scanScheduledJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+func (p *Postgres) scanScheduledJob(row pgx.Row) (models.ScheduledJob, error) {
+	var (
+		job         models.ScheduledJob
+		lockedUntil *time.Time
+	)
+	err := row.Scan(
+		&job.ID,
+		&job.Kind,
+		&job.UserID,
+		&job.OrderID,
+		&job.Request,
+		&job.RunAt,
+		&lockedUntil,
+		&job.Status,
+		&job.Error,
+		&job.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ScheduledJob{}, repository.ErrNotFound
+		}
+		p.logger.Error("failed to scan scheduled job", "error", err)
+		return models.ScheduledJob{}, err
+	}
+
+	if lockedUntil != nil {
+		job.LockedUntil = *lockedUntil
+	}
+	return job, nil
+}
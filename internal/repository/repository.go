@@ -1,14 +1,111 @@
-package repository
-
-import "github.com/BazaarTrade/ApiGatewayService/internal/models"
-
-type Repository interface {
-	GetPairsOrderBookPricePrecisions() (map[string][]int32, error)
-	GetOrderBookPricePrecisions(string) ([]int32, error)
-	GetCandleStickTimeframes(string) ([]string, error)
-	GetNotFilledOrdersByUser(int) ([]models.Order, error)
-	GetCandleStickHistory(models.CandleStickHistoryRequest) ([]models.CandleStick, error)
-	GetClosedOrdersByUser(int) ([]models.Order, error)
-	GetPairsParams() ([]models.PairParams, error)
-	CreatePair(models.PairParams) error
-}
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// ErrUnavailable is returned for data whose tables do not provide what the gateway expects
+var ErrUnavailable = errors.New("data unavailable")
+
+type Repository interface {
+	GetPairsOrderBookPricePrecisions() (map[string][]int32, error)
+	GetOrderBookPricePrecisions(string) ([]int32, error)
+	GetCandleStickTimeframes(string) ([]string, error)
+	// GetOpenOrders returns the orders that are still in the book, GetClosedOrders the rest
+	GetOpenOrders(models.OrderFilter) ([]models.Order, error)
+	GetClosedOrders(models.OrderFilter) ([]models.Order, error)
+	// CountOpenOrders counts the open orders of the user per pair, of one pair unless pair is empty: the orders in the book
+	// but exceptOrderID and the children of algo orders, untriggered conditional orders, pending scheduled orders
+	// and running or paused algo orders
+	CountOpenOrders(userID int, pair string, exceptOrderID int) (map[string]int, error)
+	GetCandleStickHistory(models.CandleStickHistoryRequest) ([]models.CandleStick, error)
+	GetOrderByID(int) (models.Order, error)
+	// GetUserOrder returns ErrNotFound for orders of other users
+	GetUserOrder(userID, orderID int) (models.Order, error)
+	// GetExecutions returns ErrUnavailable if the trades of the matching engine can not be read
+	GetExecutions(models.ExecutionFilter) ([]models.Execution, error)
+	GetPairsParams() ([]models.PairParams, error)
+	CreatePair(models.PairParams) error
+	NotifyPairsChanged() error
+	ListenPairsChanged(context.Context, func()) error
+
+	// ReserveClientOrder returns false and the existing request if the user already sent
+	// a request with the same client order ID or idempotency key.
+	ReserveClientOrder(models.ClientOrder) (models.ClientOrder, bool, error)
+	CompleteClientOrder(id, orderID int, response []byte) error
+	DeleteClientOrder(id int) error
+	GetClientOrder(userID int, clientOrderID string) (models.ClientOrder, error)
+	GetClientOrderByOrderID(orderID int) (models.ClientOrder, error)
+
+	// AddPostOnlyOrder marks a placed order as post-only, the matching engine does not store the flag
+	AddPostOnlyOrder(orderID int) error
+	IsPostOnlyOrder(orderID int) (bool, error)
+
+	CreateConditionalOrder(models.ConditionalOrder) (models.ConditionalOrder, error)
+	GetConditionalOrder(id int) (models.ConditionalOrder, error)
+	GetConditionalOrdersByUser(userID int) ([]models.ConditionalOrder, error)
+	GetUntriggeredConditionalOrders() ([]models.ConditionalOrder, error)
+	// UpdateConditionalOrder stores the stop, extreme price and trigger result, not the status
+	UpdateConditionalOrder(models.ConditionalOrder) error
+	// TransitConditionalOrder changes the status only if it is still from, so that
+	// an order is triggered or canceled once even with several gateways running.
+	TransitConditionalOrder(id int, from, to string) (bool, error)
+
+	CreateScheduledJob(models.ScheduledJob) (models.ScheduledJob, error)
+	GetScheduledJob(id int) (models.ScheduledJob, error)
+	GetPendingScheduledJobsByUser(userID int, kind string) ([]models.ScheduledJob, error)
+	// ClaimDueScheduledJobs locks up to limit pending jobs due at now until lockedUntil,
+	// jobs locked by another gateway are skipped
+	ClaimDueScheduledJobs(now, lockedUntil time.Time, limit int) ([]models.ScheduledJob, error)
+	// FinishScheduledJob stores the status, error and order ID of the job and releases its lock,
+	// it returns false if the lock of the job is no longer job.LockedUntil
+	FinishScheduledJob(models.ScheduledJob) (bool, error)
+	// CancelScheduledJob returns false if the job is not pending or currently claimed
+	CancelScheduledJob(id int) (bool, error)
+
+	CreateAlgoOrder(models.AlgoOrder) (models.AlgoOrder, error)
+	GetAlgoOrder(id int) (models.AlgoOrder, error)
+	GetAlgoOrdersByUser(userID int) ([]models.AlgoOrder, error)
+	// GetOpenAlgoOrders returns the running and paused algo orders
+	GetOpenAlgoOrders() ([]models.AlgoOrder, error)
+	// ClaimDueAlgoOrders locks up to limit running algo orders with a slice due at now until lockedUntil,
+	// orders locked by another gateway are skipped
+	ClaimDueAlgoOrders(now, lockedUntil time.Time, limit int) ([]models.AlgoOrder, error)
+	// ClaimAlgoOrder locks the running algo order until lockedUntil, it returns false if it is locked or not running
+	ClaimAlgoOrder(id int, now, lockedUntil time.Time) (models.AlgoOrder, bool, error)
+	// UpdateAlgoOrder stores the algo order and releases its lock only if its status is still status
+	UpdateAlgoOrder(algoOrder models.AlgoOrder, status string) (bool, error)
+	// UpdateClaimedAlgoOrder stores the algo order worked under the lock algoOrder.LockedUntil and releases it,
+	// it returns false if the algo order is no longer running or the lock expired and was taken
+	UpdateClaimedAlgoOrder(algoOrder models.AlgoOrder) (bool, error)
+	// UpdateAlgoOrderSizeFilled stores the filled qty without touching the lock
+	UpdateAlgoOrderSizeFilled(id int, sizeFilled string) error
+	AddAlgoOrderChild(algoOrderID, orderID int) error
+	// GetAlgoOrderChildren returns the child orders of the algo order from the oldest
+	GetAlgoOrderChildren(algoOrderID int) ([]models.Order, error)
+
+	CreateOrderGroup(models.OrderGroup) (models.OrderGroup, error)
+	GetOrderGroup(id int) (models.OrderGroup, error)
+	GetOrderGroupsByUser(userID int) ([]models.OrderGroup, error)
+	// GetOpenOrderGroups returns the pending and active order groups
+	GetOpenOrderGroups() ([]models.OrderGroup, error)
+	// UpdateOrderGroup stores the status, error and close time only if the status is still status,
+	// so that the legs of a group are placed or canceled once even with several gateways running
+	UpdateOrderGroup(orderGroup models.OrderGroup, status string) (bool, error)
+	AddOrderGroupLeg(models.OrderGroupLeg) error
+	// GetOrderGroupLegs returns the legs of the order group in the order they were placed
+	GetOrderGroupLegs(orderGroupID int) ([]models.OrderGroupLeg, error)
+
+	// GetRiskLimits returns the risk limits by pair
+	GetRiskLimits() (map[string]models.RiskLimits, error)
+	SaveRiskLimits(pair string, riskLimits models.RiskLimits) error
+	DeleteRiskLimits(pair string) error
+
+	Close()
+}
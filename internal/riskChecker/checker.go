@@ -0,0 +1,260 @@
+package riskChecker
+
+import (
+	"context"
+	"log/slog"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/decimal"
+	"github.com/BazaarTrade/ApiGatewayService/internal/marketData"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultPair holds the limits of the pairs without limits of their own
+const DefaultPair = "*"
+
+// syncInterval is how often limits edited through other gateways are picked up
+var syncInterval = 10 * time.Second
+
+// Rejection is an order beyond a risk limit. It carries a gRPC status, so that it is reported
+// like a rejection of the matching engine wherever the order came from.
+type Rejection struct {
+	Rule    string // priceBand, maxQty, maxNotional or maxUserExposure
+	Message string
+}
+
+func (r *Rejection) Error() string {
+	return r.Message
+}
+
+func (r *Rejection) GRPCStatus() *status.Status {
+	return status.New(codes.FailedPrecondition, r.Message)
+}
+
+// Checker rejects orders beyond the risk limits of their pair before they reach the matching engine.
+// Limits are stored in the repository and reloaded periodically, so edits reach every gateway.
+type Checker struct {
+	db     repository.Repository
+	cache  *marketData.Cache
+	limits map[string]models.RiskLimits
+	mu     sync.RWMutex
+	logger *slog.Logger
+}
+
+func New(db repository.Repository, cache *marketData.Cache, logger *slog.Logger) *Checker {
+	return &Checker{
+		db:     db,
+		cache:  cache,
+		limits: make(map[string]models.RiskLimits),
+		logger: logger,
+	}
+}
+
+func (c *Checker) Load() error {
+	limits, err := c.db.GetRiskLimits()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.limits = limits
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Load(); err != nil {
+				c.logger.Error("failed to load risk limits", "error", err)
+			}
+		}
+	}
+}
+
+// Limits returns the limits by pair, the defaults are under DefaultPair
+func (c *Checker) Limits() map[string]models.RiskLimits {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var limits = make(map[string]models.RiskLimits, len(c.limits))
+	for pair, pairLimits := range c.limits {
+		limits[pair] = pairLimits
+	}
+	return limits
+}
+
+// SetLimits stores the limits of the pair, they replace the defaults for it as a whole
+func (c *Checker) SetLimits(pair string, limits models.RiskLimits) error {
+	if err := c.db.SaveRiskLimits(pair, limits); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.limits[pair] = limits
+	c.mu.Unlock()
+	return nil
+}
+
+// DeleteLimits removes the limits of the pair, which then falls back to the defaults
+func (c *Checker) DeleteLimits(pair string) error {
+	if err := c.db.DeleteRiskLimits(pair); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	delete(c.limits, pair)
+	c.mu.Unlock()
+	return nil
+}
+
+// Check returns a *Rejection if the validated order is beyond the limits of its pair. replacedOrderID is
+// an open order the new one replaces and is left out of the exposure, 0 if there is none.
+// Every rejection is logged with the rule that triggered it.
+func (c *Checker) Check(req models.PlaceOrderReq, replacedOrderID int) error {
+	c.mu.RLock()
+	limits, ok := c.limits[req.Pair]
+	if !ok {
+		limits = c.limits[DefaultPair]
+	}
+	c.mu.RUnlock()
+
+	if limits == (models.RiskLimits{}) {
+		return nil
+	}
+
+	rejection, err := c.check(req, replacedOrderID, limits)
+	if err != nil {
+		return err
+	}
+	if rejection != nil {
+		c.logger.Warn("order rejected by risk check",
+			"rule", rejection.Rule,
+			"userID", req.UserID,
+			"pair", req.Pair,
+			"isBid", req.IsBid,
+			"type", req.Type,
+			"price", req.Price,
+			"qty", req.Qty,
+			"reason", rejection.Message,
+		)
+		return rejection
+	}
+	return nil
+}
+
+func (c *Checker) check(req models.PlaceOrderReq, replacedOrderID int, limits models.RiskLimits) (*Rejection, error) {
+	qty, ok := decimal.Parse(req.Qty)
+	if !ok {
+		return nil, nil
+	}
+
+	ticker, _ := c.cache.Ticker(req.Pair)
+	lastPrice, hasLastPrice := decimal.Parse(ticker.LastPrice)
+
+	// a market order is estimated at the last price
+	price := lastPrice
+	if req.Type == "limit" {
+		price, _ = decimal.Parse(req.Price)
+	}
+
+	if priceBand, ok := decimal.Parse(limits.PriceBand); ok && req.Type == "limit" && price != nil {
+		if !hasLastPrice {
+			c.skipped("priceBand", req)
+		} else {
+			distance := new(big.Rat).Mul(lastPrice, priceBand)
+			low := new(big.Rat).Sub(lastPrice, distance)
+			high := new(big.Rat).Add(lastPrice, distance)
+			if price.Cmp(low) < 0 || price.Cmp(high) > 0 {
+				return &Rejection{
+					Rule:    "priceBand",
+					Message: "price must be between " + decimal.Format(low) + " and " + decimal.Format(high) + " around the last price " + ticker.LastPrice,
+				}, nil
+			}
+		}
+	}
+
+	if maxQty, ok := decimal.Parse(limits.MaxQty); ok && qty.Cmp(maxQty) > 0 {
+		return &Rejection{
+			Rule:    "maxQty",
+			Message: "qty must not be greater than " + limits.MaxQty,
+		}, nil
+	}
+
+	// the notional of a market order without a known last price can not be checked
+	if price == nil {
+		if limits.MaxNotional != "" {
+			c.skipped("maxNotional", req)
+		}
+		if limits.MaxUserExposure != "" {
+			c.skipped("maxUserExposure", req)
+		}
+		return nil, nil
+	}
+
+	notional := new(big.Rat).Mul(qty, price)
+	if maxNotional, ok := decimal.Parse(limits.MaxNotional); ok && notional.Cmp(maxNotional) > 0 {
+		return &Rejection{
+			Rule:    "maxNotional",
+			Message: "notional must not be greater than " + limits.MaxNotional,
+		}, nil
+	}
+
+	maxUserExposure, ok := decimal.Parse(limits.MaxUserExposure)
+	if !ok {
+		return nil, nil
+	}
+
+	orders, err := c.db.GetOpenOrders(models.OrderFilter{
+		UserID: req.UserID,
+		Pair:   req.Pair,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	exposure := notional
+	for _, order := range orders {
+		if order.ID != replacedOrderID {
+			exposure.Add(exposure, restingNotional(order))
+		}
+	}
+
+	if exposure.Cmp(maxUserExposure) > 0 {
+		return &Rejection{
+			Rule:    "maxUserExposure",
+			Message: "notional of open orders would be " + decimal.Format(exposure) + ", more than the limit of " + limits.MaxUserExposure,
+		}, nil
+	}
+	return nil, nil
+}
+
+// skipped logs a rule that could not be checked because the last price of the pair is unknown
+func (c *Checker) skipped(rule string, req models.PlaceOrderReq) {
+	c.logger.Warn("risk check skipped without last price", "rule", rule, "userID", req.UserID, "pair", req.Pair, "type", req.Type)
+}
+
+// restingNotional is the notional of the unfilled rest of an open order
+func restingNotional(order models.Order) *big.Rat {
+	qty, ok := decimal.Parse(order.Qty)
+	price, hasPrice := decimal.Parse(order.Price)
+	if !ok || !hasPrice {
+		return new(big.Rat)
+	}
+
+	if sizeFilled, ok := decimal.Parse(order.SizeFilled); ok {
+		qty.Sub(qty, sizeFilled)
+	}
+	return qty.Mul(qty, price)
+}
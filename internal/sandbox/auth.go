@@ -0,0 +1,123 @@
+package sandbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+
+	"github.com/BazaarTrade/AuthProtoGen/pbA"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Auth is an in-memory pbA.AuthServer, passwords are kept in plain text as it is only meant for local development.
+type Auth struct {
+	pbA.UnimplementedAuthServer
+
+	users         map[string]*user
+	refreshTokens map[string]int64
+	lastUserID    int64
+	mu            sync.Mutex
+}
+
+type user struct {
+	id       int64
+	password string
+}
+
+func NewAuth() *Auth {
+	return &Auth{
+		users:         make(map[string]*user),
+		refreshTokens: make(map[string]int64),
+	}
+}
+
+func (a *Auth) Register(_ context.Context, req *pbA.RegisterRequest) (*pbA.RegisterResponse, error) {
+	if req.Email == "" || req.Password == "" {
+		return nil, status.Error(codes.InvalidArgument, "email and password are required")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.users[req.Email]; ok {
+		return nil, status.Error(codes.AlreadyExists, "user already exists")
+	}
+
+	a.lastUserID++
+	a.users[req.Email] = &user{id: a.lastUserID, password: req.Password}
+	return &pbA.RegisterResponse{}, nil
+}
+
+func (a *Auth) Login(_ context.Context, req *pbA.LoginRequest) (*pbA.LoginResponse, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	user, ok := a.users[req.Email]
+	if !ok || user.password != req.Password {
+		return nil, status.Error(codes.Unauthenticated, "invalid email or password")
+	}
+
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to generate refresh token")
+	}
+	a.refreshTokens[refreshToken] = user.id
+
+	return &pbA.LoginResponse{
+		UserID:       user.id,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func (a *Auth) Logout(_ context.Context, req *pbA.LogoutRequest) (*pbA.LogoutResponse, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.refreshTokens[req.RefreshToken]; !ok {
+		return nil, status.Error(codes.Unauthenticated, "invalid refresh token")
+	}
+
+	delete(a.refreshTokens, req.RefreshToken)
+	return &pbA.LogoutResponse{}, nil
+}
+
+func (a *Auth) IsRefreshTokenValid(_ context.Context, req *pbA.IsRefreshTokenValidRequest) (*pbA.IsRefreshTokenValidResponse, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	userID, ok := a.refreshTokens[req.RefreshToken]
+	return &pbA.IsRefreshTokenValidResponse{
+		IsValid: ok && userID == req.UserID,
+	}, nil
+}
+
+func (a *Auth) ChangePassword(_ context.Context, req *pbA.ChangePasswordRequest) (*pbA.ChangePasswordResponse, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	user, ok := a.users[req.Email]
+	if !ok || user.password != req.OldPassword {
+		return nil, status.Error(codes.Unauthenticated, "invalid email or password")
+	}
+
+	if userID, ok := a.refreshTokens[req.RefreshToken]; !ok || userID != user.id {
+		return nil, status.Error(codes.Unauthenticated, "invalid refresh token")
+	}
+
+	if req.NewPassword == "" {
+		return nil, status.Error(codes.InvalidArgument, "new password is required")
+	}
+
+	user.password = req.NewPassword
+	return &pbA.ChangePasswordResponse{}, nil
+}
+
+func newRefreshToken() (string, error) {
+	var token = make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
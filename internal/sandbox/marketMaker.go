@@ -0,0 +1,121 @@
+package sandbox
+
+import (
+	"context"
+	"log/slog"
+	"math/big"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/decimal"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/MatchingEngineProtoGen/pbM"
+)
+
+// MarketMakerUserID owns the sandbox liquidity, real users get IDs starting from 1
+const MarketMakerUserID = 0
+
+var (
+	marketMakerInterval = 2 * time.Second
+	marketMakerLevels   = 10
+	marketMakerStep     = big.NewRat(1, 1000)
+)
+
+// MarketMaker keeps a ladder of limit orders around the last price of the pair and
+// trades against it now and then, so the sandbox has a moving book, trades and candles.
+type MarketMaker struct {
+	matchingEngine *MatchingEngine
+	pairParams     models.PairParams
+	price          *big.Rat
+	logger         *slog.Logger
+}
+
+func NewMarketMaker(matchingEngine *MatchingEngine, pairParams models.PairParams, startPrice string, logger *slog.Logger) *MarketMaker {
+	price, ok := decimal.Parse(startPrice)
+	if !ok {
+		price = big.NewRat(100, 1)
+	}
+
+	return &MarketMaker{
+		matchingEngine: matchingEngine,
+		pairParams:     pairParams,
+		price:          price,
+		logger:         logger,
+	}
+}
+
+func (m *MarketMaker) Run(ctx context.Context) {
+	ticker := time.NewTicker(marketMakerInterval)
+	defer ticker.Stop()
+
+	for {
+		m.requote()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *MarketMaker) requote() {
+	pair := m.pairParams.Pair
+
+	for _, order := range m.matchingEngine.Orders(func(order *pbM.Order) bool {
+		return order.UserID == MarketMakerUserID && order.Pair == pair && order.Status == "filling"
+	}) {
+		if _, err := m.matchingEngine.CancelOrder(context.Background(), &pbM.OrderID{OrderID: order.ID}); err != nil {
+			m.logger.Warn("sandbox market maker failed to cancel order", "orderID", order.ID, "error", err)
+		}
+	}
+
+	if trades := m.matchingEngine.Trades(func(trade Trade) bool { return trade.Pair == pair }); len(trades) > 0 {
+		m.price = trades[len(trades)-1].Price
+	}
+
+	pricePrecision := int32(0)
+	if len(m.pairParams.OrderBookPricePrecisions) > 0 {
+		pricePrecision = slices.Max(m.pairParams.OrderBookPricePrecisions)
+	}
+
+	for level := 1; level <= marketMakerLevels; level++ {
+		offset := new(big.Rat).Mul(m.price, marketMakerStep)
+		offset.Mul(offset, big.NewRat(int64(level), 1))
+
+		bidPrice := decimal.Round(new(big.Rat).Sub(m.price, offset), pricePrecision, false)
+		askPrice := decimal.Round(new(big.Rat).Add(m.price, offset), pricePrecision, true)
+
+		m.place(true, "limit", decimal.FormatPrecision(bidPrice, pricePrecision), m.randomQty(level))
+		m.place(false, "limit", decimal.FormatPrecision(askPrice, pricePrecision), m.randomQty(level))
+	}
+
+	// a small taker order moves the last price like a random walk
+	m.place(rand.IntN(2) == 0, "market", "", m.randomQty(1))
+}
+
+func (m *MarketMaker) place(isBid bool, orderType, price, qty string) {
+	_, err := m.matchingEngine.PlaceOrder(context.Background(), &pbM.PlaceOrderReq{
+		UserID: MarketMakerUserID,
+		IsBid:  isBid,
+		Pair:   m.pairParams.Pair,
+		Price:  price,
+		Qty:    qty,
+		Type:   orderType,
+	})
+	if err != nil {
+		m.logger.Warn("sandbox market maker failed to place order", "pair", m.pairParams.Pair, "error", err)
+	}
+}
+
+// randomQty grows with the distance from the last price and is never below one qty step
+func (m *MarketMaker) randomQty(level int) string {
+	qty := big.NewRat(int64(level)*int64(1+rand.IntN(100)), 100)
+	qty = decimal.Round(qty, m.pairParams.QtyPrecision, false)
+
+	if step := decimal.Step(m.pairParams.QtyPrecision); qty.Cmp(step) < 0 {
+		qty = step
+	}
+	return decimal.FormatPrecision(qty, m.pairParams.QtyPrecision)
+}
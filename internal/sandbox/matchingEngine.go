@@ -0,0 +1,348 @@
+package sandbox
+
+import (
+	"context"
+	"math/big"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/decimal"
+	"github.com/BazaarTrade/MatchingEngineProtoGen/pbM"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// MatchingEngine is an in-memory pbM.MatchingEngineServer with price-time priority matching.
+type MatchingEngine struct {
+	pbM.UnimplementedMatchingEngineServer
+
+	books       map[string]*book
+	orders      map[int64]*pbM.Order
+	trades      []Trade
+	lastOrderID int64
+	lastTradeID int64
+	onTrade     func(Trade)
+	mu          sync.RWMutex
+}
+
+type book struct {
+	bids []*restingOrder // best (highest) price first
+	asks []*restingOrder // best (lowest) price first
+}
+
+type restingOrder struct {
+	order     *pbM.Order
+	price     *big.Rat
+	remaining *big.Rat
+}
+
+// Trade is one execution between a resting maker order and an incoming taker order.
+type Trade struct {
+	ID           int64
+	Pair         string
+	IsBid        bool // side of the taker
+	Price        *big.Rat
+	Qty          *big.Rat
+	MakerOrderID int64
+	MakerUserID  int64
+	TakerOrderID int64
+	TakerUserID  int64
+	Time         time.Time
+}
+
+// Level is the total resting quantity at one price.
+type Level struct {
+	Price *big.Rat
+	Qty   *big.Rat
+}
+
+func NewMatchingEngine() *MatchingEngine {
+	return &MatchingEngine{
+		books:  make(map[string]*book),
+		orders: make(map[int64]*pbM.Order),
+	}
+}
+
+// OnTrade registers the single trade callback, it is called with the engine lock held.
+func (m *MatchingEngine) OnTrade(onTrade func(Trade)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.onTrade = onTrade
+}
+
+func (m *MatchingEngine) CreateOrderBook(_ context.Context, req *pbM.Pair) (*pbM.Empty, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.books[req.Pair]; ok {
+		return nil, status.Error(codes.AlreadyExists, "orderbook already exists")
+	}
+
+	m.books[req.Pair] = &book{}
+	return &pbM.Empty{}, nil
+}
+
+func (m *MatchingEngine) DeleteOrderBook(_ context.Context, req *pbM.Pair) (*pbM.Empty, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	book, ok := m.books[req.Pair]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "orderbook not found")
+	}
+
+	now := timestamppb.Now()
+	for _, resting := range append(book.bids, book.asks...) {
+		resting.order.Status = "canceled"
+		resting.order.ClosedAt = now
+	}
+
+	delete(m.books, req.Pair)
+	return &pbM.Empty{}, nil
+}
+
+func (m *MatchingEngine) PlaceOrder(_ context.Context, req *pbM.PlaceOrderReq) (*pbM.PlaceOrderRes, error) {
+	qty, ok := decimal.Parse(req.Qty)
+	if !ok || qty.Sign() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid qty")
+	}
+
+	var price *big.Rat
+	switch req.Type {
+	case "limit":
+		price, ok = decimal.Parse(req.Price)
+		if !ok || price.Sign() <= 0 {
+			return nil, status.Error(codes.InvalidArgument, "invalid price")
+		}
+	case "market":
+	default:
+		return nil, status.Error(codes.InvalidArgument, "invalid order type")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	book, ok := m.books[req.Pair]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "orderbook not found")
+	}
+
+	m.lastOrderID++
+	now := time.Now()
+	order := &pbM.Order{
+		ID:         m.lastOrderID,
+		UserID:     req.UserID,
+		IsBid:      req.IsBid,
+		Pair:       req.Pair,
+		Price:      req.Price,
+		Qty:        req.Qty,
+		SizeFilled: "0",
+		Status:     "filling",
+		Type:       req.Type,
+		CreatedAt:  timestamppb.New(now),
+	}
+	m.orders[order.ID] = order
+
+	var (
+		remaining   = new(big.Rat).Set(qty)
+		filled      = new(big.Rat)
+		matchOrders []*pbM.Order
+	)
+
+	opposite := &book.asks
+	if !req.IsBid {
+		opposite = &book.bids
+	}
+
+	for remaining.Sign() > 0 && len(*opposite) > 0 {
+		maker := (*opposite)[0]
+		if price != nil && !crosses(req.IsBid, price, maker.price) {
+			break
+		}
+
+		tradeQty := new(big.Rat).Set(remaining)
+		if maker.remaining.Cmp(tradeQty) < 0 {
+			tradeQty.Set(maker.remaining)
+		}
+
+		remaining.Sub(remaining, tradeQty)
+		filled.Add(filled, tradeQty)
+		maker.remaining.Sub(maker.remaining, tradeQty)
+
+		makerFilled, _ := decimal.Parse(maker.order.SizeFilled)
+		maker.order.SizeFilled = decimal.Format(makerFilled.Add(makerFilled, tradeQty))
+		if maker.remaining.Sign() == 0 {
+			maker.order.Status = "filled"
+			maker.order.ClosedAt = timestamppb.New(now)
+			*opposite = (*opposite)[1:]
+		}
+		matchOrders = append(matchOrders, cloneOrder(maker.order))
+
+		m.lastTradeID++
+		trade := Trade{
+			ID:           m.lastTradeID,
+			Pair:         req.Pair,
+			IsBid:        req.IsBid,
+			Price:        new(big.Rat).Set(maker.price),
+			Qty:          tradeQty,
+			MakerOrderID: maker.order.ID,
+			MakerUserID:  maker.order.UserID,
+			TakerOrderID: order.ID,
+			TakerUserID:  order.UserID,
+			Time:         now,
+		}
+		m.trades = append(m.trades, trade)
+		if m.onTrade != nil {
+			m.onTrade(trade)
+		}
+	}
+
+	order.SizeFilled = decimal.Format(filled)
+
+	switch {
+	case remaining.Sign() == 0:
+		order.Status = "filled"
+		order.ClosedAt = timestamppb.New(now)
+
+	case price == nil:
+		// a market order never rests, the unfilled part is dropped
+		order.Status = "canceled"
+		order.ClosedAt = timestamppb.New(now)
+
+	default:
+		book.insert(&restingOrder{order: order, price: price, remaining: remaining})
+	}
+
+	return &pbM.PlaceOrderRes{
+		Order:       cloneOrder(order),
+		MatchOrders: matchOrders,
+	}, nil
+}
+
+func (m *MatchingEngine) CancelOrder(_ context.Context, req *pbM.OrderID) (*pbM.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	order, ok := m.orders[req.OrderID]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "order not found")
+	}
+
+	if order.Status != "filling" {
+		return nil, status.Error(codes.InvalidArgument, "order is already closed")
+	}
+
+	if book, ok := m.books[order.Pair]; ok {
+		book.remove(order.ID)
+	}
+
+	order.Status = "canceled"
+	order.ClosedAt = timestamppb.Now()
+	return cloneOrder(order), nil
+}
+
+func crosses(isBid bool, price, makerPrice *big.Rat) bool {
+	if isBid {
+		return price.Cmp(makerPrice) >= 0
+	}
+	return price.Cmp(makerPrice) <= 0
+}
+
+func (b *book) insert(resting *restingOrder) {
+	side := &b.asks
+	worse := func(price *big.Rat) bool { return price.Cmp(resting.price) > 0 }
+	if resting.order.IsBid {
+		side = &b.bids
+		worse = func(price *big.Rat) bool { return price.Cmp(resting.price) < 0 }
+	}
+
+	// after all orders with the same or a better price
+	i := sort.Search(len(*side), func(i int) bool { return worse((*side)[i].price) })
+	*side = slices.Insert(*side, i, resting)
+}
+
+func (b *book) remove(orderID int64) {
+	isOrder := func(resting *restingOrder) bool { return resting.order.ID == orderID }
+	b.bids = slices.DeleteFunc(b.bids, isOrder)
+	b.asks = slices.DeleteFunc(b.asks, isOrder)
+}
+
+// Depth returns resting quantity aggregated by price, best prices first.
+func (m *MatchingEngine) Depth(pair string) ([]Level, []Level, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	book, ok := m.books[pair]
+	if !ok {
+		return nil, nil, false
+	}
+
+	return levels(book.bids), levels(book.asks), true
+}
+
+func levels(side []*restingOrder) []Level {
+	var result []Level
+	for _, resting := range side {
+		if n := len(result); n > 0 && result[n-1].Price.Cmp(resting.price) == 0 {
+			result[n-1].Qty.Add(result[n-1].Qty, resting.remaining)
+			continue
+		}
+		result = append(result, Level{
+			Price: new(big.Rat).Set(resting.price),
+			Qty:   new(big.Rat).Set(resting.remaining),
+		})
+	}
+	return result
+}
+
+// Orders returns copies of the orders matching the filter.
+func (m *MatchingEngine) Orders(filter func(*pbM.Order) bool) []*pbM.Order {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var orders []*pbM.Order
+	for _, order := range m.orders {
+		if filter(order) {
+			orders = append(orders, cloneOrder(order))
+		}
+	}
+
+	slices.SortFunc(orders, func(a, b *pbM.Order) int {
+		return int(a.ID - b.ID)
+	})
+	return orders
+}
+
+// Trades returns the trades matching the filter in execution order.
+func (m *MatchingEngine) Trades(filter func(Trade) bool) []Trade {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var trades []Trade
+	for _, trade := range m.trades {
+		if filter(trade) {
+			trades = append(trades, trade)
+		}
+	}
+	return trades
+}
+
+func cloneOrder(order *pbM.Order) *pbM.Order {
+	return &pbM.Order{
+		ID:         order.ID,
+		UserID:     order.UserID,
+		IsBid:      order.IsBid,
+		Pair:       order.Pair,
+		Price:      order.Price,
+		Qty:        order.Qty,
+		SizeFilled: order.SizeFilled,
+		Status:     order.Status,
+		Type:       order.Type,
+		CreatedAt:  order.CreatedAt,
+		ClosedAt:   order.ClosedAt,
+	}
+}
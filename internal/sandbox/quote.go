@@ -0,0 +1,480 @@
+package sandbox
+
+import (
+	"context"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/decimal"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/QuoteProtoGen/pbQ"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	streamInterval  = 500 * time.Millisecond
+	orderBookDepth  = 50
+	keptTrades      = 1000
+	keptCandleStick = 1000
+)
+
+// Quote is an in-memory pbQ.QuoteServer that derives market data from the sandbox matching engine.
+type Quote struct {
+	pbQ.UnimplementedQuoteServer
+
+	matchingEngine    *MatchingEngine
+	pairs             map[string]*quotePair
+	lastCandleStickID int64
+	mu                sync.RWMutex
+}
+
+type quotePair struct {
+	params *pbQ.PairParams
+
+	trades      []*pbQ.Trade
+	tradesTotal int
+
+	openPrice *big.Rat
+	lastPrice *big.Rat
+	highPrice *big.Rat
+	lowPrice  *big.Rat
+	volume    *big.Rat
+	turnover  *big.Rat
+
+	// closed candle sticks followed by the current one
+	candleSticks map[string][]*candleStick
+}
+
+type candleStick struct {
+	id         int64
+	timeframe  string
+	openTime   time.Time
+	closeTime  time.Time
+	openPrice  *big.Rat
+	closePrice *big.Rat
+	highPrice  *big.Rat
+	lowPrice   *big.Rat
+	volume     *big.Rat
+	turnover   *big.Rat
+	isClosed   bool
+}
+
+func NewQuote(matchingEngine *MatchingEngine) *Quote {
+	q := &Quote{
+		matchingEngine: matchingEngine,
+		pairs:          make(map[string]*quotePair),
+	}
+	matchingEngine.OnTrade(q.addTrade)
+	return q
+}
+
+func (q *Quote) CreateOrderBook(_ context.Context, req *pbQ.PairParams) (*pbQ.Empty, error) {
+	for _, timeframe := range req.CandleStickTimeframes {
+		if _, err := parseTimeframe(timeframe); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid candleStick timeframe "+timeframe)
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.pairs[req.Pair]; ok {
+		return nil, status.Error(codes.AlreadyExists, "pair already exists")
+	}
+
+	q.pairs[req.Pair] = &quotePair{
+		params: &pbQ.PairParams{
+			Pair:                  req.Pair,
+			PricePrecisions:       slices.Clone(req.PricePrecisions),
+			QtyPrecision:          req.QtyPrecision,
+			CandleStickTimeframes: slices.Clone(req.CandleStickTimeframes),
+		},
+		volume:       new(big.Rat),
+		turnover:     new(big.Rat),
+		candleSticks: make(map[string][]*candleStick),
+	}
+	return &pbQ.Empty{}, nil
+}
+
+func (q *Quote) DeleteOrderBook(_ context.Context, req *pbQ.Pair) (*pbQ.Empty, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.pairs[req.Pair]; !ok {
+		return nil, status.Error(codes.NotFound, "pair not found")
+	}
+
+	delete(q.pairs, req.Pair)
+	return &pbQ.Empty{}, nil
+}
+
+// addTrade is called by the matching engine with its lock held, so it must not call back into it
+func (q *Quote) addTrade(trade Trade) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	pair, ok := q.pairs[trade.Pair]
+	if !ok {
+		return
+	}
+
+	pair.trades = append(pair.trades, &pbQ.Trade{
+		Pair:  trade.Pair,
+		IsBid: trade.IsBid,
+		Price: decimal.Format(trade.Price),
+		Qty:   decimal.FormatPrecision(trade.Qty, pair.params.QtyPrecision),
+		Time:  timestamppb.New(trade.Time),
+	})
+	pair.tradesTotal++
+	if len(pair.trades) > keptTrades {
+		pair.trades = slices.Clone(pair.trades[len(pair.trades)-keptTrades:])
+	}
+
+	turnover := new(big.Rat).Mul(trade.Price, trade.Qty)
+
+	if pair.openPrice == nil {
+		pair.openPrice = new(big.Rat).Set(trade.Price)
+		pair.highPrice = new(big.Rat).Set(trade.Price)
+		pair.lowPrice = new(big.Rat).Set(trade.Price)
+	}
+	pair.lastPrice = new(big.Rat).Set(trade.Price)
+	if trade.Price.Cmp(pair.highPrice) > 0 {
+		pair.highPrice.Set(trade.Price)
+	}
+	if trade.Price.Cmp(pair.lowPrice) < 0 {
+		pair.lowPrice.Set(trade.Price)
+	}
+	pair.volume.Add(pair.volume, trade.Qty)
+	pair.turnover.Add(pair.turnover, turnover)
+
+	for _, timeframe := range pair.params.CandleStickTimeframes {
+		current := q.currentCandleStick(pair, timeframe, trade.Time)
+		if current.openPrice == nil {
+			current.openPrice = new(big.Rat).Set(trade.Price)
+			current.highPrice = new(big.Rat).Set(trade.Price)
+			current.lowPrice = new(big.Rat).Set(trade.Price)
+		}
+		current.closePrice = new(big.Rat).Set(trade.Price)
+		if trade.Price.Cmp(current.highPrice) > 0 {
+			current.highPrice.Set(trade.Price)
+		}
+		if trade.Price.Cmp(current.lowPrice) < 0 {
+			current.lowPrice.Set(trade.Price)
+		}
+		current.volume.Add(current.volume, trade.Qty)
+		current.turnover.Add(current.turnover, turnover)
+	}
+}
+
+// currentCandleStick closes the current candle stick once now is past its close time
+// and opens the next one, it must be called with q.mu held for writing
+func (q *Quote) currentCandleStick(pair *quotePair, timeframe string, now time.Time) *candleStick {
+	duration, _ := parseTimeframe(timeframe)
+	candleSticks := pair.candleSticks[timeframe]
+
+	if n := len(candleSticks); n > 0 && now.Before(candleSticks[n-1].closeTime) {
+		return candleSticks[n-1]
+	}
+
+	var previousClose *big.Rat
+	if n := len(candleSticks); n > 0 {
+		previous := candleSticks[n-1]
+		previous.isClosed = true
+		previousClose = previous.closePrice
+	}
+
+	q.lastCandleStickID++
+	openTime := now.Truncate(duration)
+	current := &candleStick{
+		id:        q.lastCandleStickID,
+		timeframe: timeframe,
+		openTime:  openTime,
+		closeTime: openTime.Add(duration),
+		volume:    new(big.Rat),
+		turnover:  new(big.Rat),
+	}
+
+	// without trades the candle stick is flat at the previous close
+	if previousClose != nil {
+		current.openPrice = new(big.Rat).Set(previousClose)
+		current.closePrice = new(big.Rat).Set(previousClose)
+		current.highPrice = new(big.Rat).Set(previousClose)
+		current.lowPrice = new(big.Rat).Set(previousClose)
+	}
+
+	candleSticks = append(candleSticks, current)
+	if len(candleSticks) > keptCandleStick {
+		candleSticks = slices.Clone(candleSticks[len(candleSticks)-keptCandleStick:])
+	}
+	pair.candleSticks[timeframe] = candleSticks
+	return current
+}
+
+func (q *Quote) StreamPrecisedOrderBookSnapshots(req *pbQ.Pair, stream grpc.ServerStreamingServer[pbQ.PrecisedOrderBookSnapshots]) error {
+	return streamEvery(stream.Context(), func() error {
+		q.mu.RLock()
+		pair, ok := q.pairs[req.Pair]
+		var params *pbQ.PairParams
+		if ok {
+			params = pair.params
+		}
+		q.mu.RUnlock()
+
+		if !ok {
+			return status.Error(codes.NotFound, "pair not found")
+		}
+
+		bids, asks, ok := q.matchingEngine.Depth(req.Pair)
+		if !ok {
+			return status.Error(codes.NotFound, "orderbook not found")
+		}
+
+		snapshots := &pbQ.PrecisedOrderBookSnapshots{
+			PrecisedOrderBookSnapshot: make(map[int32]*pbQ.OrderBookSnapshot, len(params.PricePrecisions)),
+		}
+		for _, precision := range params.PricePrecisions {
+			snapshot := &pbQ.OrderBookSnapshot{Pair: req.Pair}
+			snapshot.Bids, snapshot.BidsQty = aggregate(bids, precision, params.QtyPrecision, false)
+			snapshot.Asks, snapshot.AsksQty = aggregate(asks, precision, params.QtyPrecision, true)
+			snapshots.PrecisedOrderBookSnapshot[precision] = snapshot
+		}
+		return stream.Send(snapshots)
+	})
+}
+
+// aggregate merges levels into price buckets of the given precision, bids round down and asks up
+func aggregate(levels []Level, precision, qtyPrecision int32, up bool) ([]*pbQ.Limit, string) {
+	var (
+		limits []*pbQ.Limit
+		prices []*big.Rat
+		qtys   []*big.Rat
+		total  = new(big.Rat)
+	)
+
+	for _, level := range levels {
+		total.Add(total, level.Qty)
+
+		price := decimal.Round(level.Price, precision, up)
+		if n := len(prices); n > 0 && prices[n-1].Cmp(price) == 0 {
+			qtys[n-1].Add(qtys[n-1], level.Qty)
+			continue
+		}
+
+		if len(prices) == orderBookDepth {
+			continue
+		}
+		prices = append(prices, price)
+		qtys = append(qtys, new(big.Rat).Set(level.Qty))
+	}
+
+	for i := range prices {
+		limits = append(limits, &pbQ.Limit{
+			Price: decimal.FormatPrecision(prices[i], precision),
+			Qty:   decimal.FormatPrecision(qtys[i], qtyPrecision),
+		})
+	}
+	return limits, decimal.FormatPrecision(total, qtyPrecision)
+}
+
+func (q *Quote) StreamPrecisedTrades(req *pbQ.Pair, stream grpc.ServerStreamingServer[pbQ.Trades]) error {
+	q.mu.RLock()
+	pair, ok := q.pairs[req.Pair]
+	var sent int
+	if ok {
+		sent = pair.tradesTotal
+	}
+	q.mu.RUnlock()
+
+	if !ok {
+		return status.Error(codes.NotFound, "pair not found")
+	}
+
+	return streamEvery(stream.Context(), func() error {
+		q.mu.RLock()
+		pair, ok := q.pairs[req.Pair]
+		if !ok {
+			q.mu.RUnlock()
+			return status.Error(codes.NotFound, "pair not found")
+		}
+
+		newTrades := min(pair.tradesTotal-sent, len(pair.trades))
+		trades := &pbQ.Trades{Trades: slices.Clone(pair.trades[len(pair.trades)-newTrades:])}
+		sent = pair.tradesTotal
+		q.mu.RUnlock()
+
+		if len(trades.Trades) == 0 {
+			return nil
+		}
+		return stream.Send(trades)
+	})
+}
+
+func (q *Quote) StreamTicker(req *pbQ.Pair, stream grpc.ServerStreamingServer[pbQ.Ticker]) error {
+	return streamEvery(stream.Context(), func() error {
+		q.mu.RLock()
+		pair, ok := q.pairs[req.Pair]
+		if !ok {
+			q.mu.RUnlock()
+			return status.Error(codes.NotFound, "pair not found")
+		}
+
+		ticker := &pbQ.Ticker{
+			Pair:      req.Pair,
+			LastPrice: "0",
+			Change:    "0",
+			HighPrice: "0",
+			LowPrice:  "0",
+			Volume:    decimal.FormatPrecision(pair.volume, pair.params.QtyPrecision),
+			Turnover:  decimal.Format(pair.turnover),
+		}
+		if pair.lastPrice != nil {
+			change := new(big.Rat).Sub(pair.lastPrice, pair.openPrice)
+			change.Quo(change, pair.openPrice).Mul(change, big.NewRat(100, 1))
+
+			ticker.LastPrice = decimal.Format(pair.lastPrice)
+			ticker.Change = change.FloatString(2)
+			ticker.HighPrice = decimal.Format(pair.highPrice)
+			ticker.LowPrice = decimal.Format(pair.lowPrice)
+		}
+		q.mu.RUnlock()
+
+		return stream.Send(ticker)
+	})
+}
+
+func (q *Quote) StreamCandleStick(req *pbQ.Pair, stream grpc.ServerStreamingServer[pbQ.CandleStick]) error {
+	return streamEvery(stream.Context(), func() error {
+		q.mu.Lock()
+		pair, ok := q.pairs[req.Pair]
+		if !ok {
+			q.mu.Unlock()
+			return status.Error(codes.NotFound, "pair not found")
+		}
+
+		var candleSticks []*pbQ.CandleStick
+		for _, timeframe := range pair.params.CandleStickTimeframes {
+			candleSticks = append(candleSticks, q.currentCandleStick(pair, timeframe, time.Now()).toPbQ(req.Pair))
+		}
+		q.mu.Unlock()
+
+		for _, candleStick := range candleSticks {
+			if err := stream.Send(candleStick); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (c *candleStick) toPbQ(pair string) *pbQ.CandleStick {
+	var price = func(r *big.Rat) string {
+		if r == nil {
+			return "0"
+		}
+		return decimal.Format(r)
+	}
+
+	return &pbQ.CandleStick{
+		ID:         c.id,
+		Pair:       pair,
+		Timeframe:  c.timeframe,
+		OpenTime:   timestamppb.New(c.openTime),
+		CloseTime:  timestamppb.New(c.closeTime),
+		OpenPrice:  price(c.openPrice),
+		ClosePrice: price(c.closePrice),
+		HighPrice:  price(c.highPrice),
+		LowPrice:   price(c.lowPrice),
+		Volume:     decimal.Format(c.volume),
+		Turnover:   decimal.Format(c.turnover),
+		IsClosed:   c.isClosed,
+	}
+}
+
+// CandleStickHistory returns closed and current candle sticks with ID below candleID
+// (all if candleID is 0), newest first.
+func (q *Quote) CandleStickHistory(req models.CandleStickHistoryRequest) []models.CandleStick {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	pair, ok := q.pairs[req.Pair]
+	if !ok {
+		return nil
+	}
+
+	var result []models.CandleStick
+	candleSticks := pair.candleSticks[req.Timeframe]
+	for i := len(candleSticks) - 1; i >= 0 && len(result) < req.Limit; i-- {
+		candleStick := candleSticks[i]
+		if req.CandleID != 0 && candleStick.id >= int64(req.CandleID) {
+			continue
+		}
+
+		pbQCandleStick := candleStick.toPbQ(req.Pair)
+		result = append(result, models.CandleStick{
+			ID:         int(pbQCandleStick.ID),
+			Pair:       pbQCandleStick.Pair,
+			Timeframe:  pbQCandleStick.Timeframe,
+			OpenTime:   candleStick.openTime.Format(time.RFC3339),
+			CloseTime:  candleStick.closeTime.Format(time.RFC3339),
+			OpenPrice:  pbQCandleStick.OpenPrice,
+			ClosePrice: pbQCandleStick.ClosePrice,
+			HighPrice:  pbQCandleStick.HighPrice,
+			LowPrice:   pbQCandleStick.LowPrice,
+			Volume:     pbQCandleStick.Volume,
+			Turnover:   pbQCandleStick.Turnover,
+			IsClosed:   pbQCandleStick.IsClosed,
+		})
+	}
+	return result
+}
+
+// streamEvery calls send every streamInterval until the stream context is done or send fails
+func streamEvery(ctx context.Context, send func() error) error {
+	ticker := time.NewTicker(streamInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if err := send(); err != nil {
+			return err
+		}
+	}
+}
+
+// parseTimeframe accepts Go durations plus d (day) and w (week) units, e.g. 1m, 4h, 1d, 1w
+func parseTimeframe(timeframe string) (time.Duration, error) {
+	var unit time.Duration
+	switch {
+	case strings.HasSuffix(timeframe, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(timeframe, "w"):
+		unit = 7 * 24 * time.Hour
+	default:
+		duration, err := time.ParseDuration(timeframe)
+		if err == nil && duration <= 0 {
+			return 0, strconv.ErrRange
+		}
+		return duration, err
+	}
+
+	n, err := strconv.Atoi(timeframe[:len(timeframe)-1])
+	if err != nil {
+		return 0, err
+	}
+	if n <= 0 {
+		return 0, strconv.ErrRange
+	}
+	return time.Duration(n) * unit, nil
+}
@@ -0,0 +1,849 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/converter"
+	"github.com/BazaarTrade/ApiGatewayService/internal/decimal"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/repository"
+	"github.com/BazaarTrade/MatchingEngineProtoGen/pbM"
+)
+
+var ErrPairNotFound = errors.New("pair not found")
+
+// Repository is an in-memory repository.Repository, orders and candle sticks
+// are read straight from the sandbox matching engine and quote service.
+type Repository struct {
+	matchingEngine *MatchingEngine
+	quote          *Quote
+	pairs          map[string]models.PairParams
+	clientOrders   map[int]*models.ClientOrder
+	lastID         int
+	postOnlyOrders map[int]bool
+
+	conditionalOrders map[int]*models.ConditionalOrder
+	lastConditionalID int
+
+	scheduledJobs  map[int]*models.ScheduledJob
+	lastScheduleID int
+
+	algoOrders     map[int]*models.AlgoOrder
+	lastAlgoID     int
+	algoOrderChild map[int]int // child order ID to algo order ID
+
+	orderGroups    map[int]*models.OrderGroup
+	lastGroupID    int
+	orderGroupLegs map[int][]models.OrderGroupLeg
+
+	riskLimits map[string]models.RiskLimits
+	mu         sync.RWMutex
+}
+
+func NewRepository(matchingEngine *MatchingEngine, quote *Quote) *Repository {
+	return &Repository{
+		matchingEngine: matchingEngine,
+		quote:          quote,
+		pairs:          make(map[string]models.PairParams),
+		clientOrders:   make(map[int]*models.ClientOrder),
+		postOnlyOrders: make(map[int]bool),
+
+		conditionalOrders: make(map[int]*models.ConditionalOrder),
+		scheduledJobs:     make(map[int]*models.ScheduledJob),
+		algoOrders:        make(map[int]*models.AlgoOrder),
+		algoOrderChild:    make(map[int]int),
+		orderGroups:       make(map[int]*models.OrderGroup),
+		orderGroupLegs:    make(map[int][]models.OrderGroupLeg),
+		riskLimits:        make(map[string]models.RiskLimits),
+	}
+}
+
+func (r *Repository) GetPairsOrderBookPricePrecisions() (map[string][]int32, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var orderBookPricePrecisions = make(map[string][]int32, len(r.pairs))
+	for pair, pairParams := range r.pairs {
+		orderBookPricePrecisions[pair] = slices.Clone(pairParams.OrderBookPricePrecisions)
+	}
+	return orderBookPricePrecisions, nil
+}
+
+func (r *Repository) GetOrderBookPricePrecisions(pair string) ([]int32, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pairParams, ok := r.pairs[pair]
+	if !ok {
+		return nil, ErrPairNotFound
+	}
+	return slices.Clone(pairParams.OrderBookPricePrecisions), nil
+}
+
+func (r *Repository) GetCandleStickTimeframes(pair string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pairParams, ok := r.pairs[pair]
+	if !ok {
+		return nil, ErrPairNotFound
+	}
+	return slices.Clone(pairParams.CandleStickTimeframes), nil
+}
+
+func (r *Repository) GetPairsParams() ([]models.PairParams, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var pairsParams = make([]models.PairParams, 0, len(r.pairs))
+	for _, pairParams := range r.pairs {
+		pairsParams = append(pairsParams, pairParams)
+	}
+	return pairsParams, nil
+}
+
+func (r *Repository) CreatePair(pairParams models.PairParams) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.pairs[pairParams.Pair]; ok {
+		return errors.New("pair already exists")
+	}
+
+	r.pairs[pairParams.Pair] = pairParams
+	return nil
+}
+
+// NotifyPairsChanged has nothing to notify, the sandbox runs a single gateway
+func (r *Repository) NotifyPairsChanged() error {
+	return nil
+}
+
+func (r *Repository) ListenPairsChanged(ctx context.Context, _ func()) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (r *Repository) GetOpenOrders(filter models.OrderFilter) ([]models.Order, error) {
+	return r.filterOrders(filter, true), nil
+}
+
+func (r *Repository) GetClosedOrders(filter models.OrderFilter) ([]models.Order, error) {
+	return r.filterOrders(filter, false), nil
+}
+
+func (r *Repository) CountOpenOrders(userID int, pair string, exceptOrderID int) (map[string]int, error) {
+	orders := r.filterOrders(models.OrderFilter{UserID: userID, Pair: pair}, true)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var byPair = make(map[string]int)
+	for _, order := range orders {
+		if _, isChild := r.algoOrderChild[order.ID]; order.ID != exceptOrderID && !isChild {
+			byPair[order.Pair]++
+		}
+	}
+	for _, conditionalOrder := range r.conditionalOrders {
+		if conditionalOrder.UserID == userID && conditionalOrder.Status == "untriggered" {
+			byPair[conditionalOrder.Pair]++
+		}
+	}
+	for _, job := range r.scheduledJobs {
+		if job.UserID == userID && job.Kind == "place" && job.Status == "pending" {
+			byPair[converter.ScheduledJobToModelsOrder(*job).Pair]++
+		}
+	}
+	for _, algoOrder := range r.algoOrders {
+		if algoOrder.UserID == userID && (algoOrder.Status == "running" || algoOrder.Status == "paused") {
+			byPair[algoOrder.Pair]++
+		}
+	}
+
+	if pair != "" {
+		return map[string]int{pair: byPair[pair]}, nil
+	}
+	return byPair, nil
+}
+
+// filterOrders sorts by the creation time in seconds, the precision orders are converted with
+func (r *Repository) filterOrders(filter models.OrderFilter, open bool) []models.Order {
+	createdAt := func(order *pbM.Order) time.Time {
+		return order.CreatedAt.AsTime().Truncate(time.Second)
+	}
+
+	var after time.Time
+	if filter.After != nil {
+		after, _ = time.Parse(time.RFC3339, filter.After.CreatedAt)
+	}
+
+	matchingOrders := r.matchingEngine.Orders(func(order *pbM.Order) bool {
+		switch {
+		case order.UserID != int64(filter.UserID),
+			(order.Status == "filling") != open,
+			filter.Pair != "" && order.Pair != filter.Pair,
+			filter.Side != "" && order.IsBid != (filter.Side == "buy"),
+			filter.Type != "" && order.Type != filter.Type,
+			filter.Status != "" && order.Status != filter.Status,
+			!filter.From.IsZero() && createdAt(order).Before(filter.From),
+			!filter.To.IsZero() && !createdAt(order).Before(filter.To):
+			return false
+		case filter.After != nil:
+			return createdAt(order).Before(after) || (createdAt(order).Equal(after) && order.ID < int64(filter.After.ID))
+		}
+		return true
+	})
+
+	slices.SortFunc(matchingOrders, func(a, b *pbM.Order) int {
+		if c := createdAt(b).Compare(createdAt(a)); c != 0 {
+			return c
+		}
+		return int(b.ID - a.ID)
+	})
+	if filter.Limit > 0 && len(matchingOrders) > filter.Limit {
+		matchingOrders = matchingOrders[:filter.Limit]
+	}
+
+	var orders []models.Order
+	for _, order := range matchingOrders {
+		orders = append(orders, converter.PbMOrderToModelsOrder(order))
+	}
+	return orders
+}
+
+func (r *Repository) GetOrderByID(orderID int) (models.Order, error) {
+	orders := r.orders(func(order *pbM.Order) bool {
+		return order.ID == int64(orderID)
+	})
+	if len(orders) == 0 {
+		return models.Order{}, repository.ErrNotFound
+	}
+	return orders[0], nil
+}
+
+func (r *Repository) GetUserOrder(userID, orderID int) (models.Order, error) {
+	orders := r.orders(func(order *pbM.Order) bool {
+		return order.ID == int64(orderID) && order.UserID == int64(userID)
+	})
+	if len(orders) == 0 {
+		return models.Order{}, repository.ErrNotFound
+	}
+	return orders[0], nil
+}
+
+func (r *Repository) orders(filter func(*pbM.Order) bool) []models.Order {
+	var orders []models.Order
+	for _, order := range r.matchingEngine.Orders(filter) {
+		orders = append(orders, converter.PbMOrderToModelsOrder(order))
+	}
+	return orders
+}
+
+// GetExecutions formats times in seconds like orders, the cursor compares at that precision
+func (r *Repository) GetExecutions(filter models.ExecutionFilter) ([]models.Execution, error) {
+	type execution struct {
+		models.Execution
+		time time.Time
+	}
+
+	var executions []execution
+	for _, trade := range r.matchingEngine.Trades(func(trade Trade) bool {
+		return (trade.TakerUserID == int64(filter.UserID) || trade.MakerUserID == int64(filter.UserID)) &&
+			(filter.Pair == "" || trade.Pair == filter.Pair)
+	}) {
+		tradeTime := trade.Time.Truncate(time.Second)
+		if (!filter.From.IsZero() && tradeTime.Before(filter.From)) || (!filter.To.IsZero() && !tradeTime.Before(filter.To)) {
+			continue
+		}
+
+		add := func(orderID int64, isBid bool, role string) {
+			executions = append(executions, execution{
+				Execution: models.Execution{
+					TradeID: int(trade.ID),
+					OrderID: int(orderID),
+					Pair:    trade.Pair,
+					IsBid:   isBid,
+					Price:   decimal.Format(trade.Price),
+					Qty:     decimal.Format(trade.Qty),
+					Role:    role,
+					Time:    tradeTime.Format(time.RFC3339),
+				},
+				time: tradeTime,
+			})
+		}
+		if trade.TakerUserID == int64(filter.UserID) {
+			add(trade.TakerOrderID, trade.IsBid, "taker")
+		}
+		if trade.MakerUserID == int64(filter.UserID) {
+			add(trade.MakerOrderID, !trade.IsBid, "maker")
+		}
+	}
+
+	compare := func(a execution, bTime time.Time, bTradeID int, bRole string) int {
+		if c := a.time.Compare(bTime); c != 0 {
+			return c
+		}
+		if a.TradeID != bTradeID {
+			return a.TradeID - bTradeID
+		}
+		return strings.Compare(a.Role, bRole)
+	}
+
+	slices.SortFunc(executions, func(a, b execution) int {
+		return compare(b, a.time, a.TradeID, a.Role)
+	})
+
+	var page []models.Execution
+	for _, execution := range executions {
+		if filter.After != nil {
+			afterTime, _ := time.Parse(time.RFC3339, filter.After.Time)
+			if compare(execution, afterTime, filter.After.TradeID, filter.After.Role) >= 0 {
+				continue
+			}
+		}
+		if filter.Limit > 0 && len(page) == filter.Limit {
+			break
+		}
+		page = append(page, execution.Execution)
+	}
+	return page, nil
+}
+
+func (r *Repository) GetCandleStickHistory(req models.CandleStickHistoryRequest) ([]models.CandleStick, error) {
+	return r.quote.CandleStickHistory(req), nil
+}
+
+func (r *Repository) ReserveClientOrder(clientOrder models.ClientOrder) (models.ClientOrder, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.clientOrders {
+		if existing.UserID != clientOrder.UserID {
+			continue
+		}
+
+		if (clientOrder.ClientOrderID != "" && existing.ClientOrderID == clientOrder.ClientOrderID) ||
+			(clientOrder.IdempotencyKey != "" && existing.IdempotencyKey == clientOrder.IdempotencyKey) {
+			return *existing, false, nil
+		}
+	}
+
+	r.lastID++
+	clientOrder.ID = r.lastID
+	clientOrder.CreatedAt = time.Now()
+	r.clientOrders[clientOrder.ID] = &clientOrder
+	return clientOrder, true, nil
+}
+
+func (r *Repository) CompleteClientOrder(id, orderID int, response []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	clientOrder, ok := r.clientOrders[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+
+	clientOrder.OrderID = orderID
+	clientOrder.Response = slices.Clone(response)
+	return nil
+}
+
+func (r *Repository) DeleteClientOrder(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.clientOrders, id)
+	return nil
+}
+
+func (r *Repository) GetClientOrder(userID int, clientOrderID string) (models.ClientOrder, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, clientOrder := range r.clientOrders {
+		if clientOrder.UserID == userID && clientOrder.ClientOrderID == clientOrderID {
+			return *clientOrder, nil
+		}
+	}
+	return models.ClientOrder{}, repository.ErrNotFound
+}
+
+func (r *Repository) GetClientOrderByOrderID(orderID int) (models.ClientOrder, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, clientOrder := range r.clientOrders {
+		if clientOrder.OrderID == orderID {
+			return *clientOrder, nil
+		}
+	}
+	return models.ClientOrder{}, repository.ErrNotFound
+}
+
+func (r *Repository) AddPostOnlyOrder(orderID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.postOnlyOrders[orderID] = true
+	return nil
+}
+
+func (r *Repository) IsPostOnlyOrder(orderID int) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.postOnlyOrders[orderID], nil
+}
+
+func (r *Repository) CreateConditionalOrder(conditionalOrder models.ConditionalOrder) (models.ConditionalOrder, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastConditionalID++
+	conditionalOrder.ID = r.lastConditionalID
+	conditionalOrder.CreatedAt = time.Now()
+	r.conditionalOrders[conditionalOrder.ID] = &conditionalOrder
+	return conditionalOrder, nil
+}
+
+func (r *Repository) GetConditionalOrder(id int) (models.ConditionalOrder, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	conditionalOrder, ok := r.conditionalOrders[id]
+	if !ok {
+		return models.ConditionalOrder{}, repository.ErrNotFound
+	}
+	return *conditionalOrder, nil
+}
+
+func (r *Repository) GetConditionalOrdersByUser(userID int) ([]models.ConditionalOrder, error) {
+	return r.filterConditionalOrders(func(conditionalOrder *models.ConditionalOrder) bool {
+		return conditionalOrder.UserID == userID
+	}), nil
+}
+
+func (r *Repository) GetUntriggeredConditionalOrders() ([]models.ConditionalOrder, error) {
+	return r.filterConditionalOrders(func(conditionalOrder *models.ConditionalOrder) bool {
+		return conditionalOrder.Status == "untriggered"
+	}), nil
+}
+
+func (r *Repository) UpdateConditionalOrder(conditionalOrder models.ConditionalOrder) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.conditionalOrders[conditionalOrder.ID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+
+	existing.StopPrice = conditionalOrder.StopPrice
+	existing.ExtremePrice = conditionalOrder.ExtremePrice
+	existing.OrderID = conditionalOrder.OrderID
+	existing.Error = conditionalOrder.Error
+	existing.TriggeredAt = conditionalOrder.TriggeredAt
+	return nil
+}
+
+func (r *Repository) TransitConditionalOrder(id int, from, to string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conditionalOrder, ok := r.conditionalOrders[id]
+	if !ok || conditionalOrder.Status != from {
+		return false, nil
+	}
+
+	conditionalOrder.Status = to
+	return true, nil
+}
+
+func (r *Repository) filterConditionalOrders(filter func(*models.ConditionalOrder) bool) []models.ConditionalOrder {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var conditionalOrders []models.ConditionalOrder
+	for _, conditionalOrder := range r.conditionalOrders {
+		if filter(conditionalOrder) {
+			conditionalOrders = append(conditionalOrders, *conditionalOrder)
+		}
+	}
+
+	slices.SortFunc(conditionalOrders, func(a, b models.ConditionalOrder) int {
+		return a.ID - b.ID
+	})
+	return conditionalOrders
+}
+
+func (r *Repository) CreateScheduledJob(job models.ScheduledJob) (models.ScheduledJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastScheduleID++
+	job.ID = r.lastScheduleID
+	job.Status = "pending"
+	job.CreatedAt = time.Now()
+	r.scheduledJobs[job.ID] = &job
+	return job, nil
+}
+
+func (r *Repository) GetScheduledJob(id int) (models.ScheduledJob, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	job, ok := r.scheduledJobs[id]
+	if !ok {
+		return models.ScheduledJob{}, repository.ErrNotFound
+	}
+	return *job, nil
+}
+
+func (r *Repository) GetPendingScheduledJobsByUser(userID int, kind string) ([]models.ScheduledJob, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var jobs []models.ScheduledJob
+	for _, job := range r.scheduledJobs {
+		if job.UserID == userID && job.Kind == kind && job.Status == "pending" {
+			jobs = append(jobs, *job)
+		}
+	}
+
+	slices.SortFunc(jobs, func(a, b models.ScheduledJob) int {
+		return a.RunAt.Compare(b.RunAt)
+	})
+	return jobs, nil
+}
+
+func (r *Repository) ClaimDueScheduledJobs(now, lockedUntil time.Time, limit int) ([]models.ScheduledJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var jobs []models.ScheduledJob
+	for _, job := range r.scheduledJobs {
+		if job.Status == "pending" && !job.RunAt.After(now) && !job.LockedUntil.After(now) {
+			jobs = append(jobs, *job)
+		}
+	}
+
+	slices.SortFunc(jobs, func(a, b models.ScheduledJob) int {
+		return a.RunAt.Compare(b.RunAt)
+	})
+	if len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+
+	for i := range jobs {
+		jobs[i].LockedUntil = lockedUntil
+		r.scheduledJobs[jobs[i].ID].LockedUntil = lockedUntil
+	}
+	return jobs, nil
+}
+
+func (r *Repository) FinishScheduledJob(job models.ScheduledJob) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.scheduledJobs[job.ID]
+	if !ok || !existing.LockedUntil.Equal(job.LockedUntil) {
+		return false, nil
+	}
+
+	existing.Status = job.Status
+	existing.Error = job.Error
+	existing.OrderID = job.OrderID
+	existing.LockedUntil = time.Time{}
+	return true, nil
+}
+
+func (r *Repository) CancelScheduledJob(id int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.scheduledJobs[id]
+	if !ok || job.Status != "pending" || job.LockedUntil.After(time.Now()) {
+		return false, nil
+	}
+
+	job.Status = "canceled"
+	return true, nil
+}
+
+func (r *Repository) CreateAlgoOrder(algoOrder models.AlgoOrder) (models.AlgoOrder, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastAlgoID++
+	algoOrder.ID = r.lastAlgoID
+	algoOrder.CreatedAt = time.Now()
+	r.algoOrders[algoOrder.ID] = &algoOrder
+	return algoOrder, nil
+}
+
+func (r *Repository) GetAlgoOrder(id int) (models.AlgoOrder, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	algoOrder, ok := r.algoOrders[id]
+	if !ok {
+		return models.AlgoOrder{}, repository.ErrNotFound
+	}
+	return *algoOrder, nil
+}
+
+func (r *Repository) GetAlgoOrdersByUser(userID int) ([]models.AlgoOrder, error) {
+	return r.filterAlgoOrders(func(algoOrder *models.AlgoOrder) bool {
+		return algoOrder.UserID == userID
+	}), nil
+}
+
+func (r *Repository) GetOpenAlgoOrders() ([]models.AlgoOrder, error) {
+	return r.filterAlgoOrders(func(algoOrder *models.AlgoOrder) bool {
+		return algoOrder.Status == "running" || algoOrder.Status == "paused"
+	}), nil
+}
+
+func (r *Repository) ClaimDueAlgoOrders(now, lockedUntil time.Time, limit int) ([]models.AlgoOrder, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var algoOrders []models.AlgoOrder
+	for _, algoOrder := range r.algoOrders {
+		if algoOrder.Status == "running" && !algoOrder.NextSliceAt.After(now) && !algoOrder.LockedUntil.After(now) {
+			algoOrders = append(algoOrders, *algoOrder)
+		}
+	}
+
+	slices.SortFunc(algoOrders, func(a, b models.AlgoOrder) int {
+		return a.NextSliceAt.Compare(b.NextSliceAt)
+	})
+	if len(algoOrders) > limit {
+		algoOrders = algoOrders[:limit]
+	}
+
+	for i := range algoOrders {
+		algoOrders[i].LockedUntil = lockedUntil
+		r.algoOrders[algoOrders[i].ID].LockedUntil = lockedUntil
+	}
+	return algoOrders, nil
+}
+
+func (r *Repository) ClaimAlgoOrder(id int, now, lockedUntil time.Time) (models.AlgoOrder, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	algoOrder, ok := r.algoOrders[id]
+	if !ok || algoOrder.Status != "running" || algoOrder.LockedUntil.After(now) {
+		return models.AlgoOrder{}, false, nil
+	}
+
+	algoOrder.LockedUntil = lockedUntil
+	return *algoOrder, true, nil
+}
+
+func (r *Repository) UpdateAlgoOrder(algoOrder models.AlgoOrder, status string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.algoOrders[algoOrder.ID]
+	if !ok || existing.Status != status {
+		return false, nil
+	}
+
+	r.updateAlgoOrder(existing, algoOrder)
+	return true, nil
+}
+
+func (r *Repository) UpdateClaimedAlgoOrder(algoOrder models.AlgoOrder) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.algoOrders[algoOrder.ID]
+	if !ok || existing.Status != "running" || !existing.LockedUntil.Equal(algoOrder.LockedUntil) {
+		return false, nil
+	}
+
+	r.updateAlgoOrder(existing, algoOrder)
+	return true, nil
+}
+
+// updateAlgoOrder stores the worked fields of algoOrder in existing and releases its lock
+func (r *Repository) updateAlgoOrder(existing *models.AlgoOrder, algoOrder models.AlgoOrder) {
+	existing.SlicesPlaced = algoOrder.SlicesPlaced
+	existing.SizeFilled = algoOrder.SizeFilled
+	existing.Status = algoOrder.Status
+	existing.Error = algoOrder.Error
+	existing.NextSliceAt = algoOrder.NextSliceAt
+	existing.EndAt = algoOrder.EndAt
+	existing.PausedAt = algoOrder.PausedAt
+	existing.ClosedAt = algoOrder.ClosedAt
+	existing.LockedUntil = time.Time{}
+}
+
+func (r *Repository) UpdateAlgoOrderSizeFilled(id int, sizeFilled string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	algoOrder, ok := r.algoOrders[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+
+	algoOrder.SizeFilled = sizeFilled
+	return nil
+}
+
+func (r *Repository) AddAlgoOrderChild(algoOrderID, orderID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.algoOrderChild[orderID] = algoOrderID
+	return nil
+}
+
+func (r *Repository) GetAlgoOrderChildren(algoOrderID int) ([]models.Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	orders := r.orders(func(order *pbM.Order) bool {
+		return r.algoOrderChild[int(order.ID)] == algoOrderID
+	})
+	slices.SortFunc(orders, func(a, b models.Order) int {
+		return a.ID - b.ID
+	})
+	return orders, nil
+}
+
+func (r *Repository) filterAlgoOrders(filter func(*models.AlgoOrder) bool) []models.AlgoOrder {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var algoOrders []models.AlgoOrder
+	for _, algoOrder := range r.algoOrders {
+		if filter(algoOrder) {
+			algoOrders = append(algoOrders, *algoOrder)
+		}
+	}
+
+	slices.SortFunc(algoOrders, func(a, b models.AlgoOrder) int {
+		return a.ID - b.ID
+	})
+	return algoOrders
+}
+
+func (r *Repository) CreateOrderGroup(orderGroup models.OrderGroup) (models.OrderGroup, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastGroupID++
+	orderGroup.ID = r.lastGroupID
+	orderGroup.CreatedAt = time.Now()
+	r.orderGroups[orderGroup.ID] = &orderGroup
+	return orderGroup, nil
+}
+
+func (r *Repository) GetOrderGroup(id int) (models.OrderGroup, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	orderGroup, ok := r.orderGroups[id]
+	if !ok {
+		return models.OrderGroup{}, repository.ErrNotFound
+	}
+	return *orderGroup, nil
+}
+
+func (r *Repository) GetOrderGroupsByUser(userID int) ([]models.OrderGroup, error) {
+	return r.filterOrderGroups(func(orderGroup *models.OrderGroup) bool {
+		return orderGroup.UserID == userID
+	}), nil
+}
+
+func (r *Repository) GetOpenOrderGroups() ([]models.OrderGroup, error) {
+	return r.filterOrderGroups(func(orderGroup *models.OrderGroup) bool {
+		return orderGroup.Status == "pending" || orderGroup.Status == "active"
+	}), nil
+}
+
+func (r *Repository) UpdateOrderGroup(orderGroup models.OrderGroup, status string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.orderGroups[orderGroup.ID]
+	if !ok || existing.Status != status {
+		return false, nil
+	}
+
+	existing.Status = orderGroup.Status
+	existing.Error = orderGroup.Error
+	existing.ClosedAt = orderGroup.ClosedAt
+	return true, nil
+}
+
+func (r *Repository) AddOrderGroupLeg(leg models.OrderGroupLeg) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.orderGroupLegs[leg.OrderGroupID] = append(r.orderGroupLegs[leg.OrderGroupID], leg)
+	return nil
+}
+
+func (r *Repository) GetOrderGroupLegs(orderGroupID int) ([]models.OrderGroupLeg, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Clone(r.orderGroupLegs[orderGroupID]), nil
+}
+
+func (r *Repository) filterOrderGroups(filter func(*models.OrderGroup) bool) []models.OrderGroup {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var orderGroups []models.OrderGroup
+	for _, orderGroup := range r.orderGroups {
+		if filter(orderGroup) {
+			orderGroups = append(orderGroups, *orderGroup)
+		}
+	}
+
+	slices.SortFunc(orderGroups, func(a, b models.OrderGroup) int {
+		return a.ID - b.ID
+	})
+	return orderGroups
+}
+
+func (r *Repository) GetRiskLimits() (map[string]models.RiskLimits, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return maps.Clone(r.riskLimits), nil
+}
+
+func (r *Repository) SaveRiskLimits(pair string, riskLimits models.RiskLimits) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.riskLimits[pair] = riskLimits
+	return nil
+}
+
+func (r *Repository) DeleteRiskLimits(pair string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.riskLimits[pair]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(r.riskLimits, pair)
+	return nil
+}
+
+func (r *Repository) Close() {}
@@ -0,0 +1,91 @@
+package sandbox
+
+import (
+	"context"
+	"log/slog"
+	"net"
+
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/AuthProtoGen/pbA"
+	"github.com/BazaarTrade/MatchingEngineProtoGen/pbM"
+	"github.com/BazaarTrade/QuoteProtoGen/pbQ"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// Address is the target the gateway clients dial together with DialOption
+const Address = "passthrough:///sandbox"
+
+var bufferSize = 1024 * 1024
+
+// Sandbox runs the matching engine, quote and auth services in process over bufconn,
+// so the gateway can be started without any backend service or database.
+type Sandbox struct {
+	MatchingEngine *MatchingEngine
+	Quote          *Quote
+	Auth           *Auth
+	Repository     *Repository
+
+	listener *bufconn.Listener
+	server   *grpc.Server
+	logger   *slog.Logger
+}
+
+func New(logger *slog.Logger) *Sandbox {
+	matchingEngine := NewMatchingEngine()
+	quote := NewQuote(matchingEngine)
+
+	return &Sandbox{
+		MatchingEngine: matchingEngine,
+		Quote:          quote,
+		Auth:           NewAuth(),
+		Repository:     NewRepository(matchingEngine, quote),
+		listener:       bufconn.Listen(bufferSize),
+		server:         grpc.NewServer(),
+		logger:         logger,
+	}
+}
+
+func (s *Sandbox) Run() {
+	pbM.RegisterMatchingEngineServer(s.server, s.MatchingEngine)
+	pbQ.RegisterQuoteServer(s.server, s.Quote)
+	pbA.RegisterAuthServer(s.server, s.Auth)
+
+	go func() {
+		if err := s.server.Serve(s.listener); err != nil {
+			s.logger.Error("sandbox gRPC server stopped", "error", err)
+		}
+	}()
+
+	s.logger.Info("sandbox services are running in process")
+}
+
+// DialOption connects a gRPC client dialing Address to the sandbox services
+func (s *Sandbox) DialOption() grpc.DialOption {
+	return grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return s.listener.DialContext(ctx)
+	})
+}
+
+// CreatePair lists the pair in every sandbox service the way createOrderBook does
+func (s *Sandbox) CreatePair(pairParams models.PairParams) error {
+	if err := s.Repository.CreatePair(pairParams); err != nil {
+		return err
+	}
+
+	if _, err := s.MatchingEngine.CreateOrderBook(context.Background(), &pbM.Pair{Pair: pairParams.Pair}); err != nil {
+		return err
+	}
+
+	_, err := s.Quote.CreateOrderBook(context.Background(), &pbQ.PairParams{
+		Pair:                  pairParams.Pair,
+		PricePrecisions:       pairParams.OrderBookPricePrecisions,
+		QtyPrecision:          pairParams.QtyPrecision,
+		CandleStickTimeframes: pairParams.CandleStickTimeframes,
+	})
+	return err
+}
+
+func (s *Sandbox) Stop() {
+	s.server.Stop()
+}
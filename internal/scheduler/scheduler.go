@@ -0,0 +1,268 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	ws "github.com/BazaarTrade/ApiGatewayService/internal/api/websocket"
+	"github.com/BazaarTrade/ApiGatewayService/internal/converter"
+	"github.com/BazaarTrade/ApiGatewayService/internal/models"
+	"github.com/BazaarTrade/ApiGatewayService/internal/orderRouter"
+	"github.com/BazaarTrade/ApiGatewayService/internal/repository"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	pollInterval = time.Second
+	// lockDuration is how long a claimed job is left to its gateway before it is retried
+	lockDuration = 30 * time.Second
+	// jobDuration is the longest a job is expected to take, a matching engine call and some database work,
+	// a job is only started while its lock has that long left
+	jobDuration = 15 * time.Second
+	claimLimit  = 10
+	// placeGrace is how late a held order may still be placed, e.g. after the gateways were down at its start time
+	placeGrace = time.Minute
+)
+
+var ErrNotCancelable = errors.New("scheduled order is not pending")
+
+// Scheduler runs the time in force work of orders: it places orders held until their
+// start time and cancels orders at their expiry. It also runs the cancelAllAfter
+// deadlines of users. Jobs are stored in the repository,
+// so they survive restarts and are run once across gateways.
+type Scheduler struct {
+	db     repository.Repository
+	router *orderRouter.Router
+	hub    *ws.Hub
+	// onCancelAll cancels everything of a user whose cancelAllAfter deadline passed
+	onCancelAll func(userID int) (int, error)
+	logger      *slog.Logger
+}
+
+func New(db repository.Repository, router *orderRouter.Router, hub *ws.Hub, logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		db:     db,
+		router: router,
+		hub:    hub,
+		logger: logger,
+	}
+}
+
+// SetCancelAllHandler registers the handler run at a cancelAllAfter deadline, it returns the number of
+// canceled orders and an error if anything is left to cancel. It must be set before Run.
+func (s *Scheduler) SetCancelAllHandler(handler func(userID int) (int, error)) {
+	s.onCancelAll = handler
+}
+
+// PlaceAt holds the validated order until startTime
+func (s *Scheduler) PlaceAt(req models.PlaceOrderReq, startTime time.Time) (models.ScheduledJob, error) {
+	request, err := json.Marshal(req)
+	if err != nil {
+		s.logger.Error("failed to marshal scheduled order", "error", err)
+		return models.ScheduledJob{}, err
+	}
+
+	job, err := s.db.CreateScheduledJob(models.ScheduledJob{
+		Kind:    "place",
+		UserID:  req.UserID,
+		Request: request,
+		RunAt:   startTime,
+	})
+	if err != nil {
+		return models.ScheduledJob{}, err
+	}
+
+	s.hub.BroadcastOrder(converter.ScheduledJobToModelsOrder(job))
+	return job, nil
+}
+
+// CancelAt cancels the order at expireTime unless it is closed by then
+func (s *Scheduler) CancelAt(userID, orderID int, expireTime time.Time) error {
+	_, err := s.db.CreateScheduledJob(models.ScheduledJob{
+		Kind:    "cancel",
+		UserID:  userID,
+		OrderID: orderID,
+		RunAt:   expireTime,
+	})
+	return err
+}
+
+// CancelAllAfter replaces the user's cancelAllAfter deadline with now plus timeout,
+// a zero timeout only disarms it. A deadline that is already running can not be disarmed.
+func (s *Scheduler) CancelAllAfter(userID int, timeout time.Duration) (time.Time, error) {
+	jobs, err := s.db.GetPendingScheduledJobsByUser(userID, "cancelAll")
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for _, job := range jobs {
+		if _, err := s.db.CancelScheduledJob(job.ID); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	if timeout == 0 {
+		return time.Time{}, nil
+	}
+
+	job, err := s.db.CreateScheduledJob(models.ScheduledJob{
+		Kind:   "cancelAll",
+		UserID: userID,
+		RunAt:  time.Now().Add(timeout),
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return job.RunAt, nil
+}
+
+// CancelScheduledOrder cancels a held order, the caller is expected to check that it belongs to the user
+func (s *Scheduler) CancelScheduledOrder(id int) (models.ScheduledJob, error) {
+	ok, err := s.db.CancelScheduledJob(id)
+	if err != nil {
+		return models.ScheduledJob{}, err
+	}
+	if !ok {
+		return models.ScheduledJob{}, ErrNotCancelable
+	}
+
+	job, err := s.db.GetScheduledJob(id)
+	if err != nil {
+		return models.ScheduledJob{}, err
+	}
+
+	s.hub.BroadcastOrder(converter.ScheduledJobToModelsOrder(job))
+	return job, nil
+}
+
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runDue()
+		}
+	}
+}
+
+func (s *Scheduler) runDue() {
+	now := time.Now()
+	jobs, err := s.db.ClaimDueScheduledJobs(now, now.Add(lockDuration), claimLimit)
+	if err != nil {
+		s.logger.Error("failed to claim scheduled jobs", "error", err)
+		return
+	}
+
+	for _, job := range jobs {
+		// the jobs left are claimed again by any gateway once the lock expires
+		if time.Until(job.LockedUntil) < jobDuration {
+			return
+		}
+
+		switch job.Kind {
+		case "place":
+			s.place(job)
+		case "cancel":
+			s.cancel(job)
+		case "cancelAll":
+			s.cancelAll(job)
+		default:
+			s.logger.Error("unknown scheduled job kind", "jobID", job.ID, "kind", job.Kind)
+		}
+	}
+}
+
+// place is not retried, a held order placed late would be worse than a rejected one
+func (s *Scheduler) place(job models.ScheduledJob) {
+	var req models.PlaceOrderReq
+	if err := json.Unmarshal(job.Request, &req); err != nil {
+		s.finish(job, "failed", "invalid scheduled order")
+		return
+	}
+
+	if time.Since(job.RunAt) > placeGrace {
+		s.finish(job, "failed", "start time passed before the order could be placed")
+		return
+	}
+
+	order, err := s.router.PlaceOrder(req)
+	if err != nil {
+		s.logger.Error("failed to place scheduled order", "jobID", job.ID, "error", err)
+
+		message := "internal error in matching engine"
+		if st, _ := status.FromError(err); st.Message() != "" {
+			message = st.Message()
+		}
+		s.finish(job, "failed", message)
+		return
+	}
+
+	job.OrderID = order.ID
+	if req.ExpireTime != "" {
+		expireTime, _ := time.Parse(time.RFC3339, req.ExpireTime)
+		if err := s.CancelAt(req.UserID, order.ID, expireTime); err != nil {
+			s.logger.Error("failed to schedule order expiry", "orderID", order.ID, "error", err)
+		}
+	}
+	s.finish(job, "done", "")
+}
+
+// cancel is retried once the lock expires if the matching engine or database fails
+func (s *Scheduler) cancel(job models.ScheduledJob) {
+	order, err := s.db.GetOrderByID(job.OrderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			s.finish(job, "failed", "order not found")
+		}
+		return
+	}
+
+	// a filled or canceled order has nothing left to expire
+	if order.Status != "filling" {
+		s.finish(job, "done", "")
+		return
+	}
+
+	if _, err := s.router.CancelOrder(job.OrderID); err != nil {
+		s.logger.Error("failed to cancel expired order", "orderID", job.OrderID, "error", err)
+		return
+	}
+	s.finish(job, "done", "")
+}
+
+// cancelAll is retried once the lock expires until everything of the user is canceled
+func (s *Scheduler) cancelAll(job models.ScheduledJob) {
+	canceled, err := s.onCancelAll(job.UserID)
+	if err != nil {
+		s.logger.Error("failed to cancel everything after cancelAllAfter timeout", "userID", job.UserID, "canceled", canceled, "error", err)
+		return
+	}
+
+	s.logger.Info("canceled orders after cancelAllAfter timeout", "userID", job.UserID, "canceled", canceled)
+	s.finish(job, "done", "")
+}
+
+func (s *Scheduler) finish(job models.ScheduledJob, jobStatus, errorMessage string) {
+	job.Status = jobStatus
+	job.Error = errorMessage
+	ok, err := s.db.FinishScheduledJob(job)
+	if err != nil {
+		s.logger.Error("failed to finish scheduled job", "jobID", job.ID, "error", err)
+		return
+	}
+	if !ok {
+		s.logger.Warn("scheduled job finished after its lock expired", "jobID", job.ID, "status", jobStatus)
+		return
+	}
+
+	if job.Kind == "place" {
+		s.hub.BroadcastOrder(converter.ScheduledJobToModelsOrder(job))
+	}
+}
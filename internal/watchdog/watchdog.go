@@ -0,0 +1,142 @@
+package watchdog
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	ws "github.com/BazaarTrade/ApiGatewayService/internal/api/websocket"
+	"github.com/BazaarTrade/ApiGatewayService/internal/marketData"
+)
+
+var checkInterval = time.Second
+
+// Watchdog tracks when each (pair, stream) last delivered a message and flags
+// it as stale once it has been silent for longer than the stream threshold.
+// Stream names are the websocket topic names: orderBook, trades, ticker, candleStick.
+type Watchdog struct {
+	thresholds map[string]time.Duration
+	streams    map[streamKey]*streamState
+	mu         sync.Mutex
+	cache      *marketData.Cache
+	hub        *ws.Hub
+	logger     *slog.Logger
+}
+
+type streamKey struct {
+	pair   string
+	stream string
+}
+
+type streamState struct {
+	lastSeen time.Time
+	stale    bool
+}
+
+// New creates a watchdog, a zero or missing threshold disables the check for that stream.
+func New(thresholds map[string]time.Duration, cache *marketData.Cache, hub *ws.Hub, logger *slog.Logger) *Watchdog {
+	return &Watchdog{
+		thresholds: thresholds,
+		streams:    make(map[streamKey]*streamState),
+		cache:      cache,
+		hub:        hub,
+		logger:     logger,
+	}
+}
+
+func (w *Watchdog) Run(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+func (w *Watchdog) check() {
+	var becameStale []streamKey
+
+	now := time.Now()
+
+	w.mu.Lock()
+	for key, state := range w.streams {
+		threshold := w.thresholds[key.stream]
+		if threshold <= 0 || state.stale {
+			continue
+		}
+
+		if now.Sub(state.lastSeen) > threshold {
+			state.stale = true
+			becameStale = append(becameStale, key)
+		}
+	}
+	w.mu.Unlock()
+
+	for _, key := range becameStale {
+		w.logger.Warn("market data stream is stale", "pair", key.pair, "stream", key.stream)
+		w.cache.SetStale(key.pair, key.stream, true)
+		w.hub.BroadcastTopicStatus(key.stream, key.pair, "stale")
+	}
+}
+
+// Watch starts tracking the stream, it is called every time the stream (re)connects.
+func (w *Watchdog) Watch(pair, stream string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	key := streamKey{pair: pair, stream: stream}
+	if state, ok := w.streams[key]; ok {
+		state.lastSeen = time.Now()
+		return
+	}
+
+	w.streams[key] = &streamState{lastSeen: time.Now()}
+}
+
+// Touch records a received message and reports recovery if the stream was stale.
+func (w *Watchdog) Touch(pair, stream string) {
+	w.mu.Lock()
+	state, ok := w.streams[streamKey{pair: pair, stream: stream}]
+	if !ok {
+		w.mu.Unlock()
+		return
+	}
+
+	state.lastSeen = time.Now()
+	recovered := state.stale
+	state.stale = false
+	w.mu.Unlock()
+
+	if recovered {
+		w.logger.Info("market data stream recovered", "pair", pair, "stream", stream)
+		w.cache.SetStale(pair, stream, false)
+		w.hub.BroadcastTopicStatus(stream, pair, "recovered")
+	}
+}
+
+func (w *Watchdog) Forget(pair, stream string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.streams, streamKey{pair: pair, stream: stream})
+}
+
+// StalePairs returns stale streams grouped by pair.
+func (w *Watchdog) StalePairs() map[string][]string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var stalePairs = make(map[string][]string)
+	for key, state := range w.streams {
+		if state.stale {
+			stalePairs[key.pair] = append(stalePairs[key.pair], key.stream)
+		}
+	}
+	return stalePairs
+}